@@ -0,0 +1,140 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MatteoMori/sentinel/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+// historyFlags holds the flags of the history commands
+var historyFlags struct {
+	server    string
+	output    string
+	at        string
+	namespace string
+	from      string
+	to        string
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Query the image history recorded by a running Sentinel (requires history.enabled)",
+}
+
+var historyInventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Show what was running at a point in time",
+	Long: `Show the workloads, containers and images that were running at a point in time.
+
+Example:
+  sentinel history inventory --namespace prod-payments --at 2026-01-20T14:00:00Z`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		params := url.Values{}
+		if historyFlags.at != "" {
+			params.Set("at", historyFlags.at)
+		}
+		if historyFlags.namespace != "" {
+			params.Set("namespace", historyFlags.namespace)
+		}
+
+		var response api.HistoryInventoryResponse
+		if err := getJSON(historyFlags.server, "/api/v1/history/inventory", params, &response); err != nil {
+			return err
+		}
+
+		switch historyFlags.output {
+		case "json":
+			return printJSON(response)
+		case "table":
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAMESPACE\tKIND\tWORKLOAD\tCONTAINER\tIMAGE")
+			for _, w := range response.Workloads {
+				for _, c := range w.Containers {
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", w.Namespace, w.Kind, w.Name, c.Name, c.Image.Reference)
+				}
+			}
+			return tw.Flush()
+		default:
+			return fmt.Errorf("unknown output format %q (supported: table, json)", historyFlags.output)
+		}
+	},
+}
+
+var historyTimelineCmd = &cobra.Command{
+	Use:   "timeline <namespace>/<kind>/<name>",
+	Short: "Show the image changes of a workload",
+	Long: `Show the image transitions recorded for a workload, oldest first.
+
+Example:
+  sentinel history timeline prod-payments/Deployment/api --from 2026-01-01T00:00:00Z`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		parts := strings.Split(args[0], "/")
+		if len(parts) != 3 {
+			return fmt.Errorf("expected <namespace>/<kind>/<name>, got %q", args[0])
+		}
+
+		params := url.Values{}
+		if historyFlags.from != "" {
+			params.Set("from", historyFlags.from)
+		}
+		if historyFlags.to != "" {
+			params.Set("to", historyFlags.to)
+		}
+
+		var response api.TimelineResponse
+		path := "/api/v1/history/workloads/" + url.PathEscape(parts[0]) + "/" + url.PathEscape(parts[1]) + "/" + url.PathEscape(parts[2])
+		if err := getJSON(historyFlags.server, path, params, &response); err != nil {
+			return err
+		}
+
+		switch historyFlags.output {
+		case "json":
+			return printJSON(response)
+		case "table":
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "TIME\tTYPE\tCONTAINER\tOLD IMAGE\tNEW IMAGE")
+			for _, t := range response.Transitions {
+				oldImage, newImage := "-", "-"
+				if t.OldImage != nil {
+					oldImage = t.OldImage.Reference
+				}
+				if t.NewImage != nil {
+					newImage = t.NewImage.Reference
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", t.Time.Format("2006-01-02T15:04:05Z07:00"), t.Type, t.Container, oldImage, newImage)
+			}
+			return tw.Flush()
+		default:
+			return fmt.Errorf("unknown output format %q (supported: table, json)", historyFlags.output)
+		}
+	},
+}
+
+func init() {
+	historyCmd.PersistentFlags().StringVar(&historyFlags.server, "server", "http://localhost:9090", "URL of a running Sentinel")
+	historyCmd.PersistentFlags().StringVarP(&historyFlags.output, "output", "o", "table", "output format: table or json")
+
+	historyInventoryCmd.Flags().StringVar(&historyFlags.at, "at", "", "point in time (RFC3339), default now")
+	historyInventoryCmd.Flags().StringVarP(&historyFlags.namespace, "namespace", "n", "", "only this namespace")
+
+	historyTimelineCmd.Flags().StringVar(&historyFlags.from, "from", "", "only transitions after this time (RFC3339)")
+	historyTimelineCmd.Flags().StringVar(&historyFlags.to, "to", "", "only transitions before this time (RFC3339)")
+
+	historyCmd.AddCommand(historyInventoryCmd, historyTimelineCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+// printJSON writes a value to stdout as indented JSON
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
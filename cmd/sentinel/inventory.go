@@ -0,0 +1,153 @@
+package sentinel
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	"github.com/MatteoMori/sentinel/pkg/sentinel"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// inventoryFlags holds the flags of the inventory command
+var inventoryFlags struct {
+	kubeconfig string
+	context    string
+	namespace  string
+	output     string
+	timeout    time.Duration
+}
+
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "List the images of the watched workloads once, without running the controller",
+	Long: `List the Deployments, StatefulSets and DaemonSets of the namespaces matching namespaceSelector
+(or of --namespace only) and print their container images. Uses the current kubeconfig context.
+
+Examples:
+  sentinel inventory -n prod-payments
+  sentinel inventory -o csv > images.csv
+  sentinel inventory -o prometheus`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		loadingRules.ExplicitPath = inventoryFlags.kubeconfig
+		restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			loadingRules,
+			&clientcmd.ConfigOverrides{CurrentContext: inventoryFlags.context},
+		).ClientConfig()
+		if err != nil {
+			return fmt.Errorf("unable to load kubeconfig: %w", err)
+		}
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), inventoryFlags.timeout)
+		defer cancel()
+		workloads, err := sentinel.ListInventory(ctx, clientset, config, inventoryFlags.namespace)
+		if err != nil {
+			return err
+		}
+
+		switch inventoryFlags.output {
+		case "table":
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAMESPACE\tKIND\tWORKLOAD\tCONTAINER\tIMAGE")
+			for _, w := range workloads {
+				for _, c := range w.Containers {
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", w.Namespace, w.Kind, w.Name, c.Name, c.Image.Reference)
+				}
+			}
+			return tw.Flush()
+		case "json":
+			return printJSON(workloads)
+		case "csv":
+			return writeInventoryCSV(workloads)
+		case "prometheus":
+			return writeInventoryMetrics(workloads)
+		default:
+			return fmt.Errorf("unknown output format %q (supported: table, json, csv, prometheus)", inventoryFlags.output)
+		}
+	},
+}
+
+func init() {
+	inventoryCmd.Flags().StringVar(&inventoryFlags.kubeconfig, "kubeconfig", "", "path to the kubeconfig file (default: $KUBECONFIG or ~/.kube/config)")
+	inventoryCmd.Flags().StringVar(&inventoryFlags.context, "context", "", "kubeconfig context to use")
+	inventoryCmd.Flags().StringVarP(&inventoryFlags.namespace, "namespace", "n", "", "only this namespace, instead of the namespaces matching namespaceSelector")
+	inventoryCmd.Flags().StringVarP(&inventoryFlags.output, "output", "o", "table", "output format: table, json, csv or prometheus")
+	inventoryCmd.Flags().DurationVar(&inventoryFlags.timeout, "timeout", time.Minute, "timeout of the Kubernetes API calls")
+
+	rootCmd.AddCommand(inventoryCmd)
+}
+
+// writeInventoryCSV prints one row per container, with a column per configured extra label
+func writeInventoryCSV(workloads []inventory.Workload) error {
+	w := csv.NewWriter(os.Stdout)
+	header := []string{"namespace", "kind", "workload", "container", "image", "registry", "repository", "tag", "digest"}
+	for _, el := range config.ExtraLabels {
+		header = append(header, el.TimeseriesLabelName)
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, workload := range workloads {
+		for _, c := range workload.Containers {
+			row := []string{workload.Namespace, workload.Kind, workload.Name, c.Name, c.Image.Reference, c.Image.Registry, c.Image.Repository, c.Image.Tag, c.Image.Digest}
+			for _, el := range config.ExtraLabels {
+				row = append(row, workload.ExtraLabels[el.TimeseriesLabelName])
+			}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// writeInventoryMetrics prints sentinel_container_image_info in the Prometheus text exposition format,
+// with the same labels as the controller (e.g. for a node_exporter textfile collector)
+func writeInventoryMetrics(workloads []inventory.Workload) error {
+	SentinelPrometheus.BuildMetrics(config.ExtraLabels)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(SentinelPrometheus.SentinelContainerImageInfo)
+
+	for _, workload := range workloads {
+		for _, c := range workload.Containers {
+			labelValues := []string{workload.Namespace, workload.Kind, workload.Name, c.Name, c.Image.Reference, c.Image.Registry, c.Image.Repository, c.Image.Tag}
+			for _, el := range config.ExtraLabels {
+				labelValues = append(labelValues, workload.ExtraLabels[el.TimeseriesLabelName])
+			}
+			SentinelPrometheus.SentinelContainerImageInfo.WithLabelValues(labelValues...).Set(1)
+		}
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	encoder := expfmt.NewEncoder(&b, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return err
+		}
+	}
+	_, err = os.Stdout.WriteString(b.String())
+	return err
+}
@@ -0,0 +1,64 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/MatteoMori/sentinel/pkg/cyclonedx"
+	"github.com/MatteoMori/sentinel/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// sbomFlags holds the flags of the sbom command
+var sbomFlags struct {
+	server   string
+	snapshot string
+	file     string
+}
+
+var sbomCmd = &cobra.Command{
+	Use:   "sbom",
+	Short: "Export the inventory as a CycloneDX bill of materials",
+	Long: `Render the inventory of a running Sentinel, or of a saved snapshot, as a CycloneDX 1.5 JSON document:
+every distinct image is a container component (purl, digest), with the namespaces and workloads running it.
+
+Examples:
+  sentinel sbom --server http://sentinel.prod:9090 -f prod.cdx.json
+  sentinel sbom --snapshot prod-2026-01-20.yaml`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var s snapshot.Snapshot
+		var err error
+		if sbomFlags.snapshot != "" {
+			s, err = snapshot.Load(sbomFlags.snapshot)
+		} else {
+			s, err = fetchSnapshot(sbomFlags.server)
+		}
+		if err != nil {
+			return err
+		}
+
+		var out io.Writer = os.Stdout
+		if sbomFlags.file != "" && sbomFlags.file != "-" {
+			f, err := os.Create(sbomFlags.file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(cyclonedx.Generate(s))
+	},
+}
+
+func init() {
+	sbomCmd.Flags().StringVar(&sbomFlags.server, "server", "http://localhost:9090", "URL of a running Sentinel")
+	sbomCmd.Flags().StringVar(&sbomFlags.snapshot, "snapshot", "", "render this snapshot file instead of asking a running Sentinel")
+	sbomCmd.Flags().StringVarP(&sbomFlags.file, "file", "f", "", "write the document to this file instead of stdout")
+
+	rootCmd.AddCommand(sbomCmd)
+}
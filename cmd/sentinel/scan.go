@@ -0,0 +1,135 @@
+package sentinel
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/manifests"
+	"github.com/MatteoMori/sentinel/pkg/policy"
+	"github.com/spf13/cobra"
+)
+
+// scanFlags holds the flags of the scan command
+var scanFlags struct {
+	namespace string
+	output    string
+	failOn    string
+}
+
+// ScanReport is the JSON output of the scan command
+type ScanReport struct {
+	Inventory  []ScannedWorkload  `json:"inventory"`
+	Violations []policy.Violation `json:"violations"`
+}
+
+// ScannedWorkload is an inventory record and the manifest it was read from
+type ScannedWorkload struct {
+	inventory.Workload
+	Source string `json:"source"` // "<file>#<document index>"
+}
+
+var scanCmd = &cobra.Command{
+	Use:   "scan <path|->",
+	Short: "Inventory and policy-check Kubernetes manifests without a cluster",
+	Long: `Parse Kubernetes manifests (multi-document YAML or JSON, from a file, a directory or stdin), list the images
+of the Deployments, StatefulSets and DaemonSets they define, and check them against the image policies.
+Exits non-zero when a violation reaches the --fail-on severity, to fail a pipeline.
+
+Examples:
+  sentinel scan ./deploy
+  helm template my-release ./chart | sentinel scan -
+  kustomize build overlays/prod | sentinel scan - -o json --fail-on warning`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var failOn policy.Severity
+		if scanFlags.failOn != "none" {
+			var err error
+			if failOn, err = policy.ParseSeverity(scanFlags.failOn); err != nil {
+				return err
+			}
+		}
+
+		set, err := manifests.Load(args[0], scanFlags.namespace)
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true // From here on, errors are about the manifests, not the command line
+
+		engine, err := policy.NewEngine(config.Policy)
+		if err != nil {
+			return err
+		}
+		records := manifests.Inventory(set.Workloads, config.ExtraLabels)
+		report := ScanReport{Inventory: []ScannedWorkload{}, Violations: []policy.Violation{}}
+		for i, w := range set.Workloads {
+			report.Inventory = append(report.Inventory, ScannedWorkload{Workload: records[i], Source: w.Source})
+			report.Violations = append(report.Violations, engine.Evaluate(policy.Target{
+				Workload:        records[i],
+				Containers:      w.Containers,
+				NamespaceLabels: set.NamespaceLabels[w.Namespace],
+				Object:          w.Object,
+			})...)
+		}
+
+		switch scanFlags.output {
+		case "json":
+			err = printJSON(report)
+		case "table":
+			err = printScanReport(report)
+		default:
+			return fmt.Errorf("unknown output format %q (supported: table, json)", scanFlags.output)
+		}
+		if err != nil {
+			return err
+		}
+
+		if failOn != "" {
+			failing := 0
+			for _, v := range report.Violations {
+				if v.Severity.AtLeast(failOn) {
+					failing++
+				}
+			}
+			if failing > 0 {
+				return fmt.Errorf("%d policy violation(s) at or above severity %s", failing, failOn)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	scanCmd.Flags().StringVarP(&scanFlags.namespace, "namespace", "n", "default", "namespace of the workloads without metadata.namespace")
+	scanCmd.Flags().StringVarP(&scanFlags.output, "output", "o", "table", "output format: table or json")
+	scanCmd.Flags().StringVar(&scanFlags.failOn, "fail-on", "error", "exit non-zero on violations of this severity or above: error, warning, info or none")
+
+	rootCmd.AddCommand(scanCmd)
+}
+
+// printScanReport prints the inventory, then the violations
+func printScanReport(report ScanReport) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tKIND\tWORKLOAD\tCONTAINER\tIMAGE\tSOURCE")
+	for _, w := range report.Inventory {
+		for _, c := range w.Containers {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", w.Namespace, w.Kind, w.Name, c.Name, c.Image.Reference, w.Source)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	if len(report.Violations) == 0 {
+		fmt.Println("No policy violations")
+		return nil
+	}
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tRULE\tWORKLOAD\tCONTAINER\tMESSAGE")
+	for _, v := range report.Violations {
+		fmt.Fprintf(tw, "%s\t%s\t%s/%s/%s\t%s\t%s\n", v.Severity, v.Rule, v.Namespace, v.Kind, v.Workload, v.Container, v.Message)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,116 @@
+package sentinel
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/MatteoMori/sentinel/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// snapshotFlags holds the flags of the snapshot and diff commands
+var snapshotFlags struct {
+	server     string
+	output     string
+	file       string
+	diffOutput string
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save the inventory of a running Sentinel to a JSON or YAML file",
+	Long: `Save the full inventory of a running Sentinel (workloads, containers, parsed images, extra labels)
+to a versioned snapshot file, to compare it later with 'sentinel diff'.
+
+Example:
+  sentinel snapshot --server http://sentinel.prod:9090 -f prod-2026-01-20.yaml`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := fetchSnapshot(snapshotFlags.server)
+		if err != nil {
+			return err
+		}
+
+		format := snapshotFlags.output
+		if format == "" {
+			format = snapshot.FormatFromPath(snapshotFlags.file)
+		}
+
+		var out io.Writer = os.Stdout
+		if snapshotFlags.file != "" && snapshotFlags.file != "-" {
+			f, err := os.Create(snapshotFlags.file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		return snapshot.Write(out, s, format)
+	},
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <a> <b>",
+	Short: "Compare two inventory snapshots, or a snapshot with a running Sentinel",
+	Long: `Report the workloads added and removed, and the container images changed, between <a> and <b>.
+Each side is a snapshot file ('-' for stdin) or the URL of a running Sentinel.
+
+Examples:
+  sentinel diff staging.json http://sentinel.prod:9090
+  sentinel diff last-week.yaml http://localhost:9090 -o markdown`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, err := loadSnapshot(args[0])
+		if err != nil {
+			return err
+		}
+		to, err := loadSnapshot(args[1])
+		if err != nil {
+			return err
+		}
+
+		report := snapshot.Diff(from, to, args[0], args[1])
+		switch snapshotFlags.diffOutput {
+		case "text":
+			return report.WriteText(os.Stdout)
+		case "markdown":
+			return report.WriteMarkdown(os.Stdout)
+		case "json":
+			return printJSON(report)
+		default:
+			return fmt.Errorf("unknown output format %q (supported: text, json, markdown)", snapshotFlags.diffOutput)
+		}
+	},
+}
+
+func init() {
+	snapshotCmd.Flags().StringVar(&snapshotFlags.server, "server", "http://localhost:9090", "URL of a running Sentinel")
+	snapshotCmd.Flags().StringVarP(&snapshotFlags.file, "file", "f", "", "write the snapshot to this file instead of stdout")
+	snapshotCmd.Flags().StringVarP(&snapshotFlags.output, "output", "o", "", "snapshot format: json or yaml (default: from the file extension, else json)")
+
+	diffCmd.Flags().StringVarP(&snapshotFlags.diffOutput, "output", "o", "text", "output format: text, json or markdown")
+
+	rootCmd.AddCommand(snapshotCmd, diffCmd)
+}
+
+// fetchSnapshot asks a running Sentinel for a snapshot of its inventory
+func fetchSnapshot(server string) (snapshot.Snapshot, error) {
+	var s snapshot.Snapshot
+	if err := getJSON(server, "/api/v1/snapshot", nil, &s); err != nil {
+		return snapshot.Snapshot{}, err
+	}
+	if s.SchemaVersion != snapshot.SchemaVersion {
+		return snapshot.Snapshot{}, fmt.Errorf("%s returned an unsupported snapshot schemaVersion %q", server, s.SchemaVersion)
+	}
+	return s, nil
+}
+
+// loadSnapshot reads a snapshot from a file, or from a running Sentinel when given a URL
+func loadSnapshot(source string) (snapshot.Snapshot, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return fetchSnapshot(source)
+	}
+	return snapshot.Load(source)
+}
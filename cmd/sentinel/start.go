@@ -5,6 +5,7 @@ import (
 
 	"github.com/MatteoMori/sentinel/pkg/sentinel"
 	sentinelShared "github.com/MatteoMori/sentinel/pkg/shared"
+	"github.com/MatteoMori/sentinel/pkg/updates"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -35,7 +36,80 @@ func init() {
 	viper.SetDefault("namespaceSelector", map[string]string{"sentinel.io/controlled": "enabled"})
 	viper.SetDefault("metricsPort", "9090") // Default port for Prometheus metrics endpoint
 	viper.SetDefault("verbosity", 0)
+	viper.SetDefault("clusterName", "default")
 	viper.SetDefault("extraLabels", []sentinelShared.ExtraLabel{}) // Empty by default
+	viper.SetDefault("events.bufferSize", 1000)                    // Past change events kept for SSE clients resuming a stream
+	viper.SetDefault("grpc.enabled", false)
+	viper.SetDefault("grpc.port", "9091")
+	viper.SetDefault("audit.enabled", false)
+	viper.SetDefault("audit.output", "stdout")
+	viper.SetDefault("audit.maxSizeMB", 100)
+	viper.SetDefault("audit.maxAge", "24h")
+	viper.SetDefault("audit.maxBackups", 7)
+	viper.SetDefault("history.enabled", false)
+	viper.SetDefault("history.path", "/var/lib/sentinel/history.db")
+	viper.SetDefault("history.retention", "2160h") // 90 days
+	viper.SetDefault("history.compactionInterval", "1h")
+	viper.SetDefault("backfill.enabled", false)
+	viper.SetDefault("drift.enabled", false)
+	viper.SetDefault("drift.path", "/var/lib/sentinel/desired-state")
+	viper.SetDefault("drift.interval", "1m")
+	viper.SetDefault("drift.defaultNamespace", "default")
+	viper.SetDefault("bom.enabled", false)
+	viper.SetDefault("bom.path", "")
+	viper.SetDefault("bom.configMap.namespace", "kube-system")
+	viper.SetDefault("bom.configMap.name", "")
+	viper.SetDefault("bom.configMap.key", "bom.yaml")
+	viper.SetDefault("bom.reloadInterval", "30s")
+	viper.SetDefault("vulnerabilities.enabled", false)
+	viper.SetDefault("vulnerabilities.path", "")
+	viper.SetDefault("vulnerabilities.configMaps.namespace", "")
+	viper.SetDefault("vulnerabilities.configMaps.labelSelector", "")
+	viper.SetDefault("vulnerabilities.reloadInterval", "1m")
+	viper.SetDefault("eol.enabled", false)
+	viper.SetDefault("eol.path", "")
+	viper.SetDefault("eol.reloadInterval", "1m")
+	viper.SetDefault("policy.enabled", false)
+	viper.SetDefault("policy.allowedRegistries", []string{}) // Any registry
+	viper.SetDefault("policy.forbiddenTags", []string{"latest"})
+	viper.SetDefault("policy.requireDigest.enabled", false)
+	viper.SetDefault("policy.requireDigest.namespaceSelector", map[string]string{})
+	viper.SetDefault("policy.forbiddenPullPolicies", []string{"Never"})
+	viper.SetDefault("policy.severities", map[string]string{})
+	viper.SetDefault("policy.disabledRules", []string{})
+	viper.SetDefault("policy.customRules", []sentinelShared.CustomRule{})
+	viper.SetDefault("admission.port", "8443")
+	viper.SetDefault("admission.certFile", "/etc/sentinel/tls/tls.crt")
+	viper.SetDefault("admission.keyFile", "/etc/sentinel/tls/tls.key")
+	viper.SetDefault("admission.defaultAction", "warn")
+	viper.SetDefault("admission.actions", []sentinelShared.AdmissionAction{})
+	viper.SetDefault("admission.pinDigests.enabled", false)
+	viper.SetDefault("admission.pinDigests.namespaceSelector", map[string]string{})
+	viper.SetDefault("admission.pinDigests.registries", []string{})
+	viper.SetDefault("admission.pinDigests.cacheTTL", "5m")
+	viper.SetDefault("registries", []sentinelShared.RegistryConfig{})
+	viper.SetDefault("imageMetadata.enabled", false)
+	viper.SetDefault("imageMetadata.usePullSecrets", false)
+	viper.SetDefault("imageMetadata.cacheSize", 1000)
+	viper.SetDefault("imageMetadata.cacheTTL", "1h")
+	viper.SetDefault("imageMetadata.concurrency", 4)
+	viper.SetDefault("imageMetadata.refreshInterval", "1h")
+	viper.SetDefault("imageMetadata.sourceInfo", false)
+	viper.SetDefault("updates.enabled", false)
+	viper.SetDefault("updates.repositories", []string{})
+	viper.SetDefault("updates.interval", "6h")
+	viper.SetDefault("updates.includePrereleases", false)
+	viper.SetDefault("updates.prereleasePattern", updates.DefaultPrereleasePattern)
+	viper.SetDefault("updates.matchVariant", true)
+	viper.SetDefault("updates.usePullSecrets", false)
+	viper.SetDefault("updates.concurrency", 4)
+	viper.SetDefault("signatures.enabled", false)
+	viper.SetDefault("signatures.publicKeys", []string{})
+	viper.SetDefault("signatures.repositories", []string{})
+	viper.SetDefault("signatures.interval", "1h")
+	viper.SetDefault("signatures.cacheSize", 1000)
+	viper.SetDefault("signatures.usePullSecrets", false)
+	viper.SetDefault("signatures.concurrency", 4)
 
 	// Start the sentinel command
 	rootCmd.AddCommand(startSentinel)
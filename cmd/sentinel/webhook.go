@@ -0,0 +1,84 @@
+package sentinel
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/admission"
+	"github.com/MatteoMori/sentinel/pkg/policy"
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	"github.com/MatteoMori/sentinel/pkg/registry"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Run the validating admission webhook enforcing the image policies",
+	Long: `Serve a validating admission webhook (admission.k8s.io/v1) on /validate that checks the CREATE and UPDATE of
+Deployments, StatefulSets and DaemonSets against the same image policies as the controller. Each violation is
+enforced (denied), warned about or only recorded (dryrun) as set by the admission section of the config.
+With admission.pinDigests.enabled, also serves a mutating webhook on /mutate that rewrites repo:tag images to
+repo:tag@sha256:..., resolving the tags through the registry API.
+Serves TLS from admission.certFile and admission.keyFile, reloaded when they change.`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := policy.NewEngine(config.Policy)
+		if err != nil {
+			return err
+		}
+
+		restConfig, err := rest.InClusterConfig()
+		if err != nil {
+			return fmt.Errorf("unable to load the in-cluster config: %w", err)
+		}
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return err
+		}
+
+		// Namespace labels for the namespaceSelector of the actions and the namespaceLabels of custom rules
+		factory := informers.NewSharedInformerFactory(clientset, 10*time.Minute)
+		namespaces := factory.Core().V1().Namespaces()
+		lister := namespaces.Lister()
+		informer := namespaces.Informer()
+		stop := make(chan struct{})
+		factory.Start(stop)
+		if !cache.WaitForCacheSync(stop, informer.HasSynced) {
+			return fmt.Errorf("unable to sync the namespace cache")
+		}
+
+		namespaceLabels := func(namespace string) map[string]string {
+			ns, err := lister.Get(namespace)
+			if err != nil {
+				return nil
+			}
+			return labels.Set(ns.Labels)
+		}
+		validator, err := admission.NewValidator(engine, config, namespaceLabels)
+		if err != nil {
+			return err
+		}
+		handlers := map[string]http.Handler{"/validate": validator}
+		if config.Admission.PinDigests.Enabled {
+			client, err := registry.NewClient(config.Registries)
+			if err != nil {
+				return err
+			}
+			handlers["/mutate"] = admission.NewMutator(client, config.Admission.PinDigests, namespaceLabels)
+		}
+		cmd.SilenceUsage = true
+
+		SentinelPrometheus.Init(config.MetricsPort, config.ExtraLabels)
+		return admission.Serve(config.Admission.Port, config.Admission.CertFile, config.Admission.KeyFile, handlers)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(webhookCmd)
+}
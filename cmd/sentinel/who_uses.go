@@ -0,0 +1,107 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MatteoMori/sentinel/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+// whoUsesFlags holds the flags of the who-uses command
+var whoUsesFlags struct {
+	server     string
+	registry   string
+	repository string
+	tag        string
+	digest     string
+	tagRange   string
+	output     string
+}
+
+var whoUsesCmd = &cobra.Command{
+	Use:   "who-uses",
+	Short: "List the workloads and containers running a given image",
+	Long: `Ask a running Sentinel which workloads and containers run an image.
+Registry, repository, tag and digest accept '*' and '?' globs; --tag-range accepts a semver constraint.
+
+Example:
+  sentinel who-uses --repository base/debian --tag-range ">=12.1, <=12.4"`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		params := url.Values{}
+		for key, value := range map[string]string{
+			"registry":   whoUsesFlags.registry,
+			"repository": whoUsesFlags.repository,
+			"tag":        whoUsesFlags.tag,
+			"digest":     whoUsesFlags.digest,
+			"tagRange":   whoUsesFlags.tagRange,
+		} {
+			if value != "" {
+				params.Set(key, value)
+			}
+		}
+
+		var response api.WhoUsesResponse
+		if err := getJSON(whoUsesFlags.server, "/api/v1/who-uses", params, &response); err != nil {
+			return err
+		}
+
+		switch whoUsesFlags.output {
+		case "json":
+			return printJSON(response)
+		case "table":
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAMESPACE\tKIND\tWORKLOAD\tCONTAINER\tIMAGE")
+			for _, m := range response.Matches {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", m.Namespace, m.Kind, m.Workload, m.Container, m.Image.Reference)
+			}
+			return tw.Flush()
+		default:
+			return fmt.Errorf("unknown output format %q (supported: table, json)", whoUsesFlags.output)
+		}
+	},
+}
+
+func init() {
+	whoUsesCmd.Flags().StringVar(&whoUsesFlags.server, "server", "http://localhost:9090", "URL of a running Sentinel")
+	whoUsesCmd.Flags().StringVar(&whoUsesFlags.registry, "registry", "", "image registry (glob), e.g. ghcr.io")
+	whoUsesCmd.Flags().StringVar(&whoUsesFlags.repository, "repository", "", "image repository (glob), e.g. base/debian")
+	whoUsesCmd.Flags().StringVar(&whoUsesFlags.tag, "tag", "", "image tag (glob), e.g. 12.*")
+	whoUsesCmd.Flags().StringVar(&whoUsesFlags.digest, "digest", "", "image digest (glob), e.g. sha256:4f2a...")
+	whoUsesCmd.Flags().StringVar(&whoUsesFlags.tagRange, "tag-range", "", "semver constraint on the tag, e.g. \">=12.1, <=12.4\"")
+	whoUsesCmd.Flags().StringVarP(&whoUsesFlags.output, "output", "o", "table", "output format: table or json")
+
+	rootCmd.AddCommand(whoUsesCmd)
+}
+
+// getJSON calls a Sentinel API endpoint and decodes its JSON response
+func getJSON(server, path string, params url.Values, into any) error {
+	endpoint := strings.TrimSuffix(server, "/") + path
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+
+	resp, err := http.Get(endpoint)
+	if err != nil {
+		return fmt.Errorf("unable to reach Sentinel at %s: %w", server, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("sentinel API returned %s: %s", resp.Status, apiErr.Error)
+		}
+		return fmt.Errorf("sentinel API returned %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(into)
+}
@@ -0,0 +1,244 @@
+/*
+Validating admission webhook enforcing the image policies.
+
+SCOPE:
+- Review the CREATE and UPDATE of the workloads Sentinel tracks (Deployments, StatefulSets, DaemonSets)
+  with the same policy engine as the controller and sentinel scan
+- Decide, for every violation, the action of the first matching entry of admission.actions (by rule and namespace
+  selector), or admission.defaultAction:
+    - enforce: the request is denied
+    - warn:    the request is allowed, with a warning returned to the client (kubectl prints it)
+    - dryrun:  the request is allowed silently; the violation is only logged and counted
+- On UPDATE, only the violations the workload didn't already have are enforced: the others are warned, so that
+  scaling or editing a workload admitted before a rule existed is never blocked
+- Optionally pin the images of the same workloads to their digest (mutate.go)
+- Serve AdmissionReview (admission.k8s.io/v1) over TLS, with a certificate reloaded when the mounted files change
+*/
+
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/MatteoMori/sentinel/pkg/manifests"
+	"github.com/MatteoMori/sentinel/pkg/policy"
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Action is what the webhook does with a violation
+type Action string
+
+const (
+	ActionEnforce Action = "enforce"
+	ActionWarn    Action = "warn"
+	ActionDryRun  Action = "dryrun"
+)
+
+// ParseAction validates an action name
+func ParseAction(s string) (Action, error) {
+	switch action := Action(s); action {
+	case ActionEnforce, ActionWarn, ActionDryRun:
+		return action, nil
+	}
+	return "", fmt.Errorf("unknown webhook action %q (supported: enforce, warn, dryrun)", s)
+}
+
+// maxRequestBytes bounds the size of an AdmissionReview body
+const maxRequestBytes = 3 << 20
+
+// actionRule is a parsed entry of admission.actions
+type actionRule struct {
+	rules             []string // Empty for every rule
+	namespaceSelector map[string]string
+	action            Action
+}
+
+// Validator reviews workloads against the image policies
+type Validator struct {
+	engine          *policy.Engine
+	extraLabels     []shared.ExtraLabel
+	defaultAction   Action
+	actions         []actionRule
+	namespaceLabels func(namespace string) map[string]string
+}
+
+// NewValidator builds a validator. namespaceLabels returns the labels of a namespace (nil when unknown).
+func NewValidator(engine *policy.Engine, cfg shared.Config, namespaceLabels func(namespace string) map[string]string) (*Validator, error) {
+	defaultAction, err := ParseAction(cfg.Admission.DefaultAction)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Validator{
+		engine:          engine,
+		extraLabels:     cfg.ExtraLabels,
+		defaultAction:   defaultAction,
+		namespaceLabels: namespaceLabels,
+	}
+	for i, a := range cfg.Admission.Actions {
+		action, err := ParseAction(a.Action)
+		if err != nil {
+			return nil, fmt.Errorf("admission.actions[%d]: %w", i, err)
+		}
+		v.actions = append(v.actions, actionRule{rules: a.Rules, namespaceSelector: a.NamespaceSelector, action: action})
+	}
+	return v, nil
+}
+
+// actionFor returns the action of the first entry matching a violation, or the default action
+func (v *Validator) actionFor(violation policy.Violation, labels map[string]string) Action {
+	for _, a := range v.actions {
+		if len(a.rules) > 0 && !slices.Contains(a.rules, violation.Rule) {
+			continue
+		}
+		if !matchesSelector(labels, a.namespaceSelector) {
+			continue
+		}
+		return a.action
+	}
+	return v.defaultAction
+}
+
+// Review answers an admission request
+func (v *Validator) Review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: req.UID, Allowed: true}
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return response
+	}
+
+	w, tracked, err := manifests.DecodeWorkload(req.Object.Raw, req.Namespace)
+	if err != nil {
+		// Let the API server report malformed objects: failing here would only hide its error
+		slog.Error("Unable to decode admission object", slog.String("kind", req.Kind.Kind), slog.String("ns/name", req.Namespace+"/"+req.Name), slog.Any("error", err))
+		return response
+	}
+	if !tracked {
+		return response
+	}
+
+	labels := v.namespaceLabels(w.Namespace)
+	violations := v.evaluate(w, labels)
+	existing := v.existingViolations(req, labels)
+
+	var denied []string
+	for _, violation := range violations {
+		action := v.actionFor(violation, labels)
+		if action == ActionEnforce && existing[violationKey(violation)] {
+			action = ActionWarn
+		}
+		text := fmt.Sprintf("[%s] container %s: %s", violation.Rule, violation.Container, violation.Message)
+		SentinelPrometheus.SentinelAdmissionViolationsTotal.WithLabelValues(violation.Rule, string(violation.Severity), string(action)).Inc()
+		slog.Info("Image policy violation at admission",
+			slog.String("operation", string(req.Operation)),
+			slog.String("ns/workload", w.Namespace+"/"+w.Name()),
+			slog.String("kind", w.Kind),
+			slog.String("container", violation.Container),
+			slog.String("rule", violation.Rule),
+			slog.String("action", string(action)))
+
+		switch action {
+		case ActionEnforce:
+			denied = append(denied, text)
+		case ActionWarn:
+			response.Warnings = append(response.Warnings, text)
+		}
+	}
+
+	decision := "allowed"
+	switch {
+	case len(denied) > 0:
+		decision = "denied"
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonForbidden,
+			Code:    http.StatusForbidden,
+			Message: fmt.Sprintf("%s %s/%s violates Sentinel image policies: %s", w.Kind, w.Namespace, w.Name(), strings.Join(denied, "; ")),
+		}
+	case len(response.Warnings) > 0:
+		decision = "warned"
+	}
+	SentinelPrometheus.SentinelAdmissionReviewsTotal.WithLabelValues(w.Kind, string(req.Operation), decision).Inc()
+	return response
+}
+
+// evaluate returns the violations of a workload
+func (v *Validator) evaluate(w manifests.Workload, labels map[string]string) []policy.Violation {
+	return v.engine.Evaluate(policy.Target{
+		Workload:        w.Record(v.extraLabels),
+		Containers:      w.Containers,
+		NamespaceLabels: labels,
+		Object:          w.Object,
+	})
+}
+
+// existingViolations returns the violations the workload had before an UPDATE (by violationKey), nil for other operations
+func (v *Validator) existingViolations(req *admissionv1.AdmissionRequest, labels map[string]string) map[string]bool {
+	if req.Operation != admissionv1.Update || len(req.OldObject.Raw) == 0 {
+		return nil
+	}
+	old, tracked, err := manifests.DecodeWorkload(req.OldObject.Raw, req.Namespace)
+	if err != nil || !tracked {
+		return nil // Every violation is then treated as new
+	}
+	existing := make(map[string]bool)
+	for _, violation := range v.evaluate(old, labels) {
+		existing[violationKey(violation)] = true
+	}
+	return existing
+}
+
+// violationKey identifies a violation across two versions of a workload: the same rule broken by the same container image
+func violationKey(violation policy.Violation) string {
+	return violation.Rule + "\x00" + violation.Container + "\x00" + violation.Image
+}
+
+// ServeHTTP decodes an AdmissionReview, reviews its request and writes the AdmissionReview response
+func (v *Validator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveReview(w, r, v.Review)
+}
+
+// serveReview decodes an AdmissionReview, answers its request with review and writes the AdmissionReview response
+func serveReview(w http.ResponseWriter, r *http.Request, review func(*admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var admissionReview admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &admissionReview); err != nil || admissionReview.Request == nil {
+		http.Error(w, "expected an admission.k8s.io/v1 AdmissionReview", http.StatusBadRequest)
+		return
+	}
+
+	admissionReview.Response = review(admissionReview.Request)
+	admissionReview.Request = nil // The API server only reads the response
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(admissionReview); err != nil {
+		slog.Error("Failed to encode AdmissionReview response", slog.Any("error", err))
+	}
+}
+
+// matchesSelector reports whether labels match every key/value of a selector (an empty selector matches everything)
+func matchesSelector(labels, selector map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
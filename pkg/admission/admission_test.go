@@ -0,0 +1,167 @@
+package admission
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MatteoMori/sentinel/pkg/policy"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// newTestValidator forbids the latest tag: enforced in prod, dry run in sandbox, warned elsewhere
+func newTestValidator(t *testing.T) *Validator {
+	t.Helper()
+	engine, err := policy.NewEngine(shared.PolicyConfig{ForbiddenTags: []string{"latest"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := shared.Config{Admission: shared.AdmissionConfig{
+		DefaultAction: "warn",
+		Actions: []shared.AdmissionAction{
+			{Rules: []string{policy.RuleForbiddenTag}, NamespaceSelector: map[string]string{"env": "prod"}, Action: "enforce"},
+			{NamespaceSelector: map[string]string{"env": "sandbox"}, Action: "dryrun"},
+		},
+	}}
+	namespaces := map[string]map[string]string{
+		"prod":    {"env": "prod"},
+		"dev":     {"env": "dev"},
+		"sandbox": {"env": "sandbox"},
+	}
+	v, err := NewValidator(engine, cfg, func(namespace string) map[string]string { return namespaces[namespace] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// deployment returns the JSON of a Deployment running one container
+func deployment(t *testing.T, namespace, image string, replicas int32) runtime.RawExtension {
+	t.Helper()
+	deploy := appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: namespace},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "api", Image: image}}}},
+		},
+	}
+	data, err := json.Marshal(deploy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime.RawExtension{Raw: data}
+}
+
+func TestReview(t *testing.T) {
+	v := newTestValidator(t)
+
+	cronJob, err := json.Marshal(batchv1.CronJob{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "CronJob"},
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "prod"},
+		Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "backup", Image: "backup:latest"}}},
+		}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		operation admissionv1.Operation
+		namespace string
+		object    runtime.RawExtension
+		oldObject runtime.RawExtension
+		allowed   bool
+		warnings  int
+	}{
+		{"enforce", admissionv1.Create, "prod", deployment(t, "prod", "nginx:latest", 1), runtime.RawExtension{}, false, 0},
+		{"warn", admissionv1.Create, "dev", deployment(t, "dev", "nginx:latest", 1), runtime.RawExtension{}, true, 1},
+		{"dryrun", admissionv1.Create, "sandbox", deployment(t, "sandbox", "nginx:latest", 1), runtime.RawExtension{}, true, 0},
+		{"compliant", admissionv1.Create, "prod", deployment(t, "prod", "nginx:1.27", 1), runtime.RawExtension{}, true, 0},
+		{"update new violation", admissionv1.Update, "prod", deployment(t, "prod", "nginx:latest", 1), deployment(t, "prod", "nginx:1.27", 1), false, 0},
+		{"update existing violation", admissionv1.Update, "prod", deployment(t, "prod", "nginx:latest", 3), deployment(t, "prod", "nginx:latest", 1), true, 1},
+		{"update other image", admissionv1.Update, "prod", deployment(t, "prod", "redis:latest", 1), deployment(t, "prod", "nginx:latest", 1), false, 0},
+		{"delete", admissionv1.Delete, "prod", deployment(t, "prod", "nginx:latest", 1), runtime.RawExtension{}, true, 0},
+		{"connect", admissionv1.Connect, "prod", deployment(t, "prod", "nginx:latest", 1), runtime.RawExtension{}, true, 0},
+		{"untracked kind", admissionv1.Create, "prod", runtime.RawExtension{Raw: cronJob}, runtime.RawExtension{}, true, 0},
+		{"malformed object", admissionv1.Create, "prod", runtime.RawExtension{Raw: []byte(`{"kind": "Deployment", "apiVersion": "apps/v1", "spec": 1}`)}, runtime.RawExtension{}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := v.Review(&admissionv1.AdmissionRequest{
+				UID:       "uid-1",
+				Operation: tt.operation,
+				Namespace: tt.namespace,
+				Object:    tt.object,
+				OldObject: tt.oldObject,
+			})
+			if resp.UID != "uid-1" {
+				t.Fatalf("got UID %q, want uid-1", resp.UID)
+			}
+			if resp.Allowed != tt.allowed || len(resp.Warnings) != tt.warnings {
+				t.Fatalf("got allowed=%t warnings=%v, want allowed=%t and %d warnings", resp.Allowed, resp.Warnings, tt.allowed, tt.warnings)
+			}
+			if !resp.Allowed && (resp.Result == nil || resp.Result.Code != http.StatusForbidden || !strings.Contains(resp.Result.Message, policy.RuleForbiddenTag)) {
+				t.Fatalf("unexpected denial %+v", resp.Result)
+			}
+		})
+	}
+}
+
+func TestServeReview(t *testing.T) {
+	v := newTestValidator(t)
+	review, err := json.Marshal(admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "uid-2",
+			Operation: admissionv1.Create,
+			Namespace: "prod",
+			Object:    deployment(t, "prod", "nginx:latest", 1),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+	}{
+		{"review", http.MethodPost, string(review), http.StatusOK},
+		{"method", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"malformed body", http.MethodPost, "{not json", http.StatusBadRequest},
+		{"no request", http.MethodPost, `{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			v.ServeHTTP(rec, httptest.NewRequest(tt.method, "/validate", strings.NewReader(tt.body)))
+			if rec.Code != tt.code {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.code)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			var got admissionv1.AdmissionReview
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Request != nil || got.Response == nil || got.Response.UID != "uid-2" || got.Response.Allowed {
+				t.Fatalf("unexpected AdmissionReview %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/manifests"
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	"github.com/MatteoMori/sentinel/pkg/registry"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// OriginalImagesAnnotation records, on the workload, the images as written before they were pinned: {"<container>": "<image>"}
+const OriginalImagesAnnotation = "sentinel.io/original-images"
+
+// resolveTimeout bounds the registry lookups of one admission request, well within the webhook timeout
+const resolveTimeout = 3 * time.Second
+
+// Mutator rewrites the repo:tag images of workloads to repo:tag@sha256:..., the digest the tag points to at admission
+type Mutator struct {
+	client            *registry.Client
+	namespaceSelector map[string]string
+	registries        []string
+	cacheTTL          time.Duration
+	namespaceLabels   func(namespace string) map[string]string
+
+	mu      sync.Mutex
+	digests map[string]cachedDigest // "registry/repository:tag" -> digest
+}
+
+// cachedDigest is a resolved digest and when it was resolved
+type cachedDigest struct {
+	digest   string
+	resolved time.Time
+}
+
+// patchOperation is an RFC 6902 JSON patch operation
+type patchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value,omitempty"`
+}
+
+// podTemplate holds the containers of the pod template of a workload, init containers included
+type podTemplate struct {
+	Spec struct {
+		Template struct {
+			Spec struct {
+				InitContainers []corev1.Container `json:"initContainers"`
+				Containers     []corev1.Container `json:"containers"`
+			} `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+}
+
+// NewMutator builds a mutator resolving digests with a registry client
+func NewMutator(client *registry.Client, cfg shared.PinDigestsConfig, namespaceLabels func(namespace string) map[string]string) *Mutator {
+	return &Mutator{
+		client:            client,
+		namespaceSelector: cfg.NamespaceSelector,
+		registries:        cfg.Registries,
+		cacheTTL:          cfg.CacheTTL,
+		namespaceLabels:   namespaceLabels,
+		digests:           make(map[string]cachedDigest),
+	}
+}
+
+/*
+Review answers an admission request with a patch pinning every image that has a tag but no digest.
+A failed lookup never blocks the request: the image is admitted as is, with a warning.
+*/
+func (m *Mutator) Review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: req.UID, Allowed: true}
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return response
+	}
+
+	w, tracked, err := manifests.DecodeWorkload(req.Object.Raw, req.Namespace)
+	if err != nil || !tracked {
+		return response
+	}
+	if !matchesSelector(m.namespaceLabels(w.Namespace), m.namespaceSelector) {
+		return response
+	}
+	var template podTemplate
+	if err := json.Unmarshal(req.Object.Raw, &template); err != nil {
+		return response
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	var patch []patchOperation
+	original := m.originalImages(w, template)
+	pin := func(field string, containers []corev1.Container) {
+		for i, c := range containers {
+			image := inventory.ParseImage(c.Image)
+			if image.Digest != "" || !m.pinnable(image) {
+				continue
+			}
+			digest, err := m.resolve(ctx, image)
+			if err != nil {
+				SentinelPrometheus.SentinelAdmissionPinnedImagesTotal.WithLabelValues(image.Registry, "failed").Inc()
+				slog.Warn("Unable to pin image to a digest",
+					slog.String("ns/workload", w.Namespace+"/"+w.Name()),
+					slog.String("container", c.Name),
+					slog.String("image", c.Image),
+					slog.Any("error", err))
+				response.Warnings = append(response.Warnings, fmt.Sprintf("container %s: image %s not pinned to a digest: %v", c.Name, c.Image, err))
+				continue
+			}
+			SentinelPrometheus.SentinelAdmissionPinnedImagesTotal.WithLabelValues(image.Registry, "pinned").Inc()
+
+			// Keep the tag next to the digest: it stays readable, and it is still the image_tag label of the metrics
+			pinned := c.Image
+			if image.TagDefaulted() {
+				pinned += ":" + image.Tag
+			}
+			pinned += "@" + digest
+			patch = append(patch, patchOperation{Op: "replace", Path: fmt.Sprintf("/spec/template/spec/%s/%d/image", field, i), Value: pinned})
+			original[c.Name] = c.Image
+			slog.Info("Pinned image to its digest",
+				slog.String("ns/workload", w.Namespace+"/"+w.Name()),
+				slog.String("container", c.Name),
+				slog.String("image", pinned))
+		}
+	}
+	pin("initContainers", template.Spec.Template.Spec.InitContainers)
+	pin("containers", template.Spec.Template.Spec.Containers)
+	if len(patch) == 0 {
+		return response
+	}
+
+	value, _ := json.Marshal(original)
+	if w.Object.GetAnnotations() == nil {
+		patch = append(patch, patchOperation{Op: "add", Path: "/metadata/annotations", Value: map[string]string{OriginalImagesAnnotation: string(value)}})
+	} else {
+		patch = append(patch, patchOperation{Op: "add", Path: "/metadata/annotations/" + escapePointer(OriginalImagesAnnotation), Value: string(value)})
+	}
+
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		slog.Error("Failed to encode the image patch", slog.Any("error", err))
+		return response
+	}
+	patchType := admissionv1.PatchTypeJSONPatch
+	response.Patch, response.PatchType = patchBytes, &patchType
+	return response
+}
+
+// ServeHTTP decodes an AdmissionReview, pins its images and writes the AdmissionReview response
+func (m *Mutator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveReview(w, r, m.Review)
+}
+
+// pinnable reports whether the images of a registry are to be pinned
+func (m *Mutator) pinnable(image inventory.Image) bool {
+	return len(m.registries) == 0 || slices.ContainsFunc(m.registries, func(glob string) bool { return inventory.MatchGlob(glob, image.Registry) })
+}
+
+// resolve returns the digest of an image tag, from the cache while it is fresh
+func (m *Mutator) resolve(ctx context.Context, image inventory.Image) (string, error) {
+	key := image.RepositoryKey() + ":" + image.Tag
+	m.mu.Lock()
+	cached, ok := m.digests[key]
+	m.mu.Unlock()
+	if ok && time.Since(cached.resolved) < m.cacheTTL {
+		return cached.digest, nil
+	}
+
+	digest, err := m.client.ResolveDigest(ctx, image, nil)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	m.digests[key] = cachedDigest{digest: digest, resolved: time.Now()}
+	// Drop the stale entries now and then, so that the cache doesn't grow with every tag ever admitted
+	if len(m.digests)%256 == 0 {
+		for k, d := range m.digests {
+			if time.Since(d.resolved) >= m.cacheTTL {
+				delete(m.digests, k)
+			}
+		}
+	}
+	m.mu.Unlock()
+	return digest, nil
+}
+
+// originalImages returns the original images recorded on the workload, for the containers still pinned by digest
+func (m *Mutator) originalImages(w manifests.Workload, template podTemplate) map[string]string {
+	original := make(map[string]string)
+	var recorded map[string]string
+	if err := json.Unmarshal([]byte(w.Object.GetAnnotations()[OriginalImagesAnnotation]), &recorded); err != nil {
+		return original
+	}
+	spec := template.Spec.Template.Spec
+	for _, c := range append(spec.InitContainers, spec.Containers...) {
+		if image, ok := recorded[c.Name]; ok && inventory.ParseImage(c.Image).Digest != "" {
+			original[c.Name] = image
+		}
+	}
+	return original
+}
+
+// escapePointer escapes a key for a JSON pointer (RFC 6901)
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,79 @@
+package admission
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// certificate serves the key pair of the mounted files, reloaded when they change (e.g. renewed by cert-manager)
+type certificate struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// get returns the current key pair, reloading it first when the certificate file changed
+func (c *certificate) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, err := os.Stat(c.certFile)
+	if err != nil {
+		if c.cert != nil {
+			return c.cert, nil // Keep serving the previous certificate during a symlink swap
+		}
+		return nil, err
+	}
+	if c.cert != nil && info.ModTime().Equal(c.modTime) {
+		return c.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		if c.cert != nil {
+			slog.Error("Unable to reload the webhook certificate, keeping the previous one", slog.Any("error", err))
+			return c.cert, nil
+		}
+		return nil, err
+	}
+	if c.cert != nil {
+		slog.Info("Reloaded the webhook certificate", slog.String("file", c.certFile))
+	}
+	c.cert, c.modTime = &cert, info.ModTime()
+	return c.cert, nil
+}
+
+// Serve runs the webhook HTTPS server with handlers by path, plus /healthz for the probes. It only returns on error.
+func Serve(port, certFile, keyFile string, handlers map[string]http.Handler) error {
+	cert := &certificate{certFile: certFile, keyFile: keyFile}
+	if _, err := cert.get(nil); err != nil {
+		return fmt.Errorf("unable to load the webhook certificate: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	for path, handler := range handlers {
+		mux.Handle(path, handler)
+	}
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: cert.get,
+		},
+	}
+	slog.Info("Starting admission webhook server", slog.String("port", port))
+	return server.ListenAndServeTLS("", "")
+}
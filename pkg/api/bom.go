@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/MatteoMori/sentinel/pkg/bom"
+)
+
+// InitBOM registers the BOM compliance handler (only when the BOM is enabled)
+func InitBOM(checker *bom.Checker) {
+	http.HandleFunc("GET /api/v1/bom", bomHandler(checker))
+}
+
+/*
+bomHandler returns, for every BOM component, the number of instances and the ones not running an allowed version
+Example:
+
+	GET /api/v1/bom
+*/
+func bomHandler(checker *bom.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, checker.Summary())
+	}
+}
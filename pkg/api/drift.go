@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/MatteoMori/sentinel/pkg/drift"
+)
+
+// InitDrift registers the drift handler (only when drift detection is enabled)
+func InitDrift(detector *drift.Detector) {
+	http.HandleFunc("GET /api/v1/drift", driftHandler(detector))
+}
+
+/*
+driftHandler returns the workloads whose live image differs from the desired state manifests
+Query parameters: namespace, type (image, missing_in_cluster, missing_in_git), both optional
+Example:
+
+	GET /api/v1/drift?namespace=prod-payments&type=image
+*/
+func driftHandler(detector *drift.Detector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := detector.Report()
+		namespace, driftType := r.URL.Query().Get("namespace"), r.URL.Query().Get("type")
+
+		if namespace != "" || driftType != "" {
+			filtered := []drift.Drift{}
+			for _, d := range report.Drifts {
+				if (namespace == "" || d.Namespace == namespace) && (driftType == "" || string(d.Type) == driftType) {
+					filtered = append(filtered, d)
+				}
+			}
+			report.Drifts, report.Count = filtered, len(filtered)
+		}
+
+		writeJSON(w, http.StatusOK, report)
+	}
+}
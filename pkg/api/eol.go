@@ -0,0 +1,48 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/MatteoMori/sentinel/pkg/eol"
+)
+
+// eolChecker adds the EOL status of the containers to the inventory responses, when EOL detection is enabled
+var eolChecker *eol.Checker
+
+// EOLResponse is the body of GET /api/v1/eol
+type EOLResponse struct {
+	Count   int          `json:"count"`
+	Results []eol.Result `json:"results"`
+}
+
+// InitEOL registers the EOL handler (only when EOL detection is enabled)
+func InitEOL(checker *eol.Checker) {
+	eolChecker = checker
+	http.HandleFunc("GET /api/v1/eol", eolHandler(checker))
+}
+
+/*
+eolHandler returns the release cycle and EOL date of every container whose image the EOL catalogue knows
+Query parameters: namespace, withinDays (only containers already EOL or reaching it within that many days), both optional
+Example:
+
+	GET /api/v1/eol?namespace=prod&withinDays=90
+*/
+func eolHandler(checker *eol.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		withinDays := -1
+		if value := r.URL.Query().Get("withinDays"); value != "" {
+			days, err := strconv.Atoi(value)
+			if err != nil || days < 0 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("withinDays must be a positive number of days"))
+				return
+			}
+			withinDays = days
+		}
+
+		results := checker.Results(r.URL.Query().Get("namespace"), withinDays)
+		writeJSON(w, http.StatusOK, EOLResponse{Count: len(results), Results: results})
+	}
+}
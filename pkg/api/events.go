@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+)
+
+// sseHeartbeatInterval keeps idle connections open through proxies and load balancers
+const sseHeartbeatInterval = 15 * time.Second
+
+/*
+eventsHandler streams image events (added, changed, removed) using Server-Sent Events.
+
+  - Each event is sent with its ID, so browsers/clients automatically resume with the Last-Event-ID header
+  - The ID can also be passed as ?lastEventId=<id> for clients that can't set headers
+  - Optional filters: ?namespace=<ns>&kind=<kind>&type=<image.added|image.changed|image.removed>
+
+Example:
+
+	curl -N localhost:9090/api/v1/events
+*/
+func eventsHandler(broker *events.Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = r.URL.Query().Get("lastEventId")
+		}
+		var afterID uint64
+		if lastEventID != "" {
+			id, err := strconv.ParseUint(lastEventID, 10, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid last event ID %q", lastEventID))
+				return
+			}
+			afterID = id
+		}
+
+		namespace := r.URL.Query().Get("namespace")
+		kind := r.URL.Query().Get("kind")
+		eventType := r.URL.Query().Get("type")
+		matches := func(e events.Event) bool {
+			return (namespace == "" || e.Namespace == namespace) && (kind == "" || e.Kind == kind) && (eventType == "" || string(e.Type) == eventType)
+		}
+
+		rc := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		backlog, live, cancel := broker.Subscribe(afterID, 64)
+		defer cancel()
+
+		slog.Debug("SSE client connected", slog.String("remote", r.RemoteAddr), slog.Uint64("after_id", afterID), slog.Int("backlog", len(backlog)))
+
+		for _, e := range backlog {
+			if matches(e) {
+				if err := writeSSEEvent(w, e); err != nil {
+					return
+				}
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(sseHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				slog.Debug("SSE client disconnected", slog.String("remote", r.RemoteAddr))
+				return
+			case e, ok := <-live:
+				if !ok {
+					// We were dropped for being too slow: the client reconnects and resumes from its last ID
+					return
+				}
+				if !matches(e) {
+					continue
+				}
+				if err := writeSSEEvent(w, e); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeSSEEvent writes a single event in the text/event-stream format
+func writeSSEEvent(w http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
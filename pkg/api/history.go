@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/history"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+)
+
+// InitHistory registers the handlers of the persistent history (only when it is enabled)
+func InitHistory(store *history.Store) {
+	http.HandleFunc("GET /api/v1/history/inventory", historyInventoryHandler(store))
+	http.HandleFunc("GET /api/v1/history/workloads/{namespace}/{kind}/{name}", historyTimelineHandler(store))
+}
+
+// HistoryInventoryResponse is the body of GET /api/v1/history/inventory
+type HistoryInventoryResponse struct {
+	At        time.Time            `json:"at"`
+	Namespace string               `json:"namespace,omitempty"`
+	Workloads []inventory.Workload `json:"workloads"`
+}
+
+// TimelineResponse is the body of GET /api/v1/history/workloads/{namespace}/{kind}/{name}
+type TimelineResponse struct {
+	Namespace   string               `json:"namespace"`
+	Kind        string               `json:"kind"`
+	Name        string               `json:"name"`
+	Transitions []history.Transition `json:"transitions"`
+}
+
+/*
+historyInventoryHandler returns the inventory as it was at a point in time
+Query parameters: at (RFC3339, default now), namespace (optional)
+Example:
+
+	GET /api/v1/history/inventory?at=2026-01-20T14:00:00Z&namespace=prod-payments
+*/
+func historyInventoryHandler(store *history.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		at, err := parseTimeParam(r, "at", time.Now().UTC())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		namespace := r.URL.Query().Get("namespace")
+
+		workloads, err := store.InventoryAt(at, namespace)
+		var beforeRetention history.ErrBeforeRetention
+		if errors.As(err, &beforeRetention) {
+			writeError(w, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, HistoryInventoryResponse{At: at, Namespace: namespace, Workloads: workloads})
+	}
+}
+
+/*
+historyTimelineHandler returns the image transitions of a workload, oldest first
+Query parameters: from, to (RFC3339, optional)
+Example:
+
+	GET /api/v1/history/workloads/prod-payments/Deployment/api?from=2026-01-01T00:00:00Z
+*/
+func historyTimelineHandler(store *history.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, err := parseTimeParam(r, "from", time.Time{})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		to, err := parseTimeParam(r, "to", time.Time{})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		namespace, kind, name := r.PathValue("namespace"), r.PathValue("kind"), r.PathValue("name")
+		transitions, err := store.Timeline(namespace, kind, name, from, to)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, TimelineResponse{Namespace: namespace, Kind: kind, Name: name, Transitions: transitions})
+	}
+}
+
+// parseTimeParam reads an RFC3339 query parameter, returning fallback when it is missing
+func parseTimeParam(r *http.Request, name string, fallback time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: expected RFC3339, e.g. 2026-01-20T14:00:00Z", name, value)
+	}
+	return t, nil
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/MatteoMori/sentinel/pkg/registry"
+)
+
+// ImageMetadataResponse is the body of GET /api/v1/images/metadata
+type ImageMetadataResponse struct {
+	Count   int                       `json:"count"`
+	Results []registry.MetadataResult `json:"results"`
+}
+
+// InitImageMetadata registers the image metadata handler (only when imageMetadata is enabled)
+func InitImageMetadata(enricher *registry.Enricher) {
+	http.HandleFunc("GET /api/v1/images/metadata", imageMetadataHandler(enricher))
+}
+
+/*
+imageMetadataHandler returns the registry metadata (digest, creation date, size, platforms, labels) of the image of every container
+Query parameters: namespace (optional)
+Example:
+
+	GET /api/v1/images/metadata?namespace=prod
+*/
+func imageMetadataHandler(enricher *registry.Enricher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		results := enricher.Results(r.URL.Query().Get("namespace"))
+		writeJSON(w, http.StatusOK, ImageMetadataResponse{Count: len(results), Results: results})
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/MatteoMori/sentinel/pkg/policy"
+)
+
+// policyRecorder adds the policy violations of the containers to the inventory responses, when policies are evaluated
+var policyRecorder *policy.Recorder
+
+// PolicyResponse is the body of GET /api/v1/policy/violations
+type PolicyResponse struct {
+	Count      int                `json:"count"`
+	Violations []policy.Violation `json:"violations"`
+}
+
+// InitPolicy registers the policy violations handler (only when the policies are evaluated on the live workloads)
+func InitPolicy(recorder *policy.Recorder) {
+	policyRecorder = recorder
+	http.HandleFunc("GET /api/v1/policy/violations", policyHandler(recorder))
+}
+
+/*
+policyHandler returns the image policy violations of the live workloads
+Query parameters: namespace, severity (minimum severity: error, warning or info), both optional
+Example:
+
+	GET /api/v1/policy/violations?namespace=prod&severity=error
+*/
+func policyHandler(recorder *policy.Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		min := policy.SeverityInfo
+		if value := r.URL.Query().Get("severity"); value != "" {
+			severity, err := policy.ParseSeverity(value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			min = severity
+		}
+
+		violations := recorder.Violations(r.URL.Query().Get("namespace"), min)
+		writeJSON(w, http.StatusOK, PolicyResponse{Count: len(violations), Violations: violations})
+	}
+}
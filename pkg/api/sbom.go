@@ -0,0 +1,26 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/MatteoMori/sentinel/pkg/cyclonedx"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/snapshot"
+)
+
+// sbomHandler serves GET /api/v1/sbom/cyclonedx: the inventory as a CycloneDX JSON document
+func sbomHandler(store *inventory.Store, clusterName string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		doc := cyclonedx.Generate(snapshot.New(clusterName, store.List()))
+
+		w.Header().Set("Content-Type", cyclonedx.MediaType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+clusterName+`.cdx.json"`)
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(doc); err != nil {
+			slog.Error("Failed to encode CycloneDX document", slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Sentinel HTTP API.
+
+SCOPE:
+- Expose the inventory built by the controller as JSON under /api/v1/
+- Export the whole inventory as a snapshot (see pkg/snapshot) or a CycloneDX SBOM (see pkg/cyclonedx)
+- Stream image change events to clients (Server-Sent Events)
+- Handlers are registered on the default mux, so they are served by the same
+  webserver (and port) as the Prometheus /metrics endpoint
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+)
+
+// Init registers the API handlers on the default HTTP mux
+func Init(store *inventory.Store, broker *events.Broker, clusterName string) {
+	http.HandleFunc("GET /api/v1/who-uses", whoUsesHandler(store))
+	http.HandleFunc("GET /api/v1/snapshot", snapshotHandler(store, clusterName))
+	http.HandleFunc("GET /api/v1/sbom/cyclonedx", sbomHandler(store, clusterName))
+	http.HandleFunc("GET /api/v1/events", eventsHandler(broker))
+	slog.Debug("Sentinel API handlers registered", slog.String("prefix", "/api/v1/"))
+}
+
+// writeJSON serializes a response body as JSON
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("Failed to encode API response", slog.Any("error", err))
+	}
+}
+
+// errorResponse is the body returned by every API error
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
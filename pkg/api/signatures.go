@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/MatteoMori/sentinel/pkg/signature"
+)
+
+// SignaturesResponse is the body of GET /api/v1/signatures
+type SignaturesResponse struct {
+	Count   int                `json:"count"`
+	Results []signature.Result `json:"results"`
+}
+
+// InitSignatures registers the signature verification handler (only when signatures is enabled)
+func InitSignatures(checker *signature.Checker) {
+	http.HandleFunc("GET /api/v1/signatures", signaturesHandler(checker))
+}
+
+/*
+signaturesHandler returns the signature and provenance verification of the image of every container
+Query parameters: namespace, unsigned=true (only the containers not verified as signed), both optional
+Example:
+
+	GET /api/v1/signatures?namespace=prod&unsigned=true
+*/
+func signaturesHandler(checker *signature.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		results := checker.Results(r.URL.Query().Get("namespace"), r.URL.Query().Get("unsigned") == "true")
+		writeJSON(w, http.StatusOK, SignaturesResponse{Count: len(results), Results: results})
+	}
+}
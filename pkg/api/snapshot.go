@@ -0,0 +1,15 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/snapshot"
+)
+
+// snapshotHandler serves GET /api/v1/snapshot: the full inventory as a versioned snapshot document
+func snapshotHandler(store *inventory.Store, clusterName string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, snapshot.New(clusterName, store.List()))
+	}
+}
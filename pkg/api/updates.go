@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/MatteoMori/sentinel/pkg/updates"
+)
+
+// UpdatesResponse is the body of GET /api/v1/updates
+type UpdatesResponse struct {
+	Count   int              `json:"count"`
+	Results []updates.Result `json:"results"`
+}
+
+// InitUpdates registers the newer versions handler (only when updates is enabled)
+func InitUpdates(checker *updates.Checker) {
+	http.HandleFunc("GET /api/v1/updates", updatesHandler(checker))
+}
+
+/*
+updatesHandler returns the newest version available for the image of every container running a version tag
+Query parameters: namespace, outdated=true (only the containers behind), both optional
+Example:
+
+	GET /api/v1/updates?namespace=prod&outdated=true
+*/
+func updatesHandler(checker *updates.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		results := checker.Results(r.URL.Query().Get("namespace"), r.URL.Query().Get("outdated") == "true")
+		writeJSON(w, http.StatusOK, UpdatesResponse{Count: len(results), Results: results})
+	}
+}
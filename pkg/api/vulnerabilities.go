@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/MatteoMori/sentinel/pkg/vulnerability"
+)
+
+// InitVulnerabilities registers the vulnerability reports handler (only when the ingestion is enabled)
+func InitVulnerabilities(checker *vulnerability.Checker) {
+	http.HandleFunc("GET /api/v1/vulnerabilities", vulnerabilitiesHandler(checker))
+}
+
+/*
+vulnerabilitiesHandler returns the vulnerability counts of every container, and the containers without report
+Examples:
+
+	GET /api/v1/vulnerabilities
+	GET /api/v1/vulnerabilities?namespace=prod&missing=true
+*/
+func vulnerabilitiesHandler(checker *vulnerability.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		writeJSON(w, http.StatusOK, checker.Summary(query.Get("namespace"), query.Get("missing") == "true"))
+	}
+}
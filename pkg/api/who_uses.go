@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/MatteoMori/sentinel/pkg/eol"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/policy"
+)
+
+// WhoUsesResponse is the body of GET /api/v1/who-uses
+type WhoUsesResponse struct {
+	Query   inventory.Query `json:"query"`
+	Count   int             `json:"count"`
+	Matches []WhoUsesMatch  `json:"matches"`
+}
+
+// WhoUsesMatch is a container running the image, with its EOL status when EOL detection knows the image
+// and its image policy violations when policies are evaluated
+type WhoUsesMatch struct {
+	inventory.Match
+	EOL        *eol.Status        `json:"eol,omitempty"`
+	Violations []policy.Violation `json:"violations,omitempty"`
+}
+
+/*
+whoUsesHandler answers "which workloads run this image?"
+Query parameters (all optional, at least one required): registry, repository, tag, digest, tagRange
+Example:
+
+	GET /api/v1/who-uses?repository=base/debian&tagRange=>=12.1, <=12.4
+*/
+func whoUsesHandler(store *inventory.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		query := inventory.Query{
+			Registry:   params.Get("registry"),
+			Repository: params.Get("repository"),
+			Tag:        params.Get("tag"),
+			Digest:     params.Get("digest"),
+			TagRange:   params.Get("tagRange"),
+		}
+
+		matches, err := store.WhoUses(query)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		response := WhoUsesResponse{
+			Query:   query,
+			Count:   len(matches),
+			Matches: make([]WhoUsesMatch, 0, len(matches)),
+		}
+		for _, m := range matches {
+			match := WhoUsesMatch{Match: m}
+			if eolChecker != nil {
+				if status, ok := eolChecker.Lookup(m.Namespace, m.Kind, m.Workload, m.Container); ok {
+					match.EOL = &status
+				}
+			}
+			if policyRecorder != nil {
+				match.Violations = policyRecorder.ContainerViolations(m.Namespace, m.Kind, m.Workload, m.Container)
+			}
+			response.Matches = append(response.Matches, match)
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Audit log of every inventory event.
+
+SCOPE:
+- Write one JSON line per image added/changed/removed event, following a versioned schema (see SchemaVersion)
+- Write to stdout/stderr or to a file rotated by size and age
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+)
+
+/*
+SchemaVersion identifies the format of the audit records (documented in docs/audit/v1.schema.json).
+Fields may be added within a version; renaming or removing a field requires a new version.
+*/
+const SchemaVersion = "sentinel.audit/v1"
+
+// Record is a single line of the audit log
+type Record struct {
+	SchemaVersion   string            `json:"schemaVersion"`
+	EventID         uint64            `json:"eventId"`
+	Type            events.Type       `json:"type"`       // image.added, image.changed or image.removed
+	ObservedAt      time.Time         `json:"observedAt"` // When Sentinel observed the event
+	Cluster         string            `json:"cluster"`
+	Namespace       string            `json:"namespace"`
+	Kind            string            `json:"kind"`
+	Workload        string            `json:"workload"`
+	Container       string            `json:"container"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	OldImage        *inventory.Image  `json:"oldImage"` // null for image.added
+	NewImage        *inventory.Image  `json:"newImage"` // null for image.removed
+	ExtraLabels     map[string]string `json:"extraLabels"`
+}
+
+// NewRecord converts an event into an audit record
+func NewRecord(e events.Event, clusterName string) Record {
+	extraLabels := e.ExtraLabels
+	if extraLabels == nil {
+		extraLabels = map[string]string{}
+	}
+	return Record{
+		SchemaVersion:   SchemaVersion,
+		EventID:         e.ID,
+		Type:            e.Type,
+		ObservedAt:      e.Timestamp,
+		Cluster:         clusterName,
+		Namespace:       e.Namespace,
+		Kind:            e.Kind,
+		Workload:        e.Workload,
+		Container:       e.Container,
+		ResourceVersion: e.ResourceVersion,
+		OldImage:        e.OldImage,
+		NewImage:        e.NewImage,
+		ExtraLabels:     extraLabels,
+	}
+}
+
+// Init opens the audit output and starts writing every event published by the broker
+func Init(cfg shared.AuditConfig, clusterName string, broker *events.Broker) error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	var out io.Writer
+	switch cfg.Output {
+	case "", "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		writer, err := NewRotatingWriter(cfg.Output, cfg.MaxSizeMB, cfg.MaxAge, cfg.MaxBackups)
+		if err != nil {
+			return fmt.Errorf("unable to open audit log: %w", err)
+		}
+		out = writer
+	}
+
+	slog.Info("Writing audit log", slog.String("output", cfg.Output), slog.String("schema", SchemaVersion))
+
+	encoder := json.NewEncoder(out)
+	broker.Consume("audit", 1024, func(e events.Event) {
+		if err := encoder.Encode(NewRecord(e, clusterName)); err != nil {
+			slog.Error("Failed to write audit record", slog.Uint64("event_id", e.ID), slog.Any("error", err))
+		}
+	})
+
+	return nil
+}
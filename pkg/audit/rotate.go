@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is appended to rotated files: audit.jsonl -> audit-20260120T140312.000.jsonl
+const backupTimeFormat = "20060102T150405.000"
+
+/*
+RotatingWriter is an io.Writer appending to a file that is rotated when it grows bigger than maxSize
+or older than maxAge. Rotated files are renamed with a timestamp and only the newest maxBackups are kept.
+A zero limit disables the corresponding rotation/cleanup.
+*/
+type RotatingWriter struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// NewRotatingWriter opens (or creates) the file at path
+func NewRotatingWriter(path string, maxSizeMB int, maxAge time.Duration, maxBackups int) (*RotatingWriter, error) {
+	w := &RotatingWriter{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write appends p to the current file, rotating it first if needed
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	tooBig := w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize
+	tooOld := w.maxAge > 0 && time.Since(w.openedAt) > w.maxAge
+	if tooBig || tooOld {
+		if err := w.rotate(); err != nil {
+			slog.Error("Failed to rotate audit log, appending to the current file", slog.String("path", w.path), slog.Any("error", err))
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+func (w *RotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.openedAt = time.Now() // The age limit counts from when Sentinel (re)opened the file
+	return nil
+}
+
+/*
+rotate renames the current file and opens a new one. The current handle is only closed once the new file is open:
+on any error, the writer keeps appending to the file it has (renamed or not) rather than losing records.
+*/
+func (w *RotatingWriter) rotate() error {
+	ext := filepath.Ext(w.path)
+	base := strings.TrimSuffix(w.path, ext)
+	backup := fmt.Sprintf("%s-%s%s", base, time.Now().UTC().Format(backupTimeFormat), ext)
+	if err := os.Rename(w.path, backup); err != nil {
+		return err
+	}
+	slog.Debug("Audit log rotated", slog.String("backup", backup))
+
+	previous := w.file
+	if err := w.open(); err != nil {
+		return err // open only replaces w.file on success
+	}
+	if err := previous.Close(); err != nil {
+		slog.Warn("Failed to close rotated audit log", slog.String("path", backup), slog.Any("error", err))
+	}
+	w.removeOldBackups(base, ext)
+	return nil
+}
+
+// removeOldBackups deletes the oldest rotated files beyond maxBackups
+func (w *RotatingWriter) removeOldBackups(base, ext string) {
+	if w.maxBackups <= 0 {
+		return
+	}
+
+	backups, err := filepath.Glob(base + "-*" + ext)
+	if err != nil {
+		return
+	}
+	sort.Strings(backups) // The timestamp format sorts chronologically
+	for len(backups) > w.maxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			slog.Warn("Failed to remove old audit log", slog.String("path", backups[0]), slog.Any("error", err))
+		}
+		backups = backups[1:]
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Desired version "bill of materials" (BOM).
+
+SCOPE:
+- Map image repositories (globs) to the versions allowed to run: a set of tags (globs) and/or a semver range
+- Decide whether a container image complies, is outdated, or can't be evaluated
+
+FORMAT (YAML or JSON):
+
+	components:
+	  - name: ingress-nginx
+	    repository: registry.k8s.io/ingress-nginx/controller
+	    versions: ">=1.11.0, <1.13.0"
+	  - name: log-agent
+	    repository: "*.dkr.ecr.*.amazonaws.com/platform/fluent-bit"
+	    tags: ["3.1.*", "3.2.0"]
+
+A repository without registry is a Docker Hub repository ("nginx" is "docker.io/nginx"). A "*" registry matches any registry.
+The first component matching a repository governs it; images no component matches are not evaluated.
+*/
+
+package bom
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"sigs.k8s.io/yaml"
+)
+
+// Status is the outcome of the evaluation of an image
+type Status string
+
+const (
+	StatusCompliant Status = "compliant" // The tag is allowed
+	StatusOutdated  Status = "outdated"  // The tag is not allowed
+	StatusUnknown   Status = "unknown"   // Pinned by digest only: the version can't be told
+)
+
+// Component is an entry of the BOM
+type Component struct {
+	Name       string   `json:"name"`
+	Repository string   `json:"repository"`         // "registry/repository" glob
+	Versions   string   `json:"versions,omitempty"` // Semver constraint on the tag
+	Tags       []string `json:"tags,omitempty"`     // Allowed tags (globs)
+}
+
+// File is the content of a BOM file or ConfigMap
+type File struct {
+	Components []Component `json:"components"`
+}
+
+// BOM is a parsed and validated bill of materials
+type BOM struct {
+	Components []Component
+	compiled   []compiledComponent
+}
+
+type compiledComponent struct {
+	repository string // "registry/repository" glob
+	versions   *semver.Constraints
+}
+
+// Parse reads and validates a BOM
+func Parse(data []byte) (*BOM, error) {
+	var f File
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, err
+	}
+
+	b := &BOM{Components: f.Components}
+	for i, c := range f.Components {
+		if c.Name == "" || c.Repository == "" {
+			return nil, fmt.Errorf("component #%d: name and repository are required", i)
+		}
+		if c.Versions == "" && len(c.Tags) == 0 {
+			return nil, fmt.Errorf("component %s: versions or tags is required", c.Name)
+		}
+		if slices.ContainsFunc(f.Components[:i], func(other Component) bool { return other.Name == c.Name }) {
+			return nil, fmt.Errorf("component %s: duplicate name", c.Name)
+		}
+
+		// Same defaults as container images (docker.io), unless the registry is a wildcard
+		compiled := compiledComponent{repository: c.Repository}
+		if !strings.HasPrefix(c.Repository, "*/") {
+			compiled.repository = inventory.ParseImage(c.Repository).RepositoryKey()
+		}
+		if c.Versions != "" {
+			constraint, err := semver.NewConstraint(c.Versions)
+			if err != nil {
+				return nil, fmt.Errorf("component %s: invalid versions %q: %w", c.Name, c.Versions, err)
+			}
+			compiled.versions = constraint
+		}
+		b.compiled = append(b.compiled, compiled)
+	}
+
+	return b, nil
+}
+
+// Evaluate finds the component governing an image and checks its tag. ok is false when no component matches.
+func (b *BOM) Evaluate(img inventory.Image) (component Component, status Status, ok bool) {
+	for i, c := range b.compiled {
+		if !inventory.MatchGlob(c.repository, img.RepositoryKey()) {
+			continue
+		}
+		component = b.Components[i]
+
+		if img.Tag == "" {
+			return component, StatusUnknown, true
+		}
+		for _, tag := range component.Tags {
+			if inventory.MatchGlob(tag, img.Tag) {
+				return component, StatusCompliant, true
+			}
+		}
+		if c.versions != nil {
+			if version, isSemver := inventory.TagVersion(img.Tag); isSemver && c.versions.Check(version) {
+				return component, StatusCompliant, true
+			}
+		}
+		return component, StatusOutdated, true
+	}
+	return Component{}, "", false
+}
@@ -0,0 +1,237 @@
+package bom
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	"github.com/MatteoMori/sentinel/pkg/reload"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Result is the compliance of one container
+type Result struct {
+	Namespace string          `json:"namespace"`
+	Kind      string          `json:"kind"`
+	Workload  string          `json:"workload"`
+	Container string          `json:"container"`
+	Component string          `json:"component"`
+	Image     inventory.Image `json:"image"`
+	Status    Status          `json:"status"`
+}
+
+// ComponentSummary counts the instances of a component and lists the ones not running an allowed version
+type ComponentSummary struct {
+	Component
+	Instances         int      `json:"instances"`
+	Outdated          int      `json:"outdated"`
+	Unknown           int      `json:"unknown"`
+	OutdatedInstances []Result `json:"outdatedInstances"`
+}
+
+// Summary is the compliance of the whole inventory
+type Summary struct {
+	LoadedAt   time.Time          `json:"loadedAt"`
+	Components []ComponentSummary `json:"components"`
+}
+
+// Checker evaluates the inventory against the current BOM and keeps the metrics up to date
+type Checker struct {
+	store            *inventory.Store
+	complianceSeries *SentinelPrometheus.SeriesSet // By workload key
+	instancesSeries  *SentinelPrometheus.SeriesSet
+	outdatedSeries   *SentinelPrometheus.SeriesSet
+
+	mu       sync.Mutex
+	bom      *BOM
+	loadedAt time.Time
+	results  map[string][]Result // workload key -> results of its governed containers
+}
+
+// NewChecker builds a checker without BOM: nothing is evaluated until Apply() is called
+func NewChecker(store *inventory.Store) *Checker {
+	return &Checker{
+		store:            store,
+		complianceSeries: SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelBOMCompliance),
+		instancesSeries:  SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelBOMInstances),
+		outdatedSeries:   SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelBOMOutdatedInstances),
+		results:          make(map[string][]Result),
+	}
+}
+
+/*
+Init loads the BOM from a file or a ConfigMap, reloads it when it changes and evaluates every workload
+added or changed in the inventory.
+*/
+func Init(cfg shared.BOMConfig, store *inventory.Store, broker *events.Broker, clientset kubernetes.Interface) (*Checker, error) {
+	checker := NewChecker(store)
+
+	switch {
+	case cfg.ConfigMap.Name != "":
+		reload.WatchConfigMap("BOM", clientset, cfg.ConfigMap.Namespace, cfg.ConfigMap.Name, cfg.ConfigMap.Key, checker.Apply)
+	case cfg.Path != "":
+		if err := reload.WatchFile("BOM", cfg.Path, cfg.ReloadInterval, checker.Apply); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("bom: path or configMap.name is required")
+	}
+
+	broker.Consume("bom", 256, func(e events.Event) {
+		checker.evaluateWorkload(e.Namespace, e.Kind, e.Workload)
+	})
+	return checker, nil
+}
+
+// Apply parses a new BOM and evaluates the whole inventory against it
+func (c *Checker) Apply(data []byte) error {
+	b, err := Parse(data)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.bom, c.loadedAt = b, time.Now().UTC()
+
+	// The series are replaced workload by workload, so that a scrape during the reload never sees them vanish
+	stale := maps.Clone(c.results)
+	for _, w := range c.store.List() {
+		delete(stale, w.Key())
+		c.setResults(w.Key(), c.evaluate(w))
+	}
+	for key := range stale {
+		c.setResults(key, nil)
+	}
+	c.updateSummaryMetrics()
+	return nil
+}
+
+// EvaluateNamespace evaluates every workload of a namespace, after its initial listing (which publishes no event)
+func (c *Checker) EvaluateNamespace(namespace string) {
+	for _, w := range c.store.ListNamespace(namespace) {
+		c.evaluateWorkload(w.Namespace, w.Kind, w.Name)
+	}
+}
+
+// evaluateWorkload re-evaluates one workload after a change, or forgets it once deleted
+func (c *Checker) evaluateWorkload(namespace, kind, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.bom == nil {
+		return
+	}
+
+	var results []Result
+	if w, ok := c.store.Get(namespace, kind, name); ok {
+		results = c.evaluate(w)
+	}
+	c.setResults(inventory.WorkloadKey(namespace, kind, name), results)
+	c.updateSummaryMetrics()
+}
+
+// evaluate checks every container of a workload. Must be called with the lock held.
+func (c *Checker) evaluate(w inventory.Workload) []Result {
+	var results []Result
+	for _, container := range w.Containers {
+		component, status, ok := c.bom.Evaluate(container.Image)
+		if !ok {
+			continue
+		}
+		slog.Debug("BOM evaluation",
+			slog.String("ns/workload", w.Namespace+"/"+w.Name),
+			slog.String("container", container.Name),
+			slog.String("component", component.Name),
+			slog.String("status", string(status)))
+		results = append(results, Result{
+			Namespace: w.Namespace,
+			Kind:      w.Kind,
+			Workload:  w.Name,
+			Container: container.Name,
+			Component: component.Name,
+			Image:     container.Image,
+			Status:    status,
+		})
+	}
+	return results
+}
+
+// setResults replaces the results and the compliance series of a workload. Must be called with the lock held.
+func (c *Checker) setResults(key string, results []Result) {
+	compliance := c.complianceSeries.Update(key)
+	for _, r := range results {
+		compliance.Set(1, r.Namespace, r.Kind, r.Workload, r.Container, r.Component, r.Image.Tag, string(r.Status))
+	}
+	compliance.Commit()
+
+	if len(results) == 0 {
+		delete(c.results, key)
+		return
+	}
+	c.results[key] = results
+}
+
+// updateSummaryMetrics recounts the instances per component, and drops the components no longer in the BOM. Must be called with the lock held.
+func (c *Checker) updateSummaryMetrics() {
+	instances, outdated := c.instancesSeries.Update(""), c.outdatedSeries.Update("")
+	for _, s := range c.summarize().Components {
+		instances.Set(float64(s.Instances), s.Name)
+		outdated.Set(float64(s.Outdated), s.Name)
+	}
+	instances.Commit()
+	outdated.Commit()
+}
+
+// Summary returns the compliance of every BOM component
+func (c *Checker) Summary() Summary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.summarize()
+}
+
+func (c *Checker) summarize() Summary {
+	summary := Summary{LoadedAt: c.loadedAt, Components: []ComponentSummary{}}
+	if c.bom == nil {
+		return summary
+	}
+
+	byName := make(map[string]*ComponentSummary, len(c.bom.Components))
+	for _, component := range c.bom.Components {
+		summary.Components = append(summary.Components, ComponentSummary{Component: component, OutdatedInstances: []Result{}})
+	}
+	for i := range summary.Components {
+		byName[summary.Components[i].Name] = &summary.Components[i]
+	}
+
+	for _, results := range c.results {
+		for _, r := range results {
+			s := byName[r.Component]
+			s.Instances++
+			switch r.Status {
+			case StatusOutdated:
+				s.Outdated++
+				s.OutdatedInstances = append(s.OutdatedInstances, r)
+			case StatusUnknown:
+				s.Unknown++
+			}
+		}
+	}
+
+	for i := range summary.Components {
+		outdated := summary.Components[i].OutdatedInstances
+		sort.Slice(outdated, func(a, b int) bool {
+			ka := inventory.WorkloadKey(outdated[a].Namespace, outdated[a].Kind, outdated[a].Workload) + "/" + outdated[a].Container
+			kb := inventory.WorkloadKey(outdated[b].Namespace, outdated[b].Kind, outdated[b].Workload) + "/" + outdated[b].Container
+			return ka < kb
+		})
+	}
+	return summary
+}
@@ -0,0 +1,230 @@
+/*
+CycloneDX export of the cluster inventory.
+
+SCOPE:
+- Render an inventory snapshot as a CycloneDX 1.5 JSON document (a software bill of materials of what runs in a cluster)
+- Each distinct image is a "container" component with its purl and digest
+- Namespaces and workloads are components too, linked in the dependency graph:
+  cluster -> namespaces -> workloads -> images
+- The extra labels of a workload (extraLabels config) are recorded as its properties
+
+Spec: https://cyclonedx.org/docs/1.5/json/
+*/
+
+package cyclonedx
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/snapshot"
+)
+
+// MediaType is the content type of a CycloneDX JSON document
+const MediaType = "application/vnd.cyclonedx+json; version=1.5"
+
+// Document is a CycloneDX BOM
+type Document struct {
+	BOMFormat    string       `json:"bomFormat"`
+	SpecVersion  string       `json:"specVersion"`
+	SerialNumber string       `json:"serialNumber"`
+	Version      int          `json:"version"`
+	Metadata     Metadata     `json:"metadata"`
+	Components   []Component  `json:"components"`
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// Metadata describes the BOM and its subject (the cluster)
+type Metadata struct {
+	Timestamp string    `json:"timestamp"`
+	Tools     Tools     `json:"tools"`
+	Component Component `json:"component"`
+}
+
+// Tools lists the tools that produced the BOM
+type Tools struct {
+	Components []Component `json:"components"`
+}
+
+// Component is an image, a workload, a namespace or the cluster
+type Component struct {
+	BOMRef     string     `json:"bom-ref,omitempty"`
+	Type       string     `json:"type"`
+	Group      string     `json:"group,omitempty"`
+	Name       string     `json:"name"`
+	Version    string     `json:"version,omitempty"`
+	Purl       string     `json:"purl,omitempty"`
+	Hashes     []Hash     `json:"hashes,omitempty"`
+	Properties []Property `json:"properties,omitempty"`
+}
+
+// Hash is a digest of a component
+type Hash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+// Property is a name/value pair attached to a component
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Dependency lists the components a component depends on
+type Dependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// hashAlgorithms maps OCI digest algorithms to CycloneDX hash algorithms
+var hashAlgorithms = map[string]string{
+	"sha256": "SHA-256",
+	"sha384": "SHA-384",
+	"sha512": "SHA-512",
+}
+
+// Generate renders a snapshot as a CycloneDX document. Components and dependencies are sorted, so that
+// two exports of the same inventory only differ by their serial number and timestamp.
+func Generate(s snapshot.Snapshot) Document {
+	clusterRef := "cluster:" + s.Cluster
+	doc := Document{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: newSerialNumber(),
+		Version:      1,
+		Metadata: Metadata{
+			Timestamp: s.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			Tools:     Tools{Components: []Component{{Type: "application", Name: "sentinel"}}},
+			Component: Component{BOMRef: clusterRef, Type: "platform", Name: s.Cluster},
+		},
+		Components:   []Component{},
+		Dependencies: []Dependency{},
+	}
+
+	images := make(map[string]inventory.Image)             // bom-ref -> image
+	namespaces := make(map[string][]string)                // namespace -> workload bom-refs
+	workloadImages := make(map[string]map[string]struct{}) // workload bom-ref -> image bom-refs
+
+	for _, w := range s.Workloads {
+		workloadRef := "workload:" + w.Key()
+		namespaces[w.Namespace] = append(namespaces[w.Namespace], workloadRef)
+
+		component := Component{
+			BOMRef: workloadRef,
+			Type:   "application",
+			Group:  w.Namespace,
+			Name:   w.Name,
+			Properties: []Property{
+				{Name: "sentinel:kind", Value: w.Kind},
+				{Name: "sentinel:namespace", Value: w.Namespace},
+			},
+		}
+		for _, name := range sortedKeys(w.ExtraLabels) {
+			component.Properties = append(component.Properties, Property{Name: "sentinel:extraLabel:" + name, Value: w.ExtraLabels[name]})
+		}
+		for _, c := range w.Containers {
+			component.Properties = append(component.Properties, Property{Name: "sentinel:container:" + c.Name, Value: c.Image.Reference})
+		}
+		doc.Components = append(doc.Components, component)
+
+		refs := make(map[string]struct{}, len(w.Containers))
+		for _, c := range w.Containers {
+			imageRef := "image:" + c.Image.Reference
+			images[imageRef] = c.Image
+			refs[imageRef] = struct{}{}
+		}
+		workloadImages[workloadRef] = refs
+	}
+
+	for ref, img := range images {
+		doc.Components = append(doc.Components, imageComponent(ref, img))
+		doc.Dependencies = append(doc.Dependencies, Dependency{Ref: ref, DependsOn: []string{}})
+	}
+
+	namespaceRefs := []string{}
+	for namespace, workloadRefs := range namespaces {
+		namespaceRef := "namespace:" + namespace
+		namespaceRefs = append(namespaceRefs, namespaceRef)
+		doc.Components = append(doc.Components, Component{BOMRef: namespaceRef, Type: "platform", Name: namespace})
+		sort.Strings(workloadRefs)
+		doc.Dependencies = append(doc.Dependencies, Dependency{Ref: namespaceRef, DependsOn: workloadRefs})
+	}
+	sort.Strings(namespaceRefs)
+	doc.Dependencies = append(doc.Dependencies, Dependency{Ref: clusterRef, DependsOn: namespaceRefs})
+
+	for workloadRef, refs := range workloadImages {
+		doc.Dependencies = append(doc.Dependencies, Dependency{Ref: workloadRef, DependsOn: sortedKeys(refs)})
+	}
+
+	sort.Slice(doc.Components, func(i, j int) bool { return doc.Components[i].BOMRef < doc.Components[j].BOMRef })
+	sort.Slice(doc.Dependencies, func(i, j int) bool { return doc.Dependencies[i].Ref < doc.Dependencies[j].Ref })
+	return doc
+}
+
+// imageComponent describes an image as a "container" component
+func imageComponent(ref string, img inventory.Image) Component {
+	component := Component{
+		BOMRef:  ref,
+		Type:    "container",
+		Name:    img.Registry + "/" + img.Repository,
+		Version: img.Tag,
+		Purl:    imagePurl(img),
+		Properties: []Property{
+			{Name: "sentinel:image", Value: img.Reference},
+		},
+	}
+	if component.Version == "" {
+		component.Version = img.Digest
+	}
+	if algorithm, content, ok := strings.Cut(img.Digest, ":"); ok && hashAlgorithms[algorithm] != "" {
+		component.Hashes = []Hash{{Alg: hashAlgorithms[algorithm], Content: content}}
+	}
+	return component
+}
+
+/*
+imagePurl builds the package URL of an image (https://github.com/package-url/purl-spec, type "oci"):
+
+	pkg:oci/nginx@sha256%3Aabc...?repository_url=docker.io/library/nginx&tag=1.27
+*/
+func imagePurl(img inventory.Image) string {
+	repository := img.Repository
+	if img.Registry == "docker.io" && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	purl := "pkg:oci/" + url.PathEscape(strings.ToLower(path.Base(repository)))
+	if img.Digest != "" {
+		purl += "@" + strings.ReplaceAll(img.Digest, ":", "%3A")
+	}
+
+	// repository_url keeps its slashes, as in the purl-spec examples
+	purl += "?repository_url=" + img.Registry + "/" + repository
+	if img.Tag != "" {
+		purl += "&tag=" + url.QueryEscape(img.Tag)
+	}
+	return purl
+}
+
+// newSerialNumber returns a random (version 4) UUID URN
+func newSerialNumber() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
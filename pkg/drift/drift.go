@@ -0,0 +1,209 @@
+/*
+GitOps drift detection.
+
+SCOPE:
+- Periodically parse a directory of manifests holding the desired state (e.g. a git-sync checkout)
+- Compare the desired image of every workload/container with what the controller observes in the cluster
+- Report workloads whose live image differs from git, and workloads that exist only on one side
+- Expose the result as sentinel_image_drift metrics and through the Sentinel API
+
+Only the namespaces Sentinel watches are compared: a manifest for a namespace outside the namespaceSelector is ignored.
+*/
+
+package drift
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/manifests"
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+)
+
+// Type is the kind of difference between git and the cluster
+type Type string
+
+const (
+	TypeImage            Type = "image"              // The live image differs from the desired one
+	TypeMissingInCluster Type = "missing_in_cluster" // Desired in git, not running
+	TypeMissingInGit     Type = "missing_in_git"     // Running, not in git
+)
+
+// Drift is a workload (or one of its containers) that differs between git and the cluster
+type Drift struct {
+	Type         Type   `json:"type"`
+	Namespace    string `json:"namespace"`
+	Kind         string `json:"kind"`
+	Workload     string `json:"workload"`
+	Container    string `json:"container,omitempty"`    // Empty when the whole workload is missing on one side
+	DesiredImage string `json:"desiredImage,omitempty"` // Image in git
+	LiveImage    string `json:"liveImage,omitempty"`    // Image in the cluster
+	Source       string `json:"source,omitempty"`       // Manifest defining the workload
+}
+
+// Report is the result of the latest evaluation
+type Report struct {
+	Path        string    `json:"path"`
+	EvaluatedAt time.Time `json:"evaluatedAt"`     // Zero until the first successful evaluation
+	Error       string    `json:"error,omitempty"` // Why the latest evaluation failed; Drifts are then from the previous one
+	Count       int       `json:"count"`
+	Drifts      []Drift   `json:"drifts"`
+}
+
+// Detector compares a manifests directory with the inventory
+type Detector struct {
+	cfg     shared.DriftConfig
+	store   *inventory.Store
+	watched func(namespace string) bool
+
+	mu     sync.RWMutex
+	report Report
+	series map[string][]string // Label values of the sentinel_image_drift series set by the latest evaluation
+}
+
+// NewDetector builds a detector. watched tells whether Sentinel currently watches a namespace.
+func NewDetector(cfg shared.DriftConfig, store *inventory.Store, watched func(namespace string) bool) (*Detector, error) {
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("drift.interval must be positive, got %s", cfg.Interval)
+	}
+	return &Detector{
+		cfg:     cfg,
+		store:   store,
+		watched: watched,
+		report:  Report{Path: cfg.Path, Drifts: []Drift{}},
+		series:  make(map[string][]string),
+	}, nil
+}
+
+// Run evaluates the drift every interval. The first evaluation waits one interval, so that the informers
+// have listed the cluster before workloads are reported missing. It never returns.
+func (d *Detector) Run() {
+	for {
+		time.Sleep(d.cfg.Interval)
+		d.Evaluate()
+	}
+}
+
+// Evaluate parses the manifests, compares them with the inventory and updates the report and the metrics
+func (d *Detector) Evaluate() {
+	set, err := manifests.Load(d.cfg.Path, d.cfg.DefaultNamespace)
+	if err != nil {
+		slog.Error("Unable to parse the desired state manifests", slog.String("path", d.cfg.Path), slog.Any("error", err))
+		d.mu.Lock()
+		d.report.Error = err.Error()
+		d.mu.Unlock()
+		return
+	}
+
+	drifts := Compare(set.Workloads, d.store.List(), d.watched)
+
+	// Only the series that are gone are deleted, so that a scrape during an evaluation never sees the drifts vanish
+	series := make(map[string][]string, len(drifts))
+	for _, drift := range drifts {
+		labelValues := []string{
+			drift.Namespace,
+			drift.Kind,
+			drift.Workload,
+			drift.Container,
+			string(drift.Type),
+			drift.DesiredImage,
+			drift.LiveImage,
+		}
+		series[strings.Join(labelValues, "\x00")] = labelValues
+		SentinelPrometheus.SentinelImageDrift.WithLabelValues(labelValues...).Set(1)
+	}
+	now := time.Now()
+	SentinelPrometheus.SentinelDriftLastEvaluation.Set(float64(now.Unix()))
+
+	d.mu.Lock()
+	for key, labelValues := range d.series {
+		if _, ok := series[key]; !ok {
+			SentinelPrometheus.SentinelImageDrift.DeleteLabelValues(labelValues...)
+		}
+	}
+	d.series = series
+	d.report = Report{Path: d.cfg.Path, EvaluatedAt: now.UTC(), Count: len(drifts), Drifts: drifts}
+	d.mu.Unlock()
+
+	slog.Debug("Drift evaluated", slog.String("path", d.cfg.Path), slog.Int("drifts", len(drifts)))
+}
+
+// Report returns the result of the latest evaluation
+func (d *Detector) Report() Report {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.report
+}
+
+// Compare lists the differences between the desired workloads and the live ones, in the watched namespaces
+func Compare(desired []manifests.Workload, live []inventory.Workload, watched func(namespace string) bool) []Drift {
+	drifts := []Drift{}
+
+	liveByKey := make(map[string]inventory.Workload, len(live))
+	for _, w := range live {
+		liveByKey[w.Key()] = w
+	}
+
+	seen := make(map[string]struct{}, len(desired))
+	for _, want := range desired {
+		if !watched(want.Namespace) {
+			continue
+		}
+		key := inventory.WorkloadKey(want.Namespace, want.Kind, want.Name())
+		seen[key] = struct{}{}
+
+		got, running := liveByKey[key]
+		if !running {
+			drifts = append(drifts, Drift{Type: TypeMissingInCluster, Namespace: want.Namespace, Kind: want.Kind, Workload: want.Name(), Source: want.Source})
+			continue
+		}
+
+		liveImages := make(map[string]string, len(got.Containers))
+		for _, c := range got.Containers {
+			liveImages[c.Name] = c.Image.Reference
+		}
+		desiredImages := make(map[string]struct{}, len(want.Containers))
+		for _, c := range want.Containers {
+			desiredImages[c.Name] = struct{}{}
+			if liveImages[c.Name] != c.Image {
+				drifts = append(drifts, Drift{
+					Type:         TypeImage,
+					Namespace:    want.Namespace,
+					Kind:         want.Kind,
+					Workload:     want.Name(),
+					Container:    c.Name,
+					DesiredImage: c.Image,
+					LiveImage:    liveImages[c.Name], // Empty when the container isn't running
+					Source:       want.Source,
+				})
+			}
+		}
+		for _, c := range got.Containers {
+			if _, ok := desiredImages[c.Name]; !ok {
+				drifts = append(drifts, Drift{Type: TypeImage, Namespace: got.Namespace, Kind: got.Kind, Workload: got.Name, Container: c.Name, LiveImage: c.Image.Reference, Source: want.Source})
+			}
+		}
+	}
+
+	for _, got := range live {
+		if _, ok := seen[got.Key()]; !ok && watched(got.Namespace) {
+			drifts = append(drifts, Drift{Type: TypeMissingInGit, Namespace: got.Namespace, Kind: got.Kind, Workload: got.Name})
+		}
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		ki := inventory.WorkloadKey(drifts[i].Namespace, drifts[i].Kind, drifts[i].Workload)
+		kj := inventory.WorkloadKey(drifts[j].Namespace, drifts[j].Kind, drifts[j].Workload)
+		if ki != kj {
+			return ki < kj
+		}
+		return drifts[i].Container < drifts[j].Container
+	})
+	return drifts
+}
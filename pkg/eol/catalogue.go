@@ -0,0 +1,221 @@
+/*
+End-of-life (EOL) catalogue of base images and runtimes.
+
+SCOPE:
+- Map image repositories (globs) to a product and its release cycles, in the endoflife.date format
+- Tell the release cycle of an image from its tag, and whether (or in how many days) that cycle reaches its EOL
+- A catalogue is bundled (catalogue.yaml: nginx, postgresql, nodejs, python); a file can override or extend it
+
+FORMAT (YAML or JSON):
+
+	products:
+	  - name: nodejs
+	    repositories: ["docker.io/node", "docker.io/library/node"]
+	    tagPattern: "^(?P<cycle>[0-9]+)-"   # optional, see below
+	    cycles:                              # as served by https://endoflife.date/api/nodejs.json
+	      - {cycle: "22", releaseDate: "2024-04-24", eol: "2027-04-30", lts: true}
+	      - {cycle: "21", releaseDate: "2023-10-17", eol: "2024-06-01"}
+
+The version at the start of the tag ("3.12" in "3.12.4-slim", "v" prefix allowed) picks the longest cycle it falls in:
+"3.12.4" is in cycle "3.12", "16.4" in cycle "16". A tagPattern replaces that leading version with its "cycle" group
+(or its first group). Images whose tag tells no known cycle (e.g. "latest", "bookworm") are not evaluated.
+*/
+
+package eol
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed catalogue.yaml
+var bundled []byte
+
+// Date is the eol field of a cycle: a date, or true/false when the date is unknown
+type Date struct {
+	Time time.Time // Zero when the date is unknown
+	EOL  bool      // Set when the date is unknown but the cycle is already EOL
+}
+
+// UnmarshalJSON reads a "2006-01-02" date or a boolean
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var flag bool
+	if err := json.Unmarshal(data, &flag); err == nil {
+		*d = Date{EOL: flag}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("eol must be a date or a boolean")
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		if t, err = time.Parse(time.RFC3339, s); err != nil {
+			return fmt.Errorf("invalid eol date %q", s)
+		}
+	}
+	*d = Date{Time: t.UTC()}
+	return nil
+}
+
+// MarshalJSON writes the date back in the endoflife.date format
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.Time.IsZero() {
+		return json.Marshal(d.EOL)
+	}
+	return json.Marshal(d.Time.Format(time.DateOnly))
+}
+
+// Cycle is a release line of a product
+type Cycle struct {
+	Cycle       string `json:"cycle"`
+	ReleaseDate string `json:"releaseDate,omitempty"`
+	EOL         Date   `json:"eol"`
+	Latest      string `json:"latest,omitempty"`
+	LTS         bool   `json:"lts,omitempty"`
+}
+
+// Product is an entry of the catalogue
+type Product struct {
+	Name         string   `json:"name"`
+	Repositories []string `json:"repositories"`         // "registry/repository" globs
+	TagPattern   string   `json:"tagPattern,omitempty"` // Regexp extracting the cycle from the tag
+	Cycles       []Cycle  `json:"cycles"`
+}
+
+// File is the content of a catalogue file
+type File struct {
+	Products []Product `json:"products"`
+}
+
+// Catalogue is a parsed and validated EOL catalogue
+type Catalogue struct {
+	Products   []Product
+	tagPattern []*regexp.Regexp // By product, nil when the leading version is used
+}
+
+// Status is the EOL evaluation of an image
+type Status struct {
+	Product      string `json:"product"`
+	Cycle        string `json:"cycle"`
+	EOLDate      string `json:"eolDate,omitempty"`      // Empty when the catalogue doesn't know it
+	DaysUntilEOL *int   `json:"daysUntilEol,omitempty"` // Negative once EOL; nil when the date is unknown
+	EOL          bool   `json:"eol"`
+	Latest       string `json:"latest,omitempty"` // Latest release of the cycle, when the catalogue knows it
+}
+
+// leadingVersion is the version at the start of a tag
+var leadingVersion = regexp.MustCompile(`^v?([0-9]+(?:\.[0-9]+)*)`)
+
+/*
+Load returns the bundled catalogue, overridden by a catalogue file when data isn't empty:
+the products of the file replace the bundled products of the same name, and the others are added.
+*/
+func Load(data []byte) (*Catalogue, error) {
+	var base File
+	if err := yaml.UnmarshalStrict(bundled, &base); err != nil {
+		return nil, fmt.Errorf("bundled catalogue: %w", err)
+	}
+	if len(data) == 0 {
+		return newCatalogue(base.Products)
+	}
+
+	var override File
+	if err := yaml.UnmarshalStrict(data, &override); err != nil {
+		return nil, err
+	}
+	products := slices.DeleteFunc(base.Products, func(p Product) bool {
+		return slices.ContainsFunc(override.Products, func(o Product) bool { return o.Name == p.Name })
+	})
+	return newCatalogue(append(override.Products, products...))
+}
+
+func newCatalogue(products []Product) (*Catalogue, error) {
+	c := &Catalogue{Products: products}
+	for i, p := range products {
+		if p.Name == "" || len(p.Repositories) == 0 || len(p.Cycles) == 0 {
+			return nil, fmt.Errorf("product #%d: name, repositories and cycles are required", i)
+		}
+		if slices.ContainsFunc(products[:i], func(other Product) bool { return other.Name == p.Name }) {
+			return nil, fmt.Errorf("product %s: duplicate name", p.Name)
+		}
+
+		var pattern *regexp.Regexp
+		if p.TagPattern != "" {
+			var err error
+			if pattern, err = regexp.Compile(p.TagPattern); err != nil {
+				return nil, fmt.Errorf("product %s: invalid tagPattern: %w", p.Name, err)
+			}
+			if pattern.NumSubexp() == 0 {
+				return nil, fmt.Errorf("product %s: tagPattern needs a group capturing the cycle", p.Name)
+			}
+		}
+		c.tagPattern = append(c.tagPattern, pattern)
+	}
+	return c, nil
+}
+
+// Evaluate finds the product and cycle of an image. ok is false when no product or cycle matches.
+func (c *Catalogue) Evaluate(img inventory.Image, now time.Time) (status Status, ok bool) {
+	for i, p := range c.Products {
+		if !slices.ContainsFunc(p.Repositories, func(glob string) bool { return inventory.MatchGlob(glob, img.RepositoryKey()) }) {
+			continue
+		}
+		cycle, found := findCycle(p.Cycles, tagCycle(c.tagPattern[i], img.Tag))
+		if !found {
+			return Status{}, false
+		}
+
+		status = Status{Product: p.Name, Cycle: cycle.Cycle, EOL: cycle.EOL.EOL, Latest: cycle.Latest}
+		if !cycle.EOL.Time.IsZero() {
+			days := int(cycle.EOL.Time.Sub(now.UTC().Truncate(24*time.Hour)).Hours() / 24)
+			status.EOLDate = cycle.EOL.Time.Format(time.DateOnly)
+			status.DaysUntilEOL = &days
+			status.EOL = days <= 0
+		}
+		return status, true
+	}
+	return Status{}, false
+}
+
+// tagCycle extracts the version telling the cycle of a tag
+func tagCycle(pattern *regexp.Regexp, tag string) string {
+	if pattern == nil {
+		if m := leadingVersion.FindStringSubmatch(tag); m != nil {
+			return m[1]
+		}
+		return ""
+	}
+
+	m := pattern.FindStringSubmatch(tag)
+	if m == nil {
+		return ""
+	}
+	if i := pattern.SubexpIndex("cycle"); i > 0 {
+		return m[i]
+	}
+	return m[1]
+}
+
+// findCycle returns the longest cycle a version falls in ("3.12.4" is in "3.12", not in "3")
+func findCycle(cycles []Cycle, version string) (Cycle, bool) {
+	var best Cycle
+	found := false
+	for _, cycle := range cycles {
+		if version != cycle.Cycle && !strings.HasPrefix(version, cycle.Cycle+".") {
+			continue
+		}
+		if !found || len(cycle.Cycle) > len(best.Cycle) {
+			best, found = cycle, true
+		}
+	}
+	return best, found && version != ""
+}
@@ -0,0 +1,224 @@
+package eol
+
+import (
+	"log/slog"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	"github.com/MatteoMori/sentinel/pkg/reload"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+)
+
+// refreshInterval is how often the whole inventory is re-evaluated, so that the days until EOL count down
+const refreshInterval = time.Hour
+
+// Result is the EOL status of one container
+type Result struct {
+	Namespace string          `json:"namespace"`
+	Kind      string          `json:"kind"`
+	Workload  string          `json:"workload"`
+	Container string          `json:"container"`
+	Image     inventory.Image `json:"image"`
+	Status
+}
+
+// Checker evaluates the inventory against the catalogue and keeps the metrics up to date
+type Checker struct {
+	store           *inventory.Store
+	eolSeries       *SentinelPrometheus.SeriesSet // By workload key
+	daysUntilSeries *SentinelPrometheus.SeriesSet // By workload key
+
+	mu        sync.Mutex
+	catalogue *Catalogue
+	results   map[string][]Result // workload key -> results of its containers the catalogue knows
+}
+
+// NewChecker builds a checker using a catalogue
+func NewChecker(store *inventory.Store, catalogue *Catalogue) *Checker {
+	return &Checker{
+		store:           store,
+		eolSeries:       SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelImageEOL),
+		daysUntilSeries: SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelImageDaysUntilEOL),
+		catalogue:       catalogue,
+		results:         make(map[string][]Result),
+	}
+}
+
+/*
+Init loads the bundled catalogue, overridden by the catalogue file when one is configured (and reloaded when it changes),
+then evaluates every workload added or changed in the inventory, and the whole inventory every hour.
+*/
+func Init(cfg shared.EOLConfig, store *inventory.Store, broker *events.Broker) (*Checker, error) {
+	catalogue, err := Load(nil)
+	if err != nil {
+		return nil, err
+	}
+	checker := NewChecker(store, catalogue)
+
+	if cfg.Path != "" {
+		if err := reload.WatchFile("EOL catalogue", cfg.Path, cfg.ReloadInterval, checker.Apply); err != nil {
+			return nil, err
+		}
+	}
+
+	broker.Consume("eol", 256, func(e events.Event) {
+		checker.evaluateWorkload(e.Namespace, e.Kind, e.Workload)
+	})
+	go func() {
+		for {
+			time.Sleep(refreshInterval)
+			checker.evaluateAll()
+		}
+	}()
+	return checker, nil
+}
+
+// Apply loads a catalogue file over the bundled catalogue and evaluates the whole inventory against it
+func (c *Checker) Apply(data []byte) error {
+	catalogue, err := Load(data)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.catalogue = catalogue
+	c.mu.Unlock()
+	c.evaluateAll()
+	return nil
+}
+
+// evaluateAll re-evaluates every workload of the inventory
+func (c *Checker) evaluateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The series are replaced workload by workload, so that a scrape during the evaluation never sees them vanish
+	stale := maps.Clone(c.results)
+	now := time.Now()
+	for _, w := range c.store.List() {
+		delete(stale, w.Key())
+		c.setResults(w.Namespace, w.Kind, w.Name, c.evaluate(w, now))
+	}
+	for _, results := range stale {
+		c.setResults(results[0].Namespace, results[0].Kind, results[0].Workload, nil)
+	}
+}
+
+// EvaluateNamespace evaluates every workload of a namespace, after its initial listing (which publishes no event)
+func (c *Checker) EvaluateNamespace(namespace string) {
+	for _, w := range c.store.ListNamespace(namespace) {
+		c.evaluateWorkload(w.Namespace, w.Kind, w.Name)
+	}
+}
+
+// evaluateWorkload re-evaluates one workload after a change, or forgets it once deleted
+func (c *Checker) evaluateWorkload(namespace, kind, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var results []Result
+	if w, ok := c.store.Get(namespace, kind, name); ok {
+		results = c.evaluate(w, time.Now())
+	}
+	c.setResults(namespace, kind, name, results)
+}
+
+// evaluate checks every container of a workload. Must be called with the lock held.
+func (c *Checker) evaluate(w inventory.Workload, now time.Time) []Result {
+	var results []Result
+	for _, container := range w.Containers {
+		status, ok := c.catalogue.Evaluate(container.Image, now)
+		if !ok {
+			continue
+		}
+		slog.Debug("EOL evaluation",
+			slog.String("ns/workload", w.Namespace+"/"+w.Name),
+			slog.String("container", container.Name),
+			slog.String("product", status.Product),
+			slog.String("cycle", status.Cycle),
+			slog.Bool("eol", status.EOL))
+		results = append(results, Result{
+			Namespace: w.Namespace,
+			Kind:      w.Kind,
+			Workload:  w.Name,
+			Container: container.Name,
+			Image:     container.Image,
+			Status:    status,
+		})
+	}
+	return results
+}
+
+// setResults replaces the results and the series of a workload. Must be called with the lock held.
+func (c *Checker) setResults(namespace, kind, name string, results []Result) {
+	key := inventory.WorkloadKey(namespace, kind, name)
+	eol, daysUntil := c.eolSeries.Update(key), c.daysUntilSeries.Update(key)
+	for _, r := range results {
+		labels := []string{r.Namespace, r.Kind, r.Workload, r.Container, r.Image.Reference, r.Product, r.Cycle}
+		eol.Set(boolToFloat(r.EOL), labels...)
+		if r.DaysUntilEOL != nil {
+			daysUntil.Set(float64(*r.DaysUntilEOL), labels...)
+		}
+	}
+	eol.Commit()
+	daysUntil.Commit()
+
+	if len(results) == 0 {
+		delete(c.results, key)
+		return
+	}
+	c.results[key] = results
+}
+
+// Lookup returns the EOL status of a container, when the catalogue knows its image
+func (c *Checker) Lookup(namespace, kind, name, container string) (Status, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range c.results[inventory.WorkloadKey(namespace, kind, name)] {
+		if r.Container == container {
+			return r.Status, true
+		}
+	}
+	return Status{}, false
+}
+
+/*
+Results returns the EOL status of the containers of a namespace ("" for all), sorted by workload.
+With withinDays >= 0, only the containers already EOL or reaching their EOL within that many days are returned.
+*/
+func (c *Checker) Results(namespace string, withinDays int) []Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	results := []Result{}
+	for _, workloadResults := range c.results {
+		for _, r := range workloadResults {
+			if namespace != "" && r.Namespace != namespace {
+				continue
+			}
+			if withinDays >= 0 && !r.EOL && (r.DaysUntilEOL == nil || *r.DaysUntilEOL > withinDays) {
+				continue
+			}
+			results = append(results, r)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		ki := inventory.WorkloadKey(results[i].Namespace, results[i].Kind, results[i].Workload) + "/" + results[i].Container
+		kj := inventory.WorkloadKey(results[j].Namespace, results[j].Kind, results[j].Workload) + "/" + results[j].Container
+		return ki < kj
+	})
+	return results
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
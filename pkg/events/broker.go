@@ -0,0 +1,135 @@
+package events
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+/*
+Broker distributes events to subscribers and remembers the most recent ones in a bounded ring buffer,
+so that a client that got disconnected can resume from the last event ID it has seen.
+
+Subscribers get a buffered channel. A subscriber that can't keep up is dropped (its channel is closed)
+rather than slowing down the controller: it is expected to reconnect and resume from its last event ID.
+*/
+type Broker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	ring        []Event // circular buffer of the last len(ring) events
+	next        int     // position of the next write in ring
+	count       int     // number of valid events in ring
+	subscribers map[chan Event]struct{}
+}
+
+// NewBroker returns a Broker retaining up to capacity past events
+func NewBroker(capacity int) *Broker {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Broker{
+		ring:        make([]Event, capacity),
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish assigns an ID (and a timestamp if missing) to the event, stores it and sends it to every subscriber
+func (b *Broker) Publish(e Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	e.ID = b.lastID
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+
+	b.ring[b.next] = e
+	b.next = (b.next + 1) % len(b.ring)
+	if b.count < len(b.ring) {
+		b.count++
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			slog.Warn("Dropping slow event subscriber", slog.Uint64("event_id", e.ID))
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return e
+}
+
+/*
+Subscribe registers a new subscriber.
+- backlog holds the retained events with an ID greater than afterID (use 0 for "everything retained")
+- live receives every event published from now on; it is closed when cancel is called or the subscriber is dropped
+Both are computed under the same lock, so no event can fall in between.
+*/
+func (b *Broker) Subscribe(afterID uint64, buffer int) (backlog []Event, live <-chan Event, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// An ID we never issued means Sentinel restarted since the client last connected: replay everything retained
+	if afterID > b.lastID {
+		afterID = 0
+	}
+
+	for i := 0; i < b.count; i++ {
+		e := b.ring[(b.next-b.count+i+len(b.ring))%len(b.ring)]
+		if e.ID > afterID {
+			backlog = append(backlog, e)
+		}
+	}
+
+	ch := make(chan Event, buffer)
+	b.subscribers[ch] = struct{}{}
+
+	cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return backlog, ch, cancel
+}
+
+// LastID returns the ID of the most recent event published
+func (b *Broker) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+/*
+Consume starts a Go routine calling handle for every event published from now on, in order.
+If the consumer falls behind and gets dropped by the Broker, it subscribes again and catches up
+from the retained events, so events are only lost if more than the ring capacity were missed.
+*/
+func (b *Broker) Consume(name string, buffer int, handle func(Event)) {
+	lastSeen := b.LastID()
+
+	go func() {
+		for {
+			backlog, live, _ := b.Subscribe(lastSeen, buffer)
+			if len(backlog) > 0 && backlog[0].ID > lastSeen+1 {
+				slog.Warn("Event consumer missed events", slog.String("consumer", name), slog.Uint64("from_id", lastSeen+1), slog.Uint64("to_id", backlog[0].ID-1))
+			}
+			for _, e := range backlog {
+				handle(e)
+				lastSeen = e.ID
+			}
+			for e := range live {
+				handle(e)
+				lastSeen = e.ID
+			}
+			slog.Warn("Event consumer fell behind, resubscribing", slog.String("consumer", name), slog.Uint64("last_id", lastSeen))
+		}
+	}()
+}
@@ -0,0 +1,43 @@
+/*
+Image change events produced by the controller.
+
+SCOPE:
+- Define the structured events Sentinel emits when it observes images being added, changed or removed
+- Fan events out to any number of subscribers (SSE clients, notifiers, ...) through a Broker
+*/
+
+package events
+
+import (
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+)
+
+// Type identifies what happened to a container image
+type Type string
+
+const (
+	// ImageAdded is emitted when a container appears (new workload or new container), not for the workloads already running at startup
+	ImageAdded Type = "image.added"
+	// ImageChanged is emitted when a container of an existing workload switches to a different image
+	ImageChanged Type = "image.changed"
+	// ImageRemoved is emitted when a container goes away (workload deleted or container removed)
+	ImageRemoved Type = "image.removed"
+)
+
+// Event is a single image add/change/remove observed on a workload container
+type Event struct {
+	ID        uint64    `json:"id"` // Monotonic, assigned by the Broker. Used by clients to resume a stream.
+	Type      Type      `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Namespace string    `json:"namespace"`
+	Kind      string    `json:"kind"`
+	Workload  string    `json:"workload"`
+	Container string    `json:"container"`
+	// ResourceVersion of the workload when the event was observed (last known version for deletions)
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	OldImage        *inventory.Image  `json:"oldImage,omitempty"`    // nil for ImageAdded
+	NewImage        *inventory.Image  `json:"newImage,omitempty"`    // nil for ImageRemoved
+	ExtraLabels     map[string]string `json:"extraLabels,omitempty"` // timeseriesLabelName -> value, from the extraLabels config
+}
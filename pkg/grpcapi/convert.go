@@ -0,0 +1,56 @@
+package grpcapi
+
+import (
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/grpcapi/sentinelv1"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Conversions from Sentinel's internal types to their protobuf counterparts
+
+func toProtoImage(img *inventory.Image) *sentinelv1.Image {
+	if img == nil {
+		return nil
+	}
+	return &sentinelv1.Image{
+		Reference:  img.Reference,
+		Registry:   img.Registry,
+		Repository: img.Repository,
+		Tag:        img.Tag,
+		Digest:     img.Digest,
+	}
+}
+
+func toProtoWorkload(w inventory.Workload) *sentinelv1.Workload {
+	pw := &sentinelv1.Workload{
+		Namespace:       w.Namespace,
+		Kind:            w.Kind,
+		Name:            w.Name,
+		ResourceVersion: w.ResourceVersion,
+		ExtraLabels:     w.ExtraLabels,
+	}
+	for _, c := range w.Containers {
+		pw.Containers = append(pw.Containers, &sentinelv1.Container{
+			Name:  c.Name,
+			Image: toProtoImage(&c.Image),
+		})
+	}
+	return pw
+}
+
+func toProtoEvent(e events.Event) *sentinelv1.ChangeEvent {
+	return &sentinelv1.ChangeEvent{
+		Id:              e.ID,
+		Type:            string(e.Type),
+		Timestamp:       timestamppb.New(e.Timestamp),
+		Namespace:       e.Namespace,
+		Kind:            e.Kind,
+		Workload:        e.Workload,
+		Container:       e.Container,
+		OldImage:        toProtoImage(e.OldImage),
+		NewImage:        toProtoImage(e.NewImage),
+		ExtraLabels:     e.ExtraLabels,
+		ResourceVersion: e.ResourceVersion,
+	}
+}
@@ -0,0 +1,715 @@
+// Sentinel gRPC API: typed access to the image inventory and to the stream of image changes.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: sentinel/v1/sentinel.proto
+
+package sentinelv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Image is a parsed container image reference.
+type Image struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reference     string                 `protobuf:"bytes,1,opt,name=reference,proto3" json:"reference,omitempty"`   // Full image string, e.g. "ghcr.io/myorg/myapp:v1.2.3"
+	Registry      string                 `protobuf:"bytes,2,opt,name=registry,proto3" json:"registry,omitempty"`     // e.g. "ghcr.io"
+	Repository    string                 `protobuf:"bytes,3,opt,name=repository,proto3" json:"repository,omitempty"` // e.g. "myorg/myapp"
+	Tag           string                 `protobuf:"bytes,4,opt,name=tag,proto3" json:"tag,omitempty"`               // e.g. "v1.2.3"
+	Digest        string                 `protobuf:"bytes,5,opt,name=digest,proto3" json:"digest,omitempty"`         // e.g. "sha256:..." when the image is pinned
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Image) Reset() {
+	*x = Image{}
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Image) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Image) ProtoMessage() {}
+
+func (x *Image) ProtoReflect() protoreflect.Message {
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Image.ProtoReflect.Descriptor instead.
+func (*Image) Descriptor() ([]byte, []int) {
+	return file_sentinel_v1_sentinel_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Image) GetReference() string {
+	if x != nil {
+		return x.Reference
+	}
+	return ""
+}
+
+func (x *Image) GetRegistry() string {
+	if x != nil {
+		return x.Registry
+	}
+	return ""
+}
+
+func (x *Image) GetRepository() string {
+	if x != nil {
+		return x.Repository
+	}
+	return ""
+}
+
+func (x *Image) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *Image) GetDigest() string {
+	if x != nil {
+		return x.Digest
+	}
+	return ""
+}
+
+// Container is a single container of a workload and the image it runs.
+type Container struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Image         *Image                 `protobuf:"bytes,2,opt,name=image,proto3" json:"image,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Container) Reset() {
+	*x = Container{}
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Container) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Container) ProtoMessage() {}
+
+func (x *Container) ProtoReflect() protoreflect.Message {
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Container.ProtoReflect.Descriptor instead.
+func (*Container) Descriptor() ([]byte, []int) {
+	return file_sentinel_v1_sentinel_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Container) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Container) GetImage() *Image {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+// Workload is a Deployment, StatefulSet, DaemonSet, ... tracked by Sentinel.
+type Workload struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Namespace       string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Kind            string                 `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	Name            string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	ResourceVersion string                 `protobuf:"bytes,4,opt,name=resource_version,json=resourceVersion,proto3" json:"resource_version,omitempty"`
+	ExtraLabels     map[string]string      `protobuf:"bytes,5,rep,name=extra_labels,json=extraLabels,proto3" json:"extra_labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // timeseriesLabelName -> value, from the extraLabels config
+	Containers      []*Container           `protobuf:"bytes,6,rep,name=containers,proto3" json:"containers,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Workload) Reset() {
+	*x = Workload{}
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Workload) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Workload) ProtoMessage() {}
+
+func (x *Workload) ProtoReflect() protoreflect.Message {
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Workload.ProtoReflect.Descriptor instead.
+func (*Workload) Descriptor() ([]byte, []int) {
+	return file_sentinel_v1_sentinel_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Workload) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *Workload) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *Workload) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Workload) GetResourceVersion() string {
+	if x != nil {
+		return x.ResourceVersion
+	}
+	return ""
+}
+
+func (x *Workload) GetExtraLabels() map[string]string {
+	if x != nil {
+		return x.ExtraLabels
+	}
+	return nil
+}
+
+func (x *Workload) GetContainers() []*Container {
+	if x != nil {
+		return x.Containers
+	}
+	return nil
+}
+
+type ListWorkloadsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"` // Optional filter
+	Kind          string                 `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`           // Optional filter, e.g. "Deployment"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListWorkloadsRequest) Reset() {
+	*x = ListWorkloadsRequest{}
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListWorkloadsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListWorkloadsRequest) ProtoMessage() {}
+
+func (x *ListWorkloadsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListWorkloadsRequest.ProtoReflect.Descriptor instead.
+func (*ListWorkloadsRequest) Descriptor() ([]byte, []int) {
+	return file_sentinel_v1_sentinel_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListWorkloadsRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ListWorkloadsRequest) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+type ListWorkloadsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Workloads     []*Workload            `protobuf:"bytes,1,rep,name=workloads,proto3" json:"workloads,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListWorkloadsResponse) Reset() {
+	*x = ListWorkloadsResponse{}
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListWorkloadsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListWorkloadsResponse) ProtoMessage() {}
+
+func (x *ListWorkloadsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListWorkloadsResponse.ProtoReflect.Descriptor instead.
+func (*ListWorkloadsResponse) Descriptor() ([]byte, []int) {
+	return file_sentinel_v1_sentinel_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListWorkloadsResponse) GetWorkloads() []*Workload {
+	if x != nil {
+		return x.Workloads
+	}
+	return nil
+}
+
+type GetWorkloadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Kind          string                 `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetWorkloadRequest) Reset() {
+	*x = GetWorkloadRequest{}
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetWorkloadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetWorkloadRequest) ProtoMessage() {}
+
+func (x *GetWorkloadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetWorkloadRequest.ProtoReflect.Descriptor instead.
+func (*GetWorkloadRequest) Descriptor() ([]byte, []int) {
+	return file_sentinel_v1_sentinel_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetWorkloadRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *GetWorkloadRequest) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *GetWorkloadRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type WatchChangesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AfterId       uint64                 `protobuf:"varint,1,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"` // Resume after this event ID. 0 replays every event still retained in memory.
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`             // Optional filter
+	Kind          string                 `protobuf:"bytes,3,opt,name=kind,proto3" json:"kind,omitempty"`                       // Optional filter
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchChangesRequest) Reset() {
+	*x = WatchChangesRequest{}
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchChangesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchChangesRequest) ProtoMessage() {}
+
+func (x *WatchChangesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchChangesRequest.ProtoReflect.Descriptor instead.
+func (*WatchChangesRequest) Descriptor() ([]byte, []int) {
+	return file_sentinel_v1_sentinel_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *WatchChangesRequest) GetAfterId() uint64 {
+	if x != nil {
+		return x.AfterId
+	}
+	return 0
+}
+
+func (x *WatchChangesRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *WatchChangesRequest) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+// ChangeEvent is an image added to, changed in or removed from a workload container.
+type ChangeEvent struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type            string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"` // "image.added", "image.changed" or "image.removed"
+	Timestamp       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Namespace       string                 `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Kind            string                 `protobuf:"bytes,5,opt,name=kind,proto3" json:"kind,omitempty"`
+	Workload        string                 `protobuf:"bytes,6,opt,name=workload,proto3" json:"workload,omitempty"`
+	Container       string                 `protobuf:"bytes,7,opt,name=container,proto3" json:"container,omitempty"`
+	OldImage        *Image                 `protobuf:"bytes,8,opt,name=old_image,json=oldImage,proto3" json:"old_image,omitempty"` // Unset for "image.added"
+	NewImage        *Image                 `protobuf:"bytes,9,opt,name=new_image,json=newImage,proto3" json:"new_image,omitempty"` // Unset for "image.removed"
+	ExtraLabels     map[string]string      `protobuf:"bytes,10,rep,name=extra_labels,json=extraLabels,proto3" json:"extra_labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ResourceVersion string                 `protobuf:"bytes,11,opt,name=resource_version,json=resourceVersion,proto3" json:"resource_version,omitempty"` // Workload resourceVersion when the event was observed
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ChangeEvent) Reset() {
+	*x = ChangeEvent{}
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeEvent) ProtoMessage() {}
+
+func (x *ChangeEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_sentinel_v1_sentinel_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeEvent.ProtoReflect.Descriptor instead.
+func (*ChangeEvent) Descriptor() ([]byte, []int) {
+	return file_sentinel_v1_sentinel_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ChangeEvent) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ChangeEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetWorkload() string {
+	if x != nil {
+		return x.Workload
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetContainer() string {
+	if x != nil {
+		return x.Container
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetOldImage() *Image {
+	if x != nil {
+		return x.OldImage
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetNewImage() *Image {
+	if x != nil {
+		return x.NewImage
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetExtraLabels() map[string]string {
+	if x != nil {
+		return x.ExtraLabels
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetResourceVersion() string {
+	if x != nil {
+		return x.ResourceVersion
+	}
+	return ""
+}
+
+var File_sentinel_v1_sentinel_proto protoreflect.FileDescriptor
+
+const file_sentinel_v1_sentinel_proto_rawDesc = "" +
+	"\n" +
+	"\x1asentinel/v1/sentinel.proto\x12\vsentinel.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x8b\x01\n" +
+	"\x05Image\x12\x1c\n" +
+	"\treference\x18\x01 \x01(\tR\treference\x12\x1a\n" +
+	"\bregistry\x18\x02 \x01(\tR\bregistry\x12\x1e\n" +
+	"\n" +
+	"repository\x18\x03 \x01(\tR\n" +
+	"repository\x12\x10\n" +
+	"\x03tag\x18\x04 \x01(\tR\x03tag\x12\x16\n" +
+	"\x06digest\x18\x05 \x01(\tR\x06digest\"I\n" +
+	"\tContainer\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12(\n" +
+	"\x05image\x18\x02 \x01(\v2\x12.sentinel.v1.ImageR\x05image\"\xbe\x02\n" +
+	"\bWorkload\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04kind\x18\x02 \x01(\tR\x04kind\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12)\n" +
+	"\x10resource_version\x18\x04 \x01(\tR\x0fresourceVersion\x12I\n" +
+	"\fextra_labels\x18\x05 \x03(\v2&.sentinel.v1.Workload.ExtraLabelsEntryR\vextraLabels\x126\n" +
+	"\n" +
+	"containers\x18\x06 \x03(\v2\x16.sentinel.v1.ContainerR\n" +
+	"containers\x1a>\n" +
+	"\x10ExtraLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"H\n" +
+	"\x14ListWorkloadsRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04kind\x18\x02 \x01(\tR\x04kind\"L\n" +
+	"\x15ListWorkloadsResponse\x123\n" +
+	"\tworkloads\x18\x01 \x03(\v2\x15.sentinel.v1.WorkloadR\tworkloads\"Z\n" +
+	"\x12GetWorkloadRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04kind\x18\x02 \x01(\tR\x04kind\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\"b\n" +
+	"\x13WatchChangesRequest\x12\x19\n" +
+	"\bafter_id\x18\x01 \x01(\x04R\aafterId\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04kind\x18\x03 \x01(\tR\x04kind\"\xf2\x03\n" +
+	"\vChangeEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x128\n" +
+	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x1c\n" +
+	"\tnamespace\x18\x04 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04kind\x18\x05 \x01(\tR\x04kind\x12\x1a\n" +
+	"\bworkload\x18\x06 \x01(\tR\bworkload\x12\x1c\n" +
+	"\tcontainer\x18\a \x01(\tR\tcontainer\x12/\n" +
+	"\told_image\x18\b \x01(\v2\x12.sentinel.v1.ImageR\boldImage\x12/\n" +
+	"\tnew_image\x18\t \x01(\v2\x12.sentinel.v1.ImageR\bnewImage\x12L\n" +
+	"\fextra_labels\x18\n" +
+	" \x03(\v2).sentinel.v1.ChangeEvent.ExtraLabelsEntryR\vextraLabels\x12)\n" +
+	"\x10resource_version\x18\v \x01(\tR\x0fresourceVersion\x1a>\n" +
+	"\x10ExtraLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012\xff\x01\n" +
+	"\x10InventoryService\x12V\n" +
+	"\rListWorkloads\x12!.sentinel.v1.ListWorkloadsRequest\x1a\".sentinel.v1.ListWorkloadsResponse\x12E\n" +
+	"\vGetWorkload\x12\x1f.sentinel.v1.GetWorkloadRequest\x1a\x15.sentinel.v1.Workload\x12L\n" +
+	"\fWatchChanges\x12 .sentinel.v1.WatchChangesRequest\x1a\x18.sentinel.v1.ChangeEvent0\x01BBZ@github.com/MatteoMori/sentinel/pkg/grpcapi/sentinelv1;sentinelv1b\x06proto3"
+
+var (
+	file_sentinel_v1_sentinel_proto_rawDescOnce sync.Once
+	file_sentinel_v1_sentinel_proto_rawDescData []byte
+)
+
+func file_sentinel_v1_sentinel_proto_rawDescGZIP() []byte {
+	file_sentinel_v1_sentinel_proto_rawDescOnce.Do(func() {
+		file_sentinel_v1_sentinel_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_sentinel_v1_sentinel_proto_rawDesc), len(file_sentinel_v1_sentinel_proto_rawDesc)))
+	})
+	return file_sentinel_v1_sentinel_proto_rawDescData
+}
+
+var file_sentinel_v1_sentinel_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_sentinel_v1_sentinel_proto_goTypes = []any{
+	(*Image)(nil),                 // 0: sentinel.v1.Image
+	(*Container)(nil),             // 1: sentinel.v1.Container
+	(*Workload)(nil),              // 2: sentinel.v1.Workload
+	(*ListWorkloadsRequest)(nil),  // 3: sentinel.v1.ListWorkloadsRequest
+	(*ListWorkloadsResponse)(nil), // 4: sentinel.v1.ListWorkloadsResponse
+	(*GetWorkloadRequest)(nil),    // 5: sentinel.v1.GetWorkloadRequest
+	(*WatchChangesRequest)(nil),   // 6: sentinel.v1.WatchChangesRequest
+	(*ChangeEvent)(nil),           // 7: sentinel.v1.ChangeEvent
+	nil,                           // 8: sentinel.v1.Workload.ExtraLabelsEntry
+	nil,                           // 9: sentinel.v1.ChangeEvent.ExtraLabelsEntry
+	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
+}
+var file_sentinel_v1_sentinel_proto_depIdxs = []int32{
+	0,  // 0: sentinel.v1.Container.image:type_name -> sentinel.v1.Image
+	8,  // 1: sentinel.v1.Workload.extra_labels:type_name -> sentinel.v1.Workload.ExtraLabelsEntry
+	1,  // 2: sentinel.v1.Workload.containers:type_name -> sentinel.v1.Container
+	2,  // 3: sentinel.v1.ListWorkloadsResponse.workloads:type_name -> sentinel.v1.Workload
+	10, // 4: sentinel.v1.ChangeEvent.timestamp:type_name -> google.protobuf.Timestamp
+	0,  // 5: sentinel.v1.ChangeEvent.old_image:type_name -> sentinel.v1.Image
+	0,  // 6: sentinel.v1.ChangeEvent.new_image:type_name -> sentinel.v1.Image
+	9,  // 7: sentinel.v1.ChangeEvent.extra_labels:type_name -> sentinel.v1.ChangeEvent.ExtraLabelsEntry
+	3,  // 8: sentinel.v1.InventoryService.ListWorkloads:input_type -> sentinel.v1.ListWorkloadsRequest
+	5,  // 9: sentinel.v1.InventoryService.GetWorkload:input_type -> sentinel.v1.GetWorkloadRequest
+	6,  // 10: sentinel.v1.InventoryService.WatchChanges:input_type -> sentinel.v1.WatchChangesRequest
+	4,  // 11: sentinel.v1.InventoryService.ListWorkloads:output_type -> sentinel.v1.ListWorkloadsResponse
+	2,  // 12: sentinel.v1.InventoryService.GetWorkload:output_type -> sentinel.v1.Workload
+	7,  // 13: sentinel.v1.InventoryService.WatchChanges:output_type -> sentinel.v1.ChangeEvent
+	11, // [11:14] is the sub-list for method output_type
+	8,  // [8:11] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_sentinel_v1_sentinel_proto_init() }
+func file_sentinel_v1_sentinel_proto_init() {
+	if File_sentinel_v1_sentinel_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sentinel_v1_sentinel_proto_rawDesc), len(file_sentinel_v1_sentinel_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_sentinel_v1_sentinel_proto_goTypes,
+		DependencyIndexes: file_sentinel_v1_sentinel_proto_depIdxs,
+		MessageInfos:      file_sentinel_v1_sentinel_proto_msgTypes,
+	}.Build()
+	File_sentinel_v1_sentinel_proto = out.File
+	file_sentinel_v1_sentinel_proto_goTypes = nil
+	file_sentinel_v1_sentinel_proto_depIdxs = nil
+}
@@ -0,0 +1,126 @@
+/*
+Optional gRPC server.
+
+SCOPE:
+- Serve the sentinel.v1.InventoryService defined in proto/sentinel/v1/sentinel.proto
+- ListWorkloads/GetWorkload read the inventory built by the controller
+- WatchChanges streams the image events published by the controller (same source as /api/v1/events)
+*/
+
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"net"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/grpcapi/sentinelv1"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements sentinelv1.InventoryServiceServer
+type Server struct {
+	sentinelv1.UnimplementedInventoryServiceServer
+
+	store  *inventory.Store
+	broker *events.Broker
+}
+
+// NewServer returns an InventoryService backed by the given inventory and event broker
+func NewServer(store *inventory.Store, broker *events.Broker) *Server {
+	return &Server{store: store, broker: broker}
+}
+
+// Register creates a gRPC server with the InventoryService (and server reflection, for grpcurl) registered on it
+func Register(store *inventory.Store, broker *events.Broker) *grpc.Server {
+	grpcServer := grpc.NewServer()
+	sentinelv1.RegisterInventoryServiceServer(grpcServer, NewServer(store, broker))
+	reflection.Register(grpcServer)
+	return grpcServer
+}
+
+// Init starts the gRPC server on the given port in its own Go routine
+func Init(port string, store *inventory.Store, broker *events.Broker) {
+	grpcServer := Register(store, broker)
+
+	go func() {
+		listener, err := net.Listen("tcp", ":"+port)
+		if err != nil {
+			slog.Error("gRPC server failed to listen", slog.String("port", port), slog.Any("error", err))
+			return
+		}
+		slog.Info("Starting gRPC server", slog.String("port", port))
+		if err := grpcServer.Serve(listener); err != nil {
+			slog.Error("gRPC server failed", slog.Any("error", err))
+		}
+	}()
+}
+
+// ListWorkloads returns every tracked workload, optionally filtered by namespace and kind
+func (s *Server) ListWorkloads(ctx context.Context, req *sentinelv1.ListWorkloadsRequest) (*sentinelv1.ListWorkloadsResponse, error) {
+	resp := &sentinelv1.ListWorkloadsResponse{}
+	for _, w := range s.store.List() {
+		if (req.GetNamespace() != "" && w.Namespace != req.GetNamespace()) || (req.GetKind() != "" && w.Kind != req.GetKind()) {
+			continue
+		}
+		resp.Workloads = append(resp.Workloads, toProtoWorkload(w))
+	}
+	return resp, nil
+}
+
+// GetWorkload returns a single workload
+func (s *Server) GetWorkload(ctx context.Context, req *sentinelv1.GetWorkloadRequest) (*sentinelv1.Workload, error) {
+	if req.GetNamespace() == "" || req.GetKind() == "" || req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "namespace, kind and name are required")
+	}
+
+	w, ok := s.store.Get(req.GetNamespace(), req.GetKind(), req.GetName())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "workload %s not tracked by Sentinel", inventory.WorkloadKey(req.GetNamespace(), req.GetKind(), req.GetName()))
+	}
+	return toProtoWorkload(w), nil
+}
+
+/*
+WatchChanges replays the retained events after req.AfterId, then streams new events until the client goes away.
+A client that can't keep up is disconnected with RESOURCE_EXHAUSTED and is expected to resume from the last ID it received.
+*/
+func (s *Server) WatchChanges(req *sentinelv1.WatchChangesRequest, stream grpc.ServerStreamingServer[sentinelv1.ChangeEvent]) error {
+	matches := func(e events.Event) bool {
+		return (req.GetNamespace() == "" || e.Namespace == req.GetNamespace()) && (req.GetKind() == "" || e.Kind == req.GetKind())
+	}
+
+	backlog, live, cancel := s.broker.Subscribe(req.GetAfterId(), 64)
+	defer cancel()
+
+	for _, e := range backlog {
+		if !matches(e) {
+			continue
+		}
+		if err := stream.Send(toProtoEvent(e)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case e, ok := <-live:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher too slow, resume from the last received event ID")
+			}
+			if !matches(e) {
+				continue
+			}
+			if err := stream.Send(toProtoEvent(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
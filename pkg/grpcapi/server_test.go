@@ -0,0 +1,161 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/grpcapi/sentinelv1"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves the InventoryService over an in-memory listener and returns a client connected to it
+func newTestClient(t *testing.T, store *inventory.Store, broker *events.Broker) sentinelv1.InventoryServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := Register(store, broker)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return sentinelv1.NewInventoryServiceClient(conn)
+}
+
+func testWorkload(namespace, kind, name, image string) inventory.Workload {
+	return inventory.Workload{
+		Namespace:  namespace,
+		Kind:       kind,
+		Name:       name,
+		Containers: []inventory.Container{{Name: "app", Image: inventory.ParseImage(image)}},
+	}
+}
+
+func TestListWorkloads(t *testing.T) {
+	store := inventory.NewStore()
+	store.Upsert(testWorkload("prod", "Deployment", "api", "ghcr.io/acme/api:1.0.0"))
+	store.Upsert(testWorkload("prod", "StatefulSet", "db", "postgres:16"))
+	store.Upsert(testWorkload("dev", "Deployment", "api", "ghcr.io/acme/api:1.1.0"))
+	client := newTestClient(t, store, events.NewBroker(16))
+
+	tests := []struct {
+		name string
+		req  *sentinelv1.ListWorkloadsRequest
+		want []string
+	}{
+		{"all", &sentinelv1.ListWorkloadsRequest{}, []string{"dev/Deployment/api", "prod/Deployment/api", "prod/StatefulSet/db"}},
+		{"namespace", &sentinelv1.ListWorkloadsRequest{Namespace: "prod"}, []string{"prod/Deployment/api", "prod/StatefulSet/db"}},
+		{"kind", &sentinelv1.ListWorkloadsRequest{Kind: "Deployment"}, []string{"dev/Deployment/api", "prod/Deployment/api"}},
+		{"no match", &sentinelv1.ListWorkloadsRequest{Namespace: "staging"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.ListWorkloads(context.Background(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, w := range resp.GetWorkloads() {
+				got = append(got, inventory.WorkloadKey(w.GetNamespace(), w.GetKind(), w.GetName()))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetWorkload(t *testing.T) {
+	store := inventory.NewStore()
+	store.Upsert(testWorkload("prod", "Deployment", "api", "ghcr.io/acme/api:1.0.0"))
+	client := newTestClient(t, store, events.NewBroker(16))
+
+	w, err := client.GetWorkload(context.Background(), &sentinelv1.GetWorkloadRequest{Namespace: "prod", Kind: "Deployment", Name: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.GetContainers()) != 1 || w.GetContainers()[0].GetImage().GetTag() != "1.0.0" || w.GetContainers()[0].GetImage().GetRegistry() != "ghcr.io" {
+		t.Fatalf("unexpected workload %v", w)
+	}
+
+	tests := []struct {
+		name string
+		req  *sentinelv1.GetWorkloadRequest
+		code codes.Code
+	}{
+		{"unknown", &sentinelv1.GetWorkloadRequest{Namespace: "prod", Kind: "Deployment", Name: "web"}, codes.NotFound},
+		{"missing name", &sentinelv1.GetWorkloadRequest{Namespace: "prod", Kind: "Deployment"}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.GetWorkload(context.Background(), tt.req)
+			if status.Code(err) != tt.code {
+				t.Fatalf("got %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestWatchChanges(t *testing.T) {
+	broker := events.NewBroker(16)
+	client := newTestClient(t, inventory.NewStore(), broker)
+	oldImage, newImage := inventory.ParseImage("nginx:1.25"), inventory.ParseImage("nginx:1.27")
+
+	// Retained before the client connects: replayed, filtered by namespace
+	broker.Publish(events.Event{Type: events.ImageAdded, Namespace: "dev", Kind: "Deployment", Workload: "web", Container: "nginx", NewImage: &oldImage})
+	first := broker.Publish(events.Event{Type: events.ImageAdded, Namespace: "prod", Kind: "Deployment", Workload: "web", Container: "nginx", NewImage: &oldImage})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchChanges(ctx, &sentinelv1.WatchChangesRequest{Namespace: "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.GetId() != first.ID || e.GetType() != string(events.ImageAdded) || e.GetNewImage().GetTag() != "1.25" {
+		t.Fatalf("unexpected replayed event %v", e)
+	}
+
+	// Published live, once the stream is open
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		broker.Publish(events.Event{Type: events.ImageChanged, Namespace: "dev", Kind: "Deployment", Workload: "web", Container: "nginx", OldImage: &oldImage, NewImage: &newImage})
+		broker.Publish(events.Event{Type: events.ImageChanged, Namespace: "prod", Kind: "Deployment", Workload: "web", Container: "nginx", OldImage: &oldImage, NewImage: &newImage})
+	}()
+	e, err = stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.GetNamespace() != "prod" || e.GetType() != string(events.ImageChanged) || e.GetOldImage().GetTag() != "1.25" || e.GetNewImage().GetTag() != "1.27" {
+		t.Fatalf("unexpected live event %v", e)
+	}
+
+	// Resuming after the last ID received skips what was already seen
+	resumed, err := client.WatchChanges(ctx, &sentinelv1.WatchChangesRequest{Namespace: "prod", AfterId: first.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, err = resumed.Recv(); err != nil || e.GetType() != string(events.ImageChanged) {
+		t.Fatalf("unexpected resumed event %v (%v)", e, err)
+	}
+}
@@ -0,0 +1,141 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Revision is the image a container ran in one Kubernetes revision of its workload
+type Revision struct {
+	Number int64           // deployment.kubernetes.io/revision or ControllerRevision.Revision
+	Time   time.Time       // Creation time of the ReplicaSet / ControllerRevision
+	Image  inventory.Image // Image of the container in that revision
+}
+
+/*
+Backfill records the image history of a container reconstructed from the revisions Kubernetes keeps.
+Revisions must be sorted by Number. It returns the number of transitions written.
+
+Only what the history doesn't know yet is written, so that a change is never counted twice:
+  - revisions at or after the first transition recorded for the container are skipped (they were observed live)
+  - revisions older than the compaction cutoff are skipped
+  - a container known only from the baseline is left alone
+  - the first live transition, usually the image.added recorded when Sentinel first saw the workload,
+    is dropped if it matches the latest reconstructed image, or turned into an image.changed otherwise
+
+Calling it again with the same revisions writes nothing.
+*/
+func (s *Store) Backfill(namespace, kind, workload, container string, extraLabels map[string]string, revisions []Revision) (int, error) {
+	written := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var notBefore time.Time
+		if raw := tx.Bucket(bucketMeta).Get(keyBaselineTime); raw != nil {
+			notBefore = decodeTime(raw)
+		}
+
+		firstKey, first, err := firstTransition(tx, namespace, kind, workload, container)
+		if err != nil {
+			return err
+		}
+		stateKey := containerKey(namespace, kind, workload, container)
+		if first == nil && tx.Bucket(bucketCurrent).Get(stateKey) != nil {
+			return nil // Known from the baseline, nothing recorded since
+		}
+
+		var previous *inventory.Image
+		var last Transition
+		var lastTime time.Time
+		for _, rev := range revisions {
+			// A rollback reuses an old ReplicaSet: keep the transitions in revision order
+			at := rev.Time
+			if at.Before(lastTime) {
+				at = lastTime
+			}
+			lastTime = at
+
+			if !at.After(notBefore) || (first != nil && !at.Before(first.Time)) {
+				continue
+			}
+			if previous != nil && previous.Reference == rev.Image.Reference {
+				continue
+			}
+
+			image := rev.Image
+			last = Transition{
+				Time:        at,
+				Type:        events.ImageAdded,
+				Namespace:   namespace,
+				Kind:        kind,
+				Workload:    workload,
+				Container:   container,
+				NewImage:    &image,
+				ExtraLabels: extraLabels,
+				Revision:    rev.Number,
+			}
+			if previous != nil {
+				last.Type = events.ImageChanged
+				last.OldImage = previous
+			}
+			if _, err := appendTransition(tx, last); err != nil {
+				return err
+			}
+			previous = &image
+			written++
+		}
+
+		if written == 0 {
+			return nil
+		}
+		if first == nil {
+			return putState(tx.Bucket(bucketCurrent), stateKey, stateAfter(last))
+		}
+		if first.Type != events.ImageAdded || first.NewImage == nil {
+			return nil
+		}
+
+		// The first sighting is now preceded by the real history
+		if first.NewImage.Reference == previous.Reference {
+			if err := tx.Bucket(bucketTimeline).Delete(timelineKey(namespace, kind, workload, firstKey)); err != nil {
+				return err
+			}
+			return tx.Bucket(bucketTransitions).Delete(firstKey)
+		}
+		first.Type = events.ImageChanged
+		first.OldImage = previous
+		value, err := json.Marshal(first)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(bucketTransitions).Put(firstKey, value)
+	})
+
+	return written, err
+}
+
+// firstTransition returns the oldest transition recorded for a container, or nil
+func firstTransition(tx *bolt.Tx, namespace, kind, workload, container string) ([]byte, *Transition, error) {
+	prefix := timelinePrefix(namespace, kind, workload)
+	data := tx.Bucket(bucketTransitions)
+
+	c := tx.Bucket(bucketTimeline).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		key := k[len(prefix):]
+		raw := data.Get(key)
+		if raw == nil {
+			continue
+		}
+		var t Transition
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return nil, nil, err
+		}
+		if t.Container == container {
+			return append([]byte(nil), key...), &t, nil
+		}
+	}
+	return nil, nil, nil
+}
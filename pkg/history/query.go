@@ -0,0 +1,128 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrBeforeRetention is returned when asking for a point in time older than the compaction cutoff
+type ErrBeforeRetention struct {
+	Since time.Time
+}
+
+func (e ErrBeforeRetention) Error() string {
+	return fmt.Sprintf("history is only retained since %s", e.Since.Format(time.RFC3339))
+}
+
+/*
+InventoryAt rebuilds the inventory as it was at time t: the baseline, plus every transition up to t.
+An empty namespace returns every namespace.
+*/
+func (s *Store) InventoryAt(t time.Time, namespace string) ([]inventory.Workload, error) {
+	state := make(map[string]containerState)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if raw := tx.Bucket(bucketMeta).Get(keyBaselineTime); raw != nil {
+			if since := decodeTime(raw); t.Before(since) {
+				return ErrBeforeRetention{Since: since}
+			}
+		}
+
+		err := tx.Bucket(bucketBaseline).ForEach(func(k, v []byte) error {
+			var cs containerState
+			if err := json.Unmarshal(v, &cs); err != nil {
+				return err
+			}
+			state[string(k)] = cs
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		end := transitionKey(t, ^uint64(0))
+		c := tx.Bucket(bucketTransitions).Cursor()
+		for k, v := c.First(); k != nil && bytes.Compare(k, end) <= 0; k, v = c.Next() {
+			var tr Transition
+			if err := json.Unmarshal(v, &tr); err != nil {
+				return err
+			}
+			key := string(containerKey(tr.Namespace, tr.Kind, tr.Workload, tr.Container))
+			if tr.Type == events.ImageRemoved {
+				delete(state, key)
+			} else {
+				state[key] = stateAfter(tr)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Group containers by workload
+	workloads := make(map[string]*inventory.Workload)
+	lastChange := make(map[string]time.Time)
+	for _, cs := range state {
+		if namespace != "" && cs.Namespace != namespace {
+			continue
+		}
+		key := inventory.WorkloadKey(cs.Namespace, cs.Kind, cs.Workload)
+		w, ok := workloads[key]
+		if !ok {
+			w = &inventory.Workload{Namespace: cs.Namespace, Kind: cs.Kind, Name: cs.Workload}
+			workloads[key] = w
+		}
+		// Extra labels and resource version from the most recently changed container
+		if !cs.Since.Before(lastChange[key]) {
+			lastChange[key] = cs.Since
+			w.ResourceVersion = cs.ResourceVersion
+			w.ExtraLabels = cs.ExtraLabels
+		}
+		w.Containers = append(w.Containers, inventory.Container{Name: cs.Container, Image: cs.Image})
+	}
+
+	result := make([]inventory.Workload, 0, len(workloads))
+	for _, w := range workloads {
+		sort.Slice(w.Containers, func(i, j int) bool { return w.Containers[i].Name < w.Containers[j].Name })
+		result = append(result, *w)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key() < result[j].Key() })
+
+	return result, nil
+}
+
+// Timeline returns the transitions of a workload between from and to (zero values are unbounded), oldest first
+func (s *Store) Timeline(namespace, kind, name string, from, to time.Time) ([]Transition, error) {
+	transitions := []Transition{}
+	prefix := timelinePrefix(namespace, kind, name)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketTransitions)
+		c := tx.Bucket(bucketTimeline).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			raw := data.Get(k[len(prefix):])
+			if raw == nil {
+				continue
+			}
+			var tr Transition
+			if err := json.Unmarshal(raw, &tr); err != nil {
+				return err
+			}
+			if (!from.IsZero() && tr.Time.Before(from)) || (!to.IsZero() && tr.Time.After(to)) {
+				continue
+			}
+			transitions = append(transitions, tr)
+		}
+		return nil
+	})
+
+	return transitions, err
+}
@@ -0,0 +1,404 @@
+/*
+Persistent history of image transitions.
+
+SCOPE:
+- Record every image added/changed/removed transition Sentinel observes in an embedded bbolt database (mount it on a PVC)
+- Survive restarts: once the informers listed the live workloads, what changed while Sentinel was down is recorded
+  (see Reconcile): workloads deployed, image changes and workloads deleted
+- Answer "what was running at time T?" and "what happened to this workload?"
+- Keep the database bounded: transitions older than the retention are folded into a baseline (compaction)
+- Accept transitions reconstructed from the Kubernetes revision history (ReplicaSets, ControllerRevisions) that predate
+  what was observed live (see backfill.go)
+
+LAYOUT (bbolt buckets):
+  - transitions: <time><seq> -> Transition (JSON), in chronological order
+  - timeline:    <workload key>\x00<time><seq> -> nothing, index of the transitions of a workload
+  - current:     <workload key>\x00<container> -> containerState (JSON), the latest known state
+  - baseline:    <workload key>\x00<container> -> containerState (JSON), the state at the compaction cutoff
+  - meta:        "baselineTime" -> time of the compaction cutoff
+*/
+
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketTransitions = []byte("transitions")
+	bucketTimeline    = []byte("timeline")
+	bucketCurrent     = []byte("current")
+	bucketBaseline    = []byte("baseline")
+	bucketMeta        = []byte("meta")
+
+	keyBaselineTime = []byte("baselineTime")
+)
+
+// Transition is an image added to, changed in or removed from a workload container
+type Transition struct {
+	Time            time.Time         `json:"time"`
+	Type            events.Type       `json:"type"`
+	Namespace       string            `json:"namespace"`
+	Kind            string            `json:"kind"`
+	Workload        string            `json:"workload"`
+	Container       string            `json:"container"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	OldImage        *inventory.Image  `json:"oldImage,omitempty"`
+	NewImage        *inventory.Image  `json:"newImage,omitempty"`
+	ExtraLabels     map[string]string `json:"extraLabels,omitempty"`
+	Revision        int64             `json:"revision,omitempty"` // Kubernetes revision, for transitions reconstructed by Backfill()
+}
+
+// containerState is the image a container runs at a given point in time
+type containerState struct {
+	Namespace       string            `json:"namespace"`
+	Kind            string            `json:"kind"`
+	Workload        string            `json:"workload"`
+	Container       string            `json:"container"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Image           inventory.Image   `json:"image"`
+	ExtraLabels     map[string]string `json:"extraLabels,omitempty"`
+	Since           time.Time         `json:"since"` // Time of the transition that led to this state
+}
+
+// Store is the on-disk history
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (or creates) the history database at path
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	// A second Sentinel pointing to the same file waits for the lock instead of failing immediately
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 30 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open history database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketTransitions, bucketTimeline, bucketCurrent, bucketBaseline, bucketMeta} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+/*
+Record stores the transition carried by an event, compared with the latest known state:
+  - image.added for a container already known with the same image is a re-list after a restart: nothing to record
+  - image.added for a container known with another image means it changed while Sentinel was down: recorded as image.changed
+  - image.removed for an unknown container is ignored
+*/
+func (s *Store) Record(e events.Event) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		current := tx.Bucket(bucketCurrent)
+		stateKey := containerKey(e.Namespace, e.Kind, e.Workload, e.Container)
+
+		var known *containerState
+		if raw := current.Get(stateKey); raw != nil {
+			known = &containerState{}
+			if err := json.Unmarshal(raw, known); err != nil {
+				return err
+			}
+		}
+
+		t := Transition{
+			Time:            e.Timestamp,
+			Type:            e.Type,
+			Namespace:       e.Namespace,
+			Kind:            e.Kind,
+			Workload:        e.Workload,
+			Container:       e.Container,
+			ResourceVersion: e.ResourceVersion,
+			OldImage:        e.OldImage,
+			NewImage:        e.NewImage,
+			ExtraLabels:     e.ExtraLabels,
+		}
+
+		switch e.Type {
+		case events.ImageAdded, events.ImageChanged:
+			if e.NewImage == nil {
+				return nil
+			}
+			if known != nil {
+				if known.Image.Reference == e.NewImage.Reference {
+					return nil // Nothing new
+				}
+				t.Type = events.ImageChanged
+				t.OldImage = &known.Image
+			}
+		case events.ImageRemoved:
+			if known == nil {
+				return nil
+			}
+		default:
+			return nil
+		}
+
+		return s.writeTransition(tx, t)
+	})
+}
+
+/*
+Reconcile compares the latest known state with the live workloads listed by the informers (which publish no event for them),
+and records what changed meanwhile as transitions at the current time:
+  - containers unknown, or known with another image, are recorded as image.added / image.changed
+  - known containers of the namespaces in scope that aren't live anymore are recorded as image.removed
+*/
+func (s *Store) Reconcile(live []inventory.Workload, inScope func(namespace string) bool) (int, error) {
+	now := time.Now().UTC()
+	recorded := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		current := tx.Bucket(bucketCurrent)
+		liveKeys := make(map[string]struct{})
+
+		for _, w := range live {
+			for _, c := range w.Containers {
+				stateKey := containerKey(w.Namespace, w.Kind, w.Name, c.Name)
+				liveKeys[string(stateKey)] = struct{}{}
+
+				image := c.Image
+				t := Transition{
+					Time:            now,
+					Type:            events.ImageAdded,
+					Namespace:       w.Namespace,
+					Kind:            w.Kind,
+					Workload:        w.Name,
+					Container:       c.Name,
+					ResourceVersion: w.ResourceVersion,
+					NewImage:        &image,
+					ExtraLabels:     w.ExtraLabels,
+				}
+				if raw := current.Get(stateKey); raw != nil {
+					var known containerState
+					if err := json.Unmarshal(raw, &known); err != nil {
+						return err
+					}
+					if known.Image.Reference == image.Reference {
+						continue // Nothing new
+					}
+					t.Type, t.OldImage = events.ImageChanged, &known.Image
+				}
+				if err := s.writeTransition(tx, t); err != nil {
+					return err
+				}
+				recorded++
+			}
+		}
+
+		// Collected first: the bucket can't be modified while iterating over it
+		var gone []containerState
+		err := current.ForEach(func(k, v []byte) error {
+			if _, ok := liveKeys[string(k)]; ok {
+				return nil
+			}
+			var known containerState
+			if err := json.Unmarshal(v, &known); err != nil {
+				return err
+			}
+			if inScope(known.Namespace) {
+				gone = append(gone, known)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, known := range gone {
+			t := Transition{
+				Time:            now,
+				Type:            events.ImageRemoved,
+				Namespace:       known.Namespace,
+				Kind:            known.Kind,
+				Workload:        known.Workload,
+				Container:       known.Container,
+				ResourceVersion: known.ResourceVersion,
+				OldImage:        &known.Image,
+				ExtraLabels:     known.ExtraLabels,
+			}
+			if err := s.writeTransition(tx, t); err != nil {
+				return err
+			}
+			recorded++
+		}
+		return nil
+	})
+	return recorded, err
+}
+
+// writeTransition appends a transition, indexes it and updates the current state. Must run in a write transaction.
+func (s *Store) writeTransition(tx *bolt.Tx, t Transition) error {
+	if _, err := appendTransition(tx, t); err != nil {
+		return err
+	}
+
+	current := tx.Bucket(bucketCurrent)
+	stateKey := containerKey(t.Namespace, t.Kind, t.Workload, t.Container)
+	if t.Type == events.ImageRemoved {
+		return current.Delete(stateKey)
+	}
+	return putState(current, stateKey, stateAfter(t))
+}
+
+// Compact folds the transitions older than the cutoff into the baseline and deletes them
+func (s *Store) Compact(cutoff time.Time) (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(bucketMeta)
+		if raw := meta.Get(keyBaselineTime); raw != nil && !cutoff.After(decodeTime(raw)) {
+			return nil // Already compacted up to this point
+		}
+
+		baseline := tx.Bucket(bucketBaseline)
+		timeline := tx.Bucket(bucketTimeline)
+		end := transitionKey(cutoff, ^uint64(0))
+
+		c := tx.Bucket(bucketTransitions).Cursor()
+		for k, v := c.First(); k != nil && bytes.Compare(k, end) <= 0; k, v = c.First() {
+			var t Transition
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+
+			stateKey := containerKey(t.Namespace, t.Kind, t.Workload, t.Container)
+			if t.Type == events.ImageRemoved {
+				if err := baseline.Delete(stateKey); err != nil {
+					return err
+				}
+			} else if err := putState(baseline, stateKey, stateAfter(t)); err != nil {
+				return err
+			}
+
+			if err := timeline.Delete(timelineKey(t.Namespace, t.Kind, t.Workload, k)); err != nil {
+				return err
+			}
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			removed++
+		}
+
+		return meta.Put(keyBaselineTime, encodeTime(cutoff))
+	})
+
+	return removed, err
+}
+
+// RunCompaction compacts the history every interval (which must be positive), keeping the given retention. It never returns.
+func (s *Store) RunCompaction(retention, interval time.Duration) {
+	for {
+		cutoff := time.Now().Add(-retention)
+		removed, err := s.Compact(cutoff)
+		if err != nil {
+			slog.Error("History compaction failed", slog.Any("error", err))
+		} else {
+			slog.Debug("History compacted", slog.Time("cutoff", cutoff), slog.Int("transitions_folded", removed))
+		}
+		time.Sleep(interval)
+	}
+}
+
+// appendTransition stores and indexes a transition without touching the current state. It returns the transition key.
+func appendTransition(tx *bolt.Tx, t Transition) ([]byte, error) {
+	transitions := tx.Bucket(bucketTransitions)
+	seq, err := transitions.NextSequence()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	key := transitionKey(t.Time, seq)
+	if err := transitions.Put(key, value); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Bucket(bucketTimeline).Put(timelineKey(t.Namespace, t.Kind, t.Workload, key), nil); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// stateAfter returns the container state resulting from an added/changed transition
+func stateAfter(t Transition) containerState {
+	return containerState{
+		Namespace:       t.Namespace,
+		Kind:            t.Kind,
+		Workload:        t.Workload,
+		Container:       t.Container,
+		ResourceVersion: t.ResourceVersion,
+		Image:           *t.NewImage,
+		ExtraLabels:     t.ExtraLabels,
+		Since:           t.Time,
+	}
+}
+
+func putState(bucket *bolt.Bucket, key []byte, state containerState) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, value)
+}
+
+// containerKey builds "<namespace>/<kind>/<workload>\x00<container>"
+func containerKey(namespace, kind, workload, container string) []byte {
+	return []byte(inventory.WorkloadKey(namespace, kind, workload) + "\x00" + container)
+}
+
+// timelinePrefix builds "<namespace>/<kind>/<workload>\x00", the prefix of the timeline index of a workload
+func timelinePrefix(namespace, kind, workload string) []byte {
+	return append([]byte(inventory.WorkloadKey(namespace, kind, workload)), 0)
+}
+
+// timelineKey builds the timeline index entry of a transition
+func timelineKey(namespace, kind, workload string, transition []byte) []byte {
+	return append(timelinePrefix(namespace, kind, workload), transition...)
+}
+
+// transitionKey is the big-endian time followed by a sequence number, so keys sort chronologically
+func transitionKey(t time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+func encodeTime(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+	return b
+}
+
+func decodeTime(b []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b))).UTC()
+}
@@ -0,0 +1,80 @@
+/*
+Image reference parsing shared by the controller, the API and the CLI.
+
+SCOPE:
+- Split a container image string into registry, repository, tag and digest
+- Keep the same defaults Sentinel has always used for its metric labels (docker.io, latest)
+*/
+
+package inventory
+
+import "strings"
+
+// Image is a container image reference broken down into its components
+type Image struct {
+	Reference  string `json:"reference"`        // Full image string as written in the pod template
+	Registry   string `json:"registry"`         // e.g. "ghcr.io"
+	Repository string `json:"repository"`       // e.g. "myorg/myapp"
+	Tag        string `json:"tag,omitempty"`    // e.g. "v1.2.3"
+	Digest     string `json:"digest,omitempty"` // e.g. "sha256:..." when the image is pinned
+}
+
+// RepositoryKey returns "registry/repository", the key used to index images
+func (i Image) RepositoryKey() string {
+	return i.Registry + "/" + i.Repository
+}
+
+// TagDefaulted reports whether the reference has no tag and ParseImage defaulted it to "latest"
+func (i Image) TagDefaulted() bool {
+	name, _, _ := strings.Cut(i.Reference, "@")
+	return i.Tag != "" && strings.LastIndex(name, ":") <= strings.LastIndex(name, "/")
+}
+
+/*
+ParseImage splits a container image string into its components.
+Examples:
+
+	"ghcr.io/myorg/myapp:v1.2.3"        -> ghcr.io, myorg/myapp, v1.2.3
+	"nginx"                             -> docker.io, nginx, latest
+	"localhost:5000/app:dev"            -> localhost:5000, app, dev
+	"nginx:1.29@sha256:abc..."          -> docker.io, nginx, 1.29, sha256:abc...
+	"nginx@sha256:abc..."               -> docker.io, nginx, "", sha256:abc...
+
+A missing tag defaults to "latest", unless the image is pinned by digest.
+*/
+func ParseImage(image string) Image {
+	ref := Image{Reference: image}
+
+	// Digest comes after '@' and is always the last component
+	name := image
+	if at := strings.Index(name, "@"); at >= 0 {
+		ref.Digest = name[at+1:]
+		name = name[:at]
+	}
+
+	// The tag separator is the last ':' after the last '/' (a ':' before it belongs to a registry port)
+	if colon := strings.LastIndex(name, ":"); colon > strings.LastIndex(name, "/") {
+		ref.Tag = name[colon+1:]
+		name = name[:colon]
+	} else if ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	// Split image path into registry and repository
+	// If there's no '/', assume it's docker.io (Docker Hub)
+	pathParts := strings.SplitN(name, "/", 2)
+	if len(pathParts) == 1 {
+		ref.Registry = "docker.io"
+		ref.Repository = pathParts[0]
+	} else if strings.Contains(pathParts[0], ".") || strings.Contains(pathParts[0], ":") || pathParts[0] == "localhost" {
+		// First part looks like a registry (has '.' or ':' or is 'localhost')
+		ref.Registry = pathParts[0]
+		ref.Repository = pathParts[1]
+	} else {
+		// First part is namespace, not registry (e.g., "library/nginx")
+		ref.Registry = "docker.io"
+		ref.Repository = name
+	}
+
+	return ref
+}
@@ -0,0 +1,211 @@
+/*
+Reverse lookup: "which workloads run a given image?"
+
+A Query can filter on registry, repository, tag and digest (all supporting '*' and '?' globs)
+and on a semver range applied to the tag (e.g. ">=12.1, <=12.4").
+*/
+
+package inventory
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Query describes the images we are looking for. Empty fields match everything.
+type Query struct {
+	Registry   string `json:"registry,omitempty"`   // e.g. "ghcr.io" or "*.dkr.ecr.*.amazonaws.com"
+	Repository string `json:"repository,omitempty"` // e.g. "base/debian" or "myorg/*"
+	Tag        string `json:"tag,omitempty"`        // e.g. "12.*"
+	Digest     string `json:"digest,omitempty"`     // e.g. "sha256:4f2a..."
+	TagRange   string `json:"tagRange,omitempty"`   // semver constraint, e.g. ">=12.1, <=12.4"
+}
+
+// Match is a container whose image satisfies a Query
+type Match struct {
+	Namespace   string            `json:"namespace"`
+	Kind        string            `json:"kind"`
+	Workload    string            `json:"workload"`
+	Container   string            `json:"container"`
+	Image       Image             `json:"image"`
+	ExtraLabels map[string]string `json:"extraLabels,omitempty"`
+	Source      *ImageSource      `json:"source,omitempty"`
+}
+
+// compiledQuery is a Query with its globs and semver range parsed once
+type compiledQuery struct {
+	registry, repository, tag, digest *regexp.Regexp
+	tagRange                          *semver.Constraints
+}
+
+// WhoUses returns every container whose image matches the query, sorted by workload
+func (s *Store) WhoUses(q Query) ([]Match, error) {
+	if q.Registry == "" && q.Repository == "" && q.Tag == "" && q.Digest == "" && q.TagRange == "" {
+		return nil, fmt.Errorf("at least one of registry, repository, tag, digest or tagRange is required")
+	}
+
+	cq, err := compileQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := []Match{}
+	for _, key := range s.candidates(q, cq) {
+		w := s.withSources(s.workloads[key])
+		for _, c := range w.Containers {
+			if !cq.matches(c.Image) {
+				continue
+			}
+			matches = append(matches, Match{
+				Namespace:   w.Namespace,
+				Kind:        w.Kind,
+				Workload:    w.Name,
+				Container:   c.Name,
+				Image:       c.Image,
+				ExtraLabels: w.ExtraLabels,
+				Source:      c.Source,
+			})
+		}
+	}
+
+	return matches, nil
+}
+
+/*
+candidates uses the indexes to narrow down the workloads worth inspecting:
+  - an exact digest hits the digest index directly
+  - an exact registry + repository hits the repository index directly
+  - otherwise, only the (much smaller) set of distinct repositories is scanned
+*/
+func (s *Store) candidates(q Query, cq *compiledQuery) []string {
+	keys := make(map[string]struct{})
+
+	switch {
+	case q.Digest != "" && !isGlob(q.Digest):
+		for key := range s.byDigest[q.Digest] {
+			keys[key] = struct{}{}
+		}
+	case q.Registry != "" && q.Repository != "" && !isGlob(q.Registry) && !isGlob(q.Repository):
+		for key := range s.byRepository[q.Registry+"/"+q.Repository] {
+			keys[key] = struct{}{}
+		}
+	default:
+		for repoKey, set := range s.byRepository {
+			registry, repository, _ := strings.Cut(repoKey, "/")
+			if (cq.registry != nil && !cq.registry.MatchString(registry)) || (cq.repository != nil && !cq.repository.MatchString(repository)) {
+				continue
+			}
+			for key := range set {
+				keys[key] = struct{}{}
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+func compileQuery(q Query) (*compiledQuery, error) {
+	cq := &compiledQuery{
+		registry:   globToRegexp(q.Registry),
+		repository: globToRegexp(q.Repository),
+		tag:        globToRegexp(q.Tag),
+		digest:     globToRegexp(q.Digest),
+	}
+
+	if q.TagRange != "" {
+		constraint, err := semver.NewConstraint(q.TagRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tagRange %q: %w", q.TagRange, err)
+		}
+		cq.tagRange = constraint
+	}
+
+	return cq, nil
+}
+
+func (cq *compiledQuery) matches(img Image) bool {
+	if cq.registry != nil && !cq.registry.MatchString(img.Registry) {
+		return false
+	}
+	if cq.repository != nil && !cq.repository.MatchString(img.Repository) {
+		return false
+	}
+	if cq.tag != nil && !cq.tag.MatchString(img.Tag) {
+		return false
+	}
+	if cq.digest != nil && !cq.digest.MatchString(img.Digest) {
+		return false
+	}
+	if cq.tagRange != nil {
+		version, ok := TagVersion(img.Tag)
+		if !ok || !cq.tagRange.Check(version) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+TagVersion interprets an image tag as a semantic version.
+Image tags often carry a variant suffix ("1.25.3-alpine", "12.4-slim") which semver would read
+as a pre-release; the suffix is dropped so that such tags still satisfy plain ranges.
+*/
+func TagVersion(tag string) (*semver.Version, bool) {
+	version, err := semver.NewVersion(tag)
+	if err != nil {
+		return nil, false
+	}
+	if version.Prerelease() != "" {
+		withoutSuffix, err := version.SetPrerelease("")
+		if err == nil {
+			version = &withoutSuffix
+		}
+	}
+	return version, true
+}
+
+// globToRegexp turns a '*'/'?' glob into an anchored regexp. An empty pattern returns nil (match all).
+func globToRegexp(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String())
+}
+
+// MatchGlob reports whether value matches a '*'/'?' glob. An empty pattern matches everything.
+func MatchGlob(pattern, value string) bool {
+	re := globToRegexp(pattern)
+	return re == nil || re.MatchString(value)
+}
+
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?")
+}
@@ -0,0 +1,51 @@
+/*
+Source code provenance of the running images, from the OCI annotations.
+
+SCOPE:
+- Read org.opencontainers.image.source, .revision and .version from annotations or image labels
+- Prefer what the image says about itself, and fall back to the annotations of the workload for its main container
+  when the image carries none
+*/
+
+package inventory
+
+// The OCI annotation keys (https://github.com/opencontainers/image-spec/blob/main/annotations.md)
+const (
+	SourceAnnotation   = "org.opencontainers.image.source"   // URL of the source repository
+	RevisionAnnotation = "org.opencontainers.image.revision" // Commit the image was built from
+	VersionAnnotation  = "org.opencontainers.image.version"  // Version of the packaged software
+)
+
+// ImageSource links an image to the commit it was built from
+type ImageSource struct {
+	Source   string `json:"source,omitempty"`   // e.g. "https://github.com/myorg/myapp"
+	Revision string `json:"revision,omitempty"` // e.g. "3f2a9c1..."
+	Version  string `json:"version,omitempty"`  // e.g. "1.2.3"
+}
+
+// IsZero reports whether nothing is known about the source
+func (s ImageSource) IsZero() bool {
+	return s == ImageSource{}
+}
+
+// SourceFromAnnotations reads the OCI source annotations, the first map holding a key winning
+func SourceFromAnnotations(annotations ...map[string]string) ImageSource {
+	var source ImageSource
+	for i := len(annotations) - 1; i >= 0; i-- {
+		source = ImageSource{
+			Source:   firstNonEmpty(annotations[i][SourceAnnotation], source.Source),
+			Revision: firstNonEmpty(annotations[i][RevisionAnnotation], source.Revision),
+			Version:  firstNonEmpty(annotations[i][VersionAnnotation], source.Version),
+		}
+	}
+	return source
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,228 @@
+/*
+The inventory is Sentinel's in-memory view of the workloads it observes.
+
+SCOPE:
+- Keep the latest known containers/images of every tracked workload
+- Maintain reverse indexes (repository -> workloads, digest -> workloads) so that
+  questions like "who runs this image?" don't require scanning the whole cluster
+- Attach the source read from the registries to the containers running an image (see SetImageSource)
+*/
+
+package inventory
+
+import (
+	"sort"
+	"sync"
+)
+
+// Container is a single container of a workload and the image it runs
+type Container struct {
+	Name   string       `json:"name"`
+	Image  Image        `json:"image"`
+	Source *ImageSource `json:"source,omitempty"` // Source repository and commit, from the image or the workload annotations
+}
+
+// Workload is the inventory record of a Deployment, StatefulSet, DaemonSet, ...
+type Workload struct {
+	Namespace        string            `json:"namespace"`
+	Kind             string            `json:"kind"`
+	Name             string            `json:"name"`
+	ResourceVersion  string            `json:"resourceVersion,omitempty"`
+	ExtraLabels      map[string]string `json:"extraLabels,omitempty"` // timeseriesLabelName -> value, from the extraLabels config
+	Containers       []Container       `json:"containers"`
+	ImagePullSecrets []string          `json:"imagePullSecrets,omitempty"` // Secret names of the pod template, to authenticate to the registries
+}
+
+// Key uniquely identifies a workload in the inventory
+func (w Workload) Key() string {
+	return WorkloadKey(w.Namespace, w.Kind, w.Name)
+}
+
+// WorkloadKey builds the inventory key of a workload: "namespace/kind/name"
+func WorkloadKey(namespace, kind, name string) string {
+	return namespace + "/" + kind + "/" + name
+}
+
+// Store is a concurrency-safe, indexed collection of workloads
+type Store struct {
+	mu           sync.RWMutex
+	workloads    map[string]Workload            // workload key -> workload
+	byRepository map[string]map[string]struct{} // "registry/repository" -> set of workload keys
+	byDigest     map[string]map[string]struct{} // digest -> set of workload keys
+	byReference  map[string]map[string]struct{} // image reference -> set of workload keys
+	sources      map[string]ImageSource         // image reference -> source read from its registry
+}
+
+// NewStore returns an empty inventory
+func NewStore() *Store {
+	return &Store{
+		workloads:    make(map[string]Workload),
+		byRepository: make(map[string]map[string]struct{}),
+		byDigest:     make(map[string]map[string]struct{}),
+		byReference:  make(map[string]map[string]struct{}),
+		sources:      make(map[string]ImageSource),
+	}
+}
+
+// Upsert adds or replaces a workload. It returns the previous record, if any.
+func (s *Store) Upsert(w Workload) (Workload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := w.Key()
+	previous, existed := s.workloads[key]
+	if existed {
+		s.unindex(key, previous)
+	}
+	s.workloads[key] = w
+	s.index(key, w)
+	if existed {
+		s.pruneSources(previous)
+	}
+
+	return previous, existed
+}
+
+// Delete removes a workload. It returns the removed record, if any.
+func (s *Store) Delete(namespace, kind, name string) (Workload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := WorkloadKey(namespace, kind, name)
+	previous, existed := s.workloads[key]
+	if existed {
+		s.unindex(key, previous)
+		delete(s.workloads, key)
+		s.pruneSources(previous)
+	}
+
+	return previous, existed
+}
+
+// Get returns a single workload
+func (s *Store) Get(namespace, kind, name string) (Workload, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w, ok := s.workloads[WorkloadKey(namespace, kind, name)]
+	return s.withSources(w), ok
+}
+
+// List returns all workloads, sorted by namespace, kind and name
+func (s *Store) List() []Workload {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	workloads := make([]Workload, 0, len(s.workloads))
+	for _, w := range s.workloads {
+		workloads = append(workloads, s.withSources(w))
+	}
+	sortWorkloads(workloads)
+
+	return workloads
+}
+
+/*
+SetImageSource records the source read from the registry of an image. It takes precedence over the workload annotations
+of every container running the image as a whole (they are only used for images without any), and is forgotten once no workload runs the image anymore.
+*/
+func (s *Store) SetImageSource(reference string, source ImageSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, running := s.byReference[reference]; !running || source.IsZero() {
+		delete(s.sources, reference)
+		return
+	}
+	s.sources[reference] = source
+}
+
+// withSources returns a workload whose containers carry the source read from the registries. Must be called with the lock held.
+func (s *Store) withSources(w Workload) Workload {
+	containers := w.Containers
+	copied := false
+	for i, c := range w.Containers {
+		source, ok := s.sources[c.Image.Reference]
+		if !ok {
+			continue
+		}
+		if !copied {
+			containers = append([]Container(nil), w.Containers...) // Records are shared: never modify them
+			copied = true
+		}
+		containers[i].Source = &source
+	}
+	w.Containers = containers
+	return w
+}
+
+// ListNamespace returns the workloads of a namespace, sorted by kind and name
+func (s *Store) ListNamespace(namespace string) []Workload {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	workloads := []Workload{}
+	for _, w := range s.workloads {
+		if w.Namespace == namespace {
+			workloads = append(workloads, s.withSources(w))
+		}
+	}
+	sortWorkloads(workloads)
+
+	return workloads
+}
+
+func (s *Store) index(key string, w Workload) {
+	for _, c := range w.Containers {
+		addToSet(s.byRepository, c.Image.RepositoryKey(), key)
+		addToSet(s.byReference, c.Image.Reference, key)
+		if c.Image.Digest != "" {
+			addToSet(s.byDigest, c.Image.Digest, key)
+		}
+	}
+}
+
+func (s *Store) unindex(key string, w Workload) {
+	for _, c := range w.Containers {
+		removeFromSet(s.byRepository, c.Image.RepositoryKey(), key)
+		removeFromSet(s.byReference, c.Image.Reference, key)
+		if c.Image.Digest != "" {
+			removeFromSet(s.byDigest, c.Image.Digest, key)
+		}
+	}
+}
+
+// pruneSources forgets the sources of the images of a workload that no workload runs anymore
+func (s *Store) pruneSources(w Workload) {
+	for _, c := range w.Containers {
+		if _, running := s.byReference[c.Image.Reference]; !running {
+			delete(s.sources, c.Image.Reference)
+		}
+	}
+}
+
+func addToSet(index map[string]map[string]struct{}, indexKey, workloadKey string) {
+	set, ok := index[indexKey]
+	if !ok {
+		set = make(map[string]struct{})
+		index[indexKey] = set
+	}
+	set[workloadKey] = struct{}{}
+}
+
+func removeFromSet(index map[string]map[string]struct{}, indexKey, workloadKey string) {
+	set, ok := index[indexKey]
+	if !ok {
+		return
+	}
+	delete(set, workloadKey)
+	if len(set) == 0 {
+		delete(index, indexKey)
+	}
+}
+
+func sortWorkloads(workloads []Workload) {
+	sort.Slice(workloads, func(i, j int) bool {
+		return workloads[i].Key() < workloads[j].Key()
+	})
+}
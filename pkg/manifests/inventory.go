@@ -0,0 +1,149 @@
+package manifests
+
+import (
+	"log/slog"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultContainerAnnotation names the main container of a pod, as kubectl does
+const DefaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+
+// Inventory converts workloads read from manifests into inventory records, exactly as the controller
+// does for live workloads. Records are returned in the order of the manifests.
+func Inventory(workloads []Workload, extraLabels []shared.ExtraLabel) []inventory.Workload {
+	records := make([]inventory.Workload, 0, len(workloads))
+	for _, w := range workloads {
+		records = append(records, w.Record(extraLabels))
+	}
+	return records
+}
+
+// Record converts a workload read from a manifest into an inventory record
+func (w Workload) Record(extraLabels []shared.ExtraLabel) inventory.Workload {
+	return InventoryRecord(w.Kind, w.Namespace, w.Object, w.Containers, extraLabels, ExtraLabelValues(w.Object, extraLabels))
+}
+
+/*
+InventoryRecord converts a workload, live or read from a manifest, and its containers into an inventory record.
+extraLabelValues must come from ExtraLabelValues() so that it is aligned with extraLabels.
+*/
+func InventoryRecord(kind, namespace string, workload metav1.Object, containers []corev1.Container, extraLabels []shared.ExtraLabel, extraLabelValues []string) inventory.Workload {
+	record := inventory.Workload{
+		Namespace:       namespace,
+		Kind:            kind,
+		Name:            workload.GetName(),
+		ResourceVersion: workload.GetResourceVersion(),
+		ExtraLabels:     make(map[string]string, len(extraLabels)),
+		Containers:      make([]inventory.Container, 0, len(containers)),
+	}
+
+	for i, el := range extraLabels {
+		record.ExtraLabels[el.TimeseriesLabelName] = extraLabelValues[i]
+	}
+
+	// The OCI source annotations of the workload describe its main container only, not its sidecars: they are the
+	// fallback of the image of that container (see inventory.Store.SetImageSource)
+	var source *inventory.ImageSource
+	if s := inventory.SourceFromAnnotations(workload.GetAnnotations(), workloadTemplateAnnotations(workload)); !s.IsZero() {
+		source = &s
+	}
+	main := mainContainer(workload, containers)
+
+	for _, container := range containers {
+		c := inventory.Container{
+			Name:  container.Name,
+			Image: inventory.ParseImage(container.Image),
+		}
+		if container.Name == main {
+			c.Source = source
+		}
+		record.Containers = append(record.Containers, c)
+	}
+
+	if podSpec := workloadPodSpec(workload); podSpec != nil {
+		for _, secret := range podSpec.ImagePullSecrets {
+			record.ImagePullSecrets = append(record.ImagePullSecrets, secret.Name)
+		}
+	}
+
+	return record
+}
+
+// workloadPodSpec returns the pod template spec of a workload, or nil for kinds Sentinel doesn't track
+func workloadPodSpec(workload metav1.Object) *corev1.PodSpec {
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		return &w.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		return &w.Spec.Template.Spec
+	case *appsv1.DaemonSet:
+		return &w.Spec.Template.Spec
+	}
+	return nil
+}
+
+// workloadTemplateAnnotations returns the annotations of the pod template of a workload
+func workloadTemplateAnnotations(workload metav1.Object) map[string]string {
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		return w.Spec.Template.Annotations
+	case *appsv1.StatefulSet:
+		return w.Spec.Template.Annotations
+	case *appsv1.DaemonSet:
+		return w.Spec.Template.Annotations
+	}
+	return nil
+}
+
+/*
+mainContainer returns the name of the container the workload annotations describe: the one named by the
+kubectl.kubernetes.io/default-container annotation of the pod template, or the only container of the workload.
+Returns "" when the workload runs several containers and doesn't name one.
+*/
+func mainContainer(workload metav1.Object, containers []corev1.Container) string {
+	if name := workloadTemplateAnnotations(workload)[DefaultContainerAnnotation]; name != "" {
+		return name
+	}
+	if len(containers) == 1 {
+		return containers[0].Name
+	}
+	return ""
+}
+
+/*
+ExtraLabelValues extracts label/annotation values from a Kubernetes object based on configuration
+- Returns a slice of values in the same order as the extraLabels config
+- If a label/annotation is not found, an empty string is used
+*/
+func ExtraLabelValues(obj metav1.Object, extraLabels []shared.ExtraLabel) []string {
+	values := make([]string, len(extraLabels))
+
+	for i, extractor := range extraLabels {
+		var value string
+
+		switch extractor.Type {
+		case "annotation":
+			if obj.GetAnnotations() != nil {
+				value = obj.GetAnnotations()[extractor.Key]
+			}
+		case "label":
+			if obj.GetLabels() != nil {
+				value = obj.GetLabels()[extractor.Key]
+			}
+		default:
+			slog.Warn("Unknown extraLabel type, skipping",
+				slog.String("type", extractor.Type),
+				slog.String("key", extractor.Key))
+		}
+
+		// Use empty string if not found (Prometheus requires all series to have same label set)
+		values[i] = value
+	}
+
+	return values
+}
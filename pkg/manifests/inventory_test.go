@@ -0,0 +1,44 @@
+package manifests
+
+import (
+	"testing"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInventoryRecordSource(t *testing.T) {
+	annotations := map[string]string{inventory.SourceAnnotation: "https://github.com/acme/api"}
+	app := corev1.Container{Name: "api", Image: "ghcr.io/acme/api:1.4.2"}
+	sidecar := corev1.Container{Name: "proxy", Image: "envoyproxy/envoy:v1.31.0"}
+
+	tests := []struct {
+		name                string
+		templateAnnotations map[string]string
+		containers          []corev1.Container
+		want                map[string]bool // container -> carries the workload source
+	}{
+		{"single container", nil, []corev1.Container{app}, map[string]bool{"api": true}},
+		{"sidecar", nil, []corev1.Container{app, sidecar}, map[string]bool{"api": false, "proxy": false}},
+		{"default container", map[string]string{DefaultContainerAnnotation: "api"}, []corev1.Container{app, sidecar}, map[string]bool{"api": true, "proxy": false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deploy := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod", Annotations: annotations},
+				Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Annotations: tt.templateAnnotations},
+					Spec:       corev1.PodSpec{Containers: tt.containers},
+				}},
+			}
+			record := InventoryRecord("Deployment", "prod", deploy, tt.containers, nil, nil)
+			for _, c := range record.Containers {
+				if got := c.Source != nil; got != tt.want[c.Name] {
+					t.Fatalf("container %s: got source %+v, want source=%t", c.Name, c.Source, tt.want[c.Name])
+				}
+			}
+		})
+	}
+}
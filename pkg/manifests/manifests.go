@@ -0,0 +1,206 @@
+/*
+Kubernetes manifests parsing, for checks that run without a cluster.
+
+SCOPE:
+- Read multi-document YAML or JSON: plain manifests, `helm template` output, `kustomize build` output, `kubectl get -o yaml` lists
+- From files, directories (walked recursively) or stdin
+- Keep the workload kinds Sentinel supports (Deployments, StatefulSets, DaemonSets) and the labels of Namespace objects
+- Convert workloads into inventory records (inventory.go), the same way for manifests and for the live workloads of the controller
+*/
+
+package manifests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// Workload is a supported workload found in a manifest
+type Workload struct {
+	Kind       string             // Deployment, StatefulSet or DaemonSet
+	Namespace  string             // metadata.namespace, or the default namespace given to the parser
+	Object     metav1.Object      // The decoded object, for labels and annotations
+	Containers []corev1.Container // Containers of the pod template
+	Source     string             // "<file>#<document index>"
+}
+
+// Name returns the name of the workload
+func (w Workload) Name() string {
+	return w.Object.GetName()
+}
+
+// Set is everything relevant found in a group of manifests
+type Set struct {
+	Workloads       []Workload
+	NamespaceLabels map[string]map[string]string // Namespace name -> labels, from Namespace objects
+}
+
+// document is the part of any Kubernetes object needed to route it
+type document struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Items      []json.RawMessage `json:"items"`
+}
+
+/*
+Load parses a file, a directory (*.yaml, *.yml and *.json files, recursively) or stdin ("-").
+Workloads without metadata.namespace are placed in defaultNamespace.
+*/
+func Load(path, defaultNamespace string) (Set, error) {
+	set := Set{NamespaceLabels: make(map[string]map[string]string)}
+
+	if path == "-" {
+		err := set.parse(os.Stdin, "stdin", defaultNamespace)
+		return set, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return set, err
+	}
+	if !info.IsDir() {
+		err := set.parseFile(path, defaultNamespace)
+		return set, err
+	}
+
+	// WalkDir visits files in lexical order, so the result is stable
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if file != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir // .git and friends
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml", ".json":
+			return set.parseFile(file, defaultNamespace)
+		}
+		return nil
+	})
+	return set, err
+}
+
+func (s *Set) parseFile(path, defaultNamespace string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.parse(f, path, defaultNamespace)
+}
+
+// parse reads every document of a YAML/JSON stream
+func (s *Set) parse(r io.Reader, source, defaultNamespace string) error {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+	for index := 0; ; index++ {
+		raw, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
+		}
+
+		data, err := yaml.YAMLToJSON(raw)
+		if err != nil {
+			return fmt.Errorf("%s#%d: %w", source, index, err)
+		}
+		if err := s.add(data, fmt.Sprintf("%s#%d", source, index), defaultNamespace); err != nil {
+			return err
+		}
+	}
+}
+
+// add decodes one object (JSON), recursing into lists
+func (s *Set) add(data []byte, source, defaultNamespace string) error {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+
+	switch {
+	case strings.HasSuffix(doc.Kind, "List") && doc.APIVersion == "v1":
+		for i, item := range doc.Items {
+			if err := s.add(item, fmt.Sprintf("%s[%d]", source, i), defaultNamespace); err != nil {
+				return err
+			}
+		}
+		return nil
+	case doc.Kind == "Namespace" && doc.APIVersion == "v1":
+		var ns corev1.Namespace
+		if err := json.Unmarshal(data, &ns); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		s.NamespaceLabels[ns.Name] = ns.Labels
+		return nil
+	}
+
+	w, tracked, err := DecodeWorkload(data, defaultNamespace)
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	if !tracked {
+		return nil // Not a kind Sentinel tracks
+	}
+	w.Source = source
+	s.Workloads = append(s.Workloads, w)
+	return nil
+}
+
+// DecodeWorkload decodes one JSON object of a kind Sentinel tracks. tracked is false for other kinds.
+func DecodeWorkload(data []byte, defaultNamespace string) (w Workload, tracked bool, err error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Workload{}, false, err
+	}
+
+	var object metav1.Object
+	var containers []corev1.Container
+	switch {
+	case doc.Kind == "Deployment" && doc.APIVersion == "apps/v1":
+		var deploy appsv1.Deployment
+		if err := json.Unmarshal(data, &deploy); err != nil {
+			return Workload{}, false, err
+		}
+		object, containers = &deploy, deploy.Spec.Template.Spec.Containers
+	case doc.Kind == "StatefulSet" && doc.APIVersion == "apps/v1":
+		var statefulset appsv1.StatefulSet
+		if err := json.Unmarshal(data, &statefulset); err != nil {
+			return Workload{}, false, err
+		}
+		object, containers = &statefulset, statefulset.Spec.Template.Spec.Containers
+	case doc.Kind == "DaemonSet" && doc.APIVersion == "apps/v1":
+		var daemonset appsv1.DaemonSet
+		if err := json.Unmarshal(data, &daemonset); err != nil {
+			return Workload{}, false, err
+		}
+		object, containers = &daemonset, daemonset.Spec.Template.Spec.Containers
+	default:
+		return Workload{}, false, nil
+	}
+
+	namespace := object.GetNamespace()
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	return Workload{Kind: doc.Kind, Namespace: namespace, Object: object, Containers: containers}, true, nil
+}
@@ -0,0 +1,208 @@
+package notify
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+	"github.com/MatteoMori/sentinel/pkg/updates"
+)
+
+// Supported chat payload formats
+const (
+	chatFormatSlack = "slack"
+	chatFormatTeams = "teams"
+)
+
+// chatDefaultTypes are the events sent to chat whose filter doesn't list types
+var chatDefaultTypes = []events.Type{events.ImageChanged}
+
+/*
+ChatNotifier posts digests of image changes to Slack or Microsoft Teams incoming webhooks.
+  - Changes are batched per destination: the first change opens a window, and a single digest message
+    with every change received during that window is sent when it closes
+  - Destinations are chosen with an extraLabel (e.g. "owner"): each route maps label values to a webhook URL,
+    changes that match no route go to the default URL (or are skipped if there isn't one)
+  - Downgrades and ":latest" tags are highlighted
+*/
+type ChatNotifier struct {
+	cfg         shared.ChatNotifierConfig
+	clusterName string
+
+	mu      sync.Mutex
+	batches map[string]*chatBatch // destination URL -> pending changes
+	queues  map[string]*deliveryQueue
+}
+
+// chatBatch holds the changes waiting for the window of a destination to close
+type chatBatch struct {
+	route  string // Route value, or "default"
+	events []events.Event
+}
+
+// chatChange is an event with the highlights computed for rendering
+type chatChange struct {
+	events.Event
+	Downgrade bool
+	Latest    bool
+}
+
+// NewChatNotifier validates the configuration of a chat notifier
+func NewChatNotifier(cfg shared.ChatNotifierConfig, clusterName string) (*ChatNotifier, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if cfg.Format != chatFormatSlack && cfg.Format != chatFormatTeams {
+		return nil, fmt.Errorf("unknown format %q (supported: %s, %s)", cfg.Format, chatFormatSlack, chatFormatTeams)
+	}
+	if cfg.URL == "" && len(cfg.Routes) == 0 {
+		return nil, fmt.Errorf("url or routes are required")
+	}
+	if len(cfg.Routes) > 0 && cfg.RouteBy == "" {
+		return nil, fmt.Errorf("routeBy is required when routes are configured")
+	}
+	for i, route := range cfg.Routes {
+		if route.Value == "" || route.URL == "" {
+			return nil, fmt.Errorf("route %d: value and url are required", i)
+		}
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.MaxChangesPerMessage <= 0 {
+		cfg.MaxChangesPerMessage = 50
+	}
+
+	n := &ChatNotifier{
+		cfg:         cfg,
+		clusterName: clusterName,
+		batches:     make(map[string]*chatBatch),
+		queues:      make(map[string]*deliveryQueue),
+	}
+
+	// One delivery queue per destination, so that a broken channel doesn't delay the others
+	if cfg.URL != "" {
+		n.queues[cfg.URL] = newDeliveryQueue("chat", cfg.Name+"/default", cfg.URL, cfg.Delivery)
+	}
+	for _, route := range cfg.Routes {
+		if _, exists := n.queues[route.URL]; !exists {
+			n.queues[route.URL] = newDeliveryQueue("chat", cfg.Name+"/"+route.Value, route.URL, cfg.Delivery)
+		}
+	}
+
+	slog.Debug("Chat notifier configured", slog.String("notifier", cfg.Name), slog.String("format", cfg.Format), slog.Duration("window", cfg.Window))
+	return n, nil
+}
+
+// Notify adds the event to the batch of its destination, opening a new window if needed
+func (n *ChatNotifier) Notify(e events.Event) {
+	if !matchesFilter(n.cfg.Filter, chatDefaultTypes, e) {
+		return
+	}
+
+	url, route := n.destination(e)
+	if url == "" {
+		slog.Debug("No chat route for event, skipping", slog.String("notifier", n.cfg.Name), slog.Uint64("event_id", e.ID))
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	batch, open := n.batches[url]
+	if !open {
+		batch = &chatBatch{route: route}
+		n.batches[url] = batch
+		time.AfterFunc(n.cfg.Window, func() { n.flush(url) })
+	}
+	batch.events = append(batch.events, e)
+}
+
+// destination returns the webhook URL (and route name) an event must be sent to
+func (n *ChatNotifier) destination(e events.Event) (url, route string) {
+	if n.cfg.RouteBy != "" {
+		value := e.ExtraLabels[n.cfg.RouteBy]
+		for _, r := range n.cfg.Routes {
+			if inventory.MatchGlob(r.Value, value) {
+				return r.URL, r.Value
+			}
+		}
+	}
+	return n.cfg.URL, "default"
+}
+
+// flush closes the window of a destination and sends its digest
+func (n *ChatNotifier) flush(url string) {
+	n.mu.Lock()
+	batch := n.batches[url]
+	delete(n.batches, url)
+	n.mu.Unlock()
+
+	if batch == nil || len(batch.events) == 0 {
+		return
+	}
+
+	changes := make([]chatChange, 0, len(batch.events))
+	for _, e := range batch.events {
+		changes = append(changes, chatChange{Event: e, Downgrade: isDowngrade(e), Latest: isLatest(e)})
+	}
+
+	// Highlighted changes first, then by workload
+	sort.SliceStable(changes, func(i, j int) bool {
+		hi, hj := changes[i].Downgrade || changes[i].Latest, changes[j].Downgrade || changes[j].Latest
+		if hi != hj {
+			return hi
+		}
+		return inventory.WorkloadKey(changes[i].Namespace, changes[i].Kind, changes[i].Workload) < inventory.WorkloadKey(changes[j].Namespace, changes[j].Kind, changes[j].Workload)
+	})
+
+	digest := chatDigest{
+		ClusterName: n.clusterName,
+		Route:       batch.route,
+		Window:      n.cfg.Window,
+		Changes:     changes,
+		Limit:       n.cfg.MaxChangesPerMessage,
+	}
+
+	var body []byte
+	var err error
+	switch n.cfg.Format {
+	case chatFormatSlack:
+		body, err = renderSlackDigest(digest)
+	case chatFormatTeams:
+		body, err = renderTeamsDigest(digest)
+	}
+	if err != nil {
+		slog.Error("Unable to render chat digest", slog.String("notifier", n.cfg.Name), slog.Any("error", err))
+		return
+	}
+
+	header := http.Header{"Content-Type": {contentTypeJSON}}
+	n.queues[url].enqueue(message{body: body, header: header, eventID: changes[len(changes)-1].ID})
+}
+
+// versions compares the tags of a change the way the updates checker does, variants (-alpine) apart from versions
+var versions = mustMatcher(updates.NewMatcher(updates.DefaultPrereleasePattern, true, true))
+
+func mustMatcher(m *updates.Matcher, err error) *updates.Matcher {
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// isDowngrade reports whether both tags are versions of the same shape and variant, and the new one is lower
+func isDowngrade(e events.Event) bool {
+	return e.OldImage != nil && e.NewImage != nil && versions.Downgrade(e.OldImage.Tag, e.NewImage.Tag)
+}
+
+// isLatest reports whether the new image uses the "latest" tag (explicitly or by default)
+func isLatest(e events.Event) bool {
+	return e.NewImage != nil && e.NewImage.Tag == "latest"
+}
@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// chatDigest is everything needed to render one chat message
+type chatDigest struct {
+	ClusterName string
+	Route       string
+	Window      time.Duration
+	Changes     []chatChange
+	Limit       int // Max changes listed, the rest is summarized
+}
+
+func (d chatDigest) title() string {
+	noun := "change"
+	if len(d.Changes) > 1 {
+		noun = "changes"
+	}
+	title := fmt.Sprintf("Sentinel: %d image %s in %s", len(d.Changes), noun, d.ClusterName)
+	if d.Route != "default" {
+		title += " (" + d.Route + ")"
+	}
+	return title
+}
+
+// highlights summarizes the downgrades and :latest tags of the digest, e.g. "2 downgrades, 1 :latest tag"
+func (d chatDigest) highlights() string {
+	var downgrades, latest int
+	for _, c := range d.Changes {
+		if c.Downgrade {
+			downgrades++
+		}
+		if c.Latest {
+			latest++
+		}
+	}
+
+	var parts []string
+	if downgrades > 0 {
+		parts = append(parts, plural(downgrades, "downgrade"))
+	}
+	if latest > 0 {
+		parts = append(parts, plural(latest, ":latest tag"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// lines renders one line per change (up to the limit), with markdown emphasis provided by the caller
+func (d chatDigest) lines(code, bold func(string) string) []string {
+	var lines []string
+	for i, c := range d.Changes {
+		if i == d.Limit {
+			lines = append(lines, fmt.Sprintf("… and %d more", len(d.Changes)-d.Limit))
+			break
+		}
+
+		var flags []string
+		if c.Downgrade {
+			flags = append(flags, bold("⚠️ downgrade"))
+		}
+		if c.Latest {
+			flags = append(flags, bold("⚠️ :latest"))
+		}
+		prefix := ""
+		if len(flags) > 0 {
+			prefix = strings.Join(flags, " ") + " "
+		}
+
+		oldTag, newTag := "", ""
+		if c.OldImage != nil {
+			oldTag = c.OldImage.Tag
+		}
+		if c.NewImage != nil {
+			newTag = c.NewImage.Tag
+		}
+		lines = append(lines, fmt.Sprintf("%s%s (%s) container %s: %s → %s",
+			prefix, code(c.Namespace+"/"+c.Workload), c.Kind, code(c.Container), code(oldTag), code(newTag)))
+	}
+	return lines
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// renderSlackDigest builds a Slack incoming webhook payload (Block Kit, with a plain text fallback)
+func renderSlackDigest(d chatDigest) ([]byte, error) {
+	code := func(s string) string { return "`" + s + "`" }
+	bold := func(s string) string { return "*" + s + "*" }
+
+	lines := d.lines(code, bold)
+	for i := range lines {
+		lines[i] = "• " + lines[i]
+	}
+
+	blocks := []map[string]any{
+		{"type": "header", "text": map[string]any{"type": "plain_text", "text": d.title()}},
+	}
+	if h := d.highlights(); h != "" {
+		blocks = append(blocks, map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": ":warning: " + bold(h)}})
+	}
+	// Slack limits a section text to 3000 characters: split the list in chunks
+	for _, chunk := range chunkLines(lines, 2900) {
+		blocks = append(blocks, map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": chunk}})
+	}
+	blocks = append(blocks, map[string]any{"type": "context", "elements": []map[string]any{
+		{"type": "mrkdwn", "text": fmt.Sprintf("Changes detected over %s", d.Window)},
+	}})
+
+	return json.Marshal(map[string]any{
+		"text":   d.title(),
+		"blocks": blocks,
+	})
+}
+
+// renderTeamsDigest builds a Microsoft Teams webhook payload (message with an Adaptive Card attachment)
+func renderTeamsDigest(d chatDigest) ([]byte, error) {
+	code := func(s string) string { return "`" + s + "`" }
+	bold := func(s string) string { return "**" + s + "**" }
+
+	body := []map[string]any{
+		{"type": "TextBlock", "text": d.title(), "weight": "Bolder", "size": "Medium", "wrap": true},
+	}
+	if h := d.highlights(); h != "" {
+		body = append(body, map[string]any{"type": "TextBlock", "text": "⚠️ " + h, "color": "Attention", "weight": "Bolder", "wrap": true})
+	}
+	lines := d.lines(code, bold)
+	for i := range lines {
+		lines[i] = "- " + lines[i]
+	}
+	body = append(body,
+		map[string]any{"type": "TextBlock", "text": strings.Join(lines, "\n"), "wrap": true},
+		map[string]any{"type": "TextBlock", "text": fmt.Sprintf("Changes detected over %s", d.Window), "isSubtle": true, "size": "Small", "wrap": true},
+	)
+
+	return json.Marshal(map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	})
+}
+
+// chunkLines joins lines with newlines, starting a new chunk before maxLen is exceeded
+func chunkLines(lines []string, maxLen int) []string {
+	var chunks []string
+	var current strings.Builder
+	for _, line := range lines {
+		if current.Len() > 0 && current.Len()+len(line)+1 > maxLen {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n")
+		}
+		current.WriteString(line)
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+	return chunks
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+)
+
+func TestIsDowngrade(t *testing.T) {
+	tests := []struct {
+		oldTag string
+		newTag string
+		want   bool
+	}{
+		{"1.25.3", "1.25.2", true},
+		{"1.25.3", "1.26.0", false},
+		{"v2.1.0", "v2.0.9", true},
+		{"1.26.0", "1.26.0-rc.1", true},
+		{"1.26.0-rc.1", "1.26.0", false},
+		{"1.26.0-rc.10", "1.26.0-rc.2", true},
+		{"1.25.3", "1.25.3-alpine", false},
+		{"1.25.3-alpine", "1.25.3", false},
+		{"20-slim", "20-alpine", false},
+		{"1.25.4-alpine", "1.25.3-alpine", true},
+		{"1.25.3-alpine", "1.24.0", false}, // Another variant
+		{"1.25.3", "1.25", false},          // Another shape
+		{"latest", "1.25.3", false},
+		{"1.25.3", "latest", false},
+	}
+	for _, tt := range tests {
+		old, updated := inventory.ParseImage("nginx:"+tt.oldTag), inventory.ParseImage("nginx:"+tt.newTag)
+		if got := isDowngrade(events.Event{OldImage: &old, NewImage: &updated}); got != tt.want {
+			t.Errorf("isDowngrade(%s -> %s) = %t, want %t", tt.oldTag, tt.newTag, got, tt.want)
+		}
+	}
+
+	added := inventory.ParseImage("nginx:1.25.3")
+	if isDowngrade(events.Event{NewImage: &added}) {
+		t.Error("an added image isn't a downgrade")
+	}
+}
@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+)
+
+// CloudEvents v1.0 constants (https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/spec.md)
+const (
+	CloudEventsSpecVersion = "1.0"
+	CloudEventsTypePrefix  = "io.sentinel." // io.sentinel.image.added, io.sentinel.image.changed, io.sentinel.image.removed
+
+	contentTypeCloudEvents = "application/cloudevents+json; charset=utf-8"
+	cloudEventsStructured  = "structured"
+	cloudEventsBinary      = "binary"
+)
+
+// cloudEventsDefaultTypes are the events sent to a sink whose filter doesn't list types (image.added is opt-in, like for webhooks)
+var cloudEventsDefaultTypes = []events.Type{events.ImageChanged, events.ImageRemoved}
+
+// instanceID makes CloudEvent IDs unique across Sentinel restarts (event IDs start again from 1)
+var instanceID = newInstanceID()
+
+func newInstanceID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// CloudEvent is a Sentinel event in the CloudEvents v1.0 JSON (structured) format
+type CloudEvent struct {
+	SpecVersion     string       `json:"specversion"`
+	Type            string       `json:"type"`    // e.g. "io.sentinel.image.changed"
+	Source          string       `json:"source"`  // e.g. "/clusters/prod-eu/namespaces/payments/deployments/api"
+	Subject         string       `json:"subject"` // Container name
+	ID              string       `json:"id"`      // "<sentinel instance>-<event id>", unique per source
+	Time            time.Time    `json:"time"`
+	DataContentType string       `json:"datacontenttype"`
+	Data            events.Event `json:"data"` // Same payload as /api/v1/events
+}
+
+// NewCloudEvent wraps a Sentinel event into a CloudEvent
+func NewCloudEvent(e events.Event, clusterName string) CloudEvent {
+	return CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		Type:            CloudEventsTypePrefix + string(e.Type),
+		Source:          CloudEventSource(clusterName, e.Namespace, e.Kind, e.Workload),
+		Subject:         e.Container,
+		ID:              fmt.Sprintf("%s-%d", instanceID, e.ID),
+		Time:            e.Timestamp,
+		DataContentType: "application/json",
+		Data:            e,
+	}
+}
+
+// CloudEventSource builds the source URI-reference of a workload: /clusters/<cluster>/namespaces/<ns>/<kind>s/<name>
+func CloudEventSource(clusterName, namespace, kind, workload string) string {
+	return fmt.Sprintf("/clusters/%s/namespaces/%s/%ss/%s", clusterName, namespace, strings.ToLower(kind), workload)
+}
+
+/*
+CloudEventsSink POSTs events to a CloudEvents-aware endpoint (Knative Broker, Argo Events, ...)
+  - structured mode: the whole CloudEvent is the JSON body (Content-Type: application/cloudevents+json)
+  - binary mode: attributes are sent as ce-* headers and the body is the event data
+*/
+type CloudEventsSink struct {
+	cfg         shared.CloudEventsSinkConfig
+	clusterName string
+	queue       *deliveryQueue
+}
+
+// NewCloudEventsSink validates the configuration and starts the delivery queue of a sink
+func NewCloudEventsSink(cfg shared.CloudEventsSinkConfig, clusterName string) (*CloudEventsSink, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = cloudEventsStructured
+	}
+	if cfg.Mode != cloudEventsStructured && cfg.Mode != cloudEventsBinary {
+		return nil, fmt.Errorf("unknown mode %q (supported: %s, %s)", cfg.Mode, cloudEventsStructured, cloudEventsBinary)
+	}
+
+	sink := &CloudEventsSink{
+		cfg:         cfg,
+		clusterName: clusterName,
+		queue:       newDeliveryQueue("cloudevents", cfg.Name, cfg.URL, cfg.Delivery),
+	}
+	slog.Debug("CloudEvents sink configured", slog.String("sink", cfg.Name), slog.String("url", cfg.URL), slog.String("mode", cfg.Mode))
+
+	return sink, nil
+}
+
+// Notify converts the event to a CloudEvent and queues it for delivery, if it passes the sink filter
+func (s *CloudEventsSink) Notify(e events.Event) {
+	if !matchesFilter(s.cfg.Filter, cloudEventsDefaultTypes, e) {
+		return
+	}
+
+	ce := NewCloudEvent(e, s.clusterName)
+	header := http.Header{}
+	for key, value := range s.cfg.Headers {
+		header.Set(key, value)
+	}
+
+	var body []byte
+	var err error
+	if s.cfg.Mode == cloudEventsBinary {
+		header.Set("Content-Type", contentTypeJSON)
+		header.Set("ce-specversion", ce.SpecVersion)
+		header.Set("ce-type", ce.Type)
+		header.Set("ce-source", ce.Source)
+		header.Set("ce-subject", ce.Subject)
+		header.Set("ce-id", ce.ID)
+		header.Set("ce-time", ce.Time.Format(time.RFC3339Nano))
+		body, err = json.Marshal(ce.Data)
+	} else {
+		header.Set("Content-Type", contentTypeCloudEvents)
+		body, err = json.Marshal(ce)
+	}
+	if err != nil {
+		slog.Error("Unable to encode CloudEvent", slog.String("sink", s.cfg.Name), slog.Uint64("event_id", e.ID), slog.Any("error", err))
+		return
+	}
+
+	s.queue.enqueue(message{body: body, header: header, eventID: e.ID})
+}
@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+)
+
+// message is a fully rendered HTTP request waiting to be delivered
+type message struct {
+	body    []byte
+	header  http.Header
+	eventID uint64
+	secret  []byte // Signing key of the webhook, the signature is computed at each attempt (see Sign)
+}
+
+/*
+deliveryQueue POSTs messages to a single endpoint, one at a time and in order.
+- The queue is bounded: when it's full, new messages are dropped (and counted) instead of blocking
+- Network errors, 408, 429 and 5xx responses are retried with exponential backoff; other errors are final
+*/
+type deliveryQueue struct {
+	notifier string // e.g. "webhook", used in metrics
+	endpoint string // Endpoint name, used in logs and metrics
+	url      string
+	cfg      shared.DeliveryConfig
+	retries  int // Resolved cfg.MaxRetries
+	client   *http.Client
+	queue    chan message
+}
+
+// applyDeliveryDefaults fills the delivery settings left empty in the configuration
+func applyDeliveryDefaults(cfg shared.DeliveryConfig) shared.DeliveryConfig {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.MaxRetries == nil {
+		retries := 5
+		cfg.MaxRetries = &retries
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return cfg
+}
+
+// newDeliveryQueue creates the queue and starts its worker
+func newDeliveryQueue(notifier, endpoint, url string, cfg shared.DeliveryConfig) *deliveryQueue {
+	cfg = applyDeliveryDefaults(cfg)
+	q := &deliveryQueue{
+		notifier: notifier,
+		endpoint: endpoint,
+		url:      url,
+		cfg:      cfg,
+		retries:  max(*cfg.MaxRetries, 0),
+		client:   &http.Client{Timeout: cfg.Timeout},
+		queue:    make(chan message, cfg.QueueSize),
+	}
+	go q.run()
+	return q
+}
+
+// enqueue adds a message to the queue without blocking
+func (q *deliveryQueue) enqueue(m message) {
+	select {
+	case q.queue <- m:
+		SentinelPrometheus.SentinelNotificationQueueLength.WithLabelValues(q.notifier, q.endpoint).Set(float64(len(q.queue)))
+	default:
+		slog.Warn("Notification queue full, dropping message",
+			slog.String("notifier", q.notifier),
+			slog.String("endpoint", q.endpoint),
+			slog.Uint64("event_id", m.eventID))
+		SentinelPrometheus.SentinelNotificationDeliveriesTotal.WithLabelValues(q.notifier, q.endpoint, "dropped").Inc()
+	}
+}
+
+func (q *deliveryQueue) run() {
+	for m := range q.queue {
+		SentinelPrometheus.SentinelNotificationQueueLength.WithLabelValues(q.notifier, q.endpoint).Set(float64(len(q.queue)))
+
+		if err := q.deliver(m); err != nil {
+			slog.Error("Notification delivery failed",
+				slog.String("notifier", q.notifier),
+				slog.String("endpoint", q.endpoint),
+				slog.Uint64("event_id", m.eventID),
+				slog.Any("error", err))
+			SentinelPrometheus.SentinelNotificationDeliveriesTotal.WithLabelValues(q.notifier, q.endpoint, "failure").Inc()
+			continue
+		}
+
+		slog.Debug("Notification delivered",
+			slog.String("notifier", q.notifier),
+			slog.String("endpoint", q.endpoint),
+			slog.Uint64("event_id", m.eventID))
+		SentinelPrometheus.SentinelNotificationDeliveriesTotal.WithLabelValues(q.notifier, q.endpoint, "success").Inc()
+	}
+}
+
+// deliver sends a message, retrying transient failures
+func (q *deliveryQueue) deliver(m message) error {
+	backoff := q.cfg.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := q.post(m)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= q.retries {
+			return err
+		}
+
+		// Full jitter on top of the exponential backoff, so that many endpoints don't retry in lockstep
+		wait := backoff/2 + rand.N(backoff/2+1)
+		slog.Debug("Retrying notification delivery",
+			slog.String("notifier", q.notifier),
+			slog.String("endpoint", q.endpoint),
+			slog.Int("attempt", attempt+1),
+			slog.Duration("wait", wait),
+			slog.Any("error", err))
+		SentinelPrometheus.SentinelNotificationRetriesTotal.WithLabelValues(q.notifier, q.endpoint).Inc()
+		time.Sleep(wait)
+
+		backoff = min(backoff*2, q.cfg.MaxBackoff)
+	}
+}
+
+// post performs a single attempt. It reports whether a failure is worth retrying.
+func (q *deliveryQueue) post(m message) (retryable bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, q.url, bytes.NewReader(m.body))
+	if err != nil {
+		return false, err
+	}
+	for key, values := range m.header {
+		req.Header[key] = values
+	}
+	if len(m.secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderTimestamp, timestamp)
+		req.Header.Set(HeaderSignature, Sign(m.secret, timestamp, m.body))
+	}
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // Drain so the connection can be reused
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("endpoint returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+}
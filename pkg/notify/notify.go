@@ -0,0 +1,98 @@
+/*
+Outbound notifications.
+
+SCOPE:
+- Consume the image events published by the controller
+- Hand them to every configured notifier (webhooks, CloudEvents sinks, Slack/Teams, ...), each with its own filter and delivery queue
+- Never block the controller: slow or unreachable endpoints only delay their own queue
+*/
+
+package notify
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+)
+
+// Notifier receives the events published by the controller. Notify must not block.
+type Notifier interface {
+	Notify(e events.Event)
+}
+
+// Init builds the configured notifiers and starts feeding them events from the broker
+func Init(cfg shared.NotificationsConfig, clusterName string, broker *events.Broker) {
+	var notifiers []Notifier
+
+	for _, webhookCfg := range cfg.Webhooks {
+		webhook, err := NewWebhook(webhookCfg)
+		if err != nil {
+			slog.Error("Invalid webhook configuration, skipping it", slog.String("webhook", webhookCfg.Name), slog.Any("error", err))
+			continue
+		}
+		notifiers = append(notifiers, webhook)
+	}
+
+	for _, sinkCfg := range cfg.CloudEvents {
+		sink, err := NewCloudEventsSink(sinkCfg, clusterName)
+		if err != nil {
+			slog.Error("Invalid CloudEvents sink configuration, skipping it", slog.String("sink", sinkCfg.Name), slog.Any("error", err))
+			continue
+		}
+		notifiers = append(notifiers, sink)
+	}
+
+	for _, chatCfg := range cfg.Chat {
+		chat, err := NewChatNotifier(chatCfg, clusterName)
+		if err != nil {
+			slog.Error("Invalid chat notifier configuration, skipping it", slog.String("notifier", chatCfg.Name), slog.Any("error", err))
+			continue
+		}
+		notifiers = append(notifiers, chat)
+	}
+
+	if len(notifiers) == 0 {
+		slog.Debug("No notifiers configured")
+		return
+	}
+
+	slog.Info("Starting notifiers", slog.Int("count", len(notifiers)))
+	broker.Consume("notifications", 256, func(e events.Event) {
+		for _, n := range notifiers {
+			n.Notify(e)
+		}
+	})
+}
+
+// matchesFilter reports whether an event passes a notifier filter
+// defaultTypes applies when the filter doesn't list event types itself
+func matchesFilter(f shared.EventFilter, defaultTypes []events.Type, e events.Event) bool {
+	if len(f.Types) > 0 {
+		if !slices.Contains(f.Types, string(e.Type)) {
+			return false
+		}
+	} else if !slices.Contains(defaultTypes, e.Type) {
+		return false
+	}
+
+	if len(f.Namespaces) > 0 && !slices.ContainsFunc(f.Namespaces, func(pattern string) bool {
+		return inventory.MatchGlob(pattern, e.Namespace)
+	}) {
+		return false
+	}
+
+	if len(f.Kinds) > 0 && !slices.Contains(f.Kinds, e.Kind) {
+		return false
+	}
+
+	for label, pattern := range f.ExtraLabels {
+		if !inventory.MatchGlob(pattern, e.ExtraLabels[label]) {
+			return false
+		}
+	}
+
+	return true
+}
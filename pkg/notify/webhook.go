@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"text/template"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+)
+
+// Headers sent with every webhook request
+const (
+	HeaderEvent     = "X-Sentinel-Event"         // Event type, e.g. "image.changed"
+	HeaderDelivery  = "X-Sentinel-Delivery"      // Event ID
+	HeaderSignature = "X-Sentinel-Signature-256" // "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)), when a secret is configured
+	HeaderTimestamp = "X-Sentinel-Timestamp"     // Unix time of the delivery attempt, covered by the signature
+	contentTypeJSON = "application/json; charset=utf-8"
+)
+
+// webhookDefaultTypes are the events sent to a webhook whose filter doesn't list types
+var webhookDefaultTypes = []events.Type{events.ImageChanged}
+
+/*
+Webhook POSTs image change events to an HTTP endpoint.
+The body is the event as JSON, or the output of the configured Go template, e.g.:
+
+	{"text": "{{.Namespace}}/{{.Workload}}: {{.OldImage.Tag}} -> {{.NewImage.Tag}}"}
+
+Template helpers: json (JSON-encode a value, e.g. {{json .ExtraLabels}}).
+*/
+type Webhook struct {
+	cfg      shared.WebhookConfig
+	secret   []byte
+	template *template.Template
+	queue    *deliveryQueue
+}
+
+// NewWebhook validates the configuration and starts the delivery queue of a webhook
+func NewWebhook(cfg shared.WebhookConfig) (*Webhook, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	wh := &Webhook{cfg: cfg}
+
+	wh.secret = []byte(cfg.Secret)
+	if cfg.SecretEnv != "" {
+		value, ok := os.LookupEnv(cfg.SecretEnv)
+		if !ok {
+			return nil, fmt.Errorf("secret environment variable %q is not set", cfg.SecretEnv)
+		}
+		wh.secret = []byte(value)
+	}
+
+	if cfg.Template != "" {
+		tmpl, err := template.New(cfg.Name).Funcs(templateFuncs).Option("missingkey=zero").Parse(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		wh.template = tmpl
+	}
+
+	wh.queue = newDeliveryQueue("webhook", cfg.Name, cfg.URL, cfg.Delivery)
+	slog.Debug("Webhook notifier configured", slog.String("webhook", cfg.Name), slog.String("url", cfg.URL))
+
+	return wh, nil
+}
+
+// Notify renders the event and queues it for delivery, if it passes the webhook filter
+func (wh *Webhook) Notify(e events.Event) {
+	if !matchesFilter(wh.cfg.Filter, webhookDefaultTypes, e) {
+		return
+	}
+
+	body, err := wh.render(e)
+	if err != nil {
+		slog.Error("Unable to render webhook payload", slog.String("webhook", wh.cfg.Name), slog.Uint64("event_id", e.ID), slog.Any("error", err))
+		return
+	}
+
+	header := http.Header{}
+	for key, value := range wh.cfg.Headers {
+		header.Set(key, value)
+	}
+	header.Set("Content-Type", contentTypeJSON)
+	header.Set(HeaderEvent, string(e.Type))
+	header.Set(HeaderDelivery, strconv.FormatUint(e.ID, 10))
+	wh.queue.enqueue(message{body: body, header: header, eventID: e.ID, secret: wh.secret})
+}
+
+func (wh *Webhook) render(e events.Event) ([]byte, error) {
+	if wh.template == nil {
+		return json.Marshal(e)
+	}
+
+	var buf bytes.Buffer
+	if err := wh.template.Execute(&buf, e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Sign returns the value of the signature header for a body: "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body))
+// Receivers should recompute it over the timestamp header and the raw request body, compare with hmac.Equal,
+// and reject old timestamps so that a captured delivery can't be replayed
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// templateFuncs are the helpers available in payload templates
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
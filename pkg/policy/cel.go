@@ -0,0 +1,126 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+	"github.com/google/cel-go/cel"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+/*
+celEnv declares the variables of custom rules:
+
+	object           the workload (Deployment, StatefulSet, DaemonSet) as in the Kubernetes API: object.metadata.annotations, object.spec...
+	container        the pod template container being checked: container.name, container.image, container.imagePullPolicy...
+	image            the parsed image reference: image.reference, image.registry, image.repository, image.tag, image.digest
+	namespaceLabels  the labels of the workload namespace ("namespace" is a reserved word in CEL; its name is object.metadata.namespace)
+*/
+func celEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("object", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("container", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("image", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("namespaceLabels", cel.MapType(cel.StringType, cel.StringType)),
+	)
+}
+
+/*
+compileCustomRules compiles and type-checks the custom rules of the config. An expression must return a bool:
+true when the container complies, false for a violation. An evaluation error is reported as a violation too.
+*/
+func compileCustomRules(customRules []shared.CustomRule, builtin []rule) ([]rule, error) {
+	if len(customRules) == 0 {
+		return nil, nil
+	}
+	env, err := celEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []rule
+	for i, cr := range customRules {
+		if cr.Name == "" || cr.Expression == "" {
+			return nil, fmt.Errorf("policy: custom rule #%d: name and expression are required", i)
+		}
+		taken := func(r rule) bool { return r.name == cr.Name }
+		if slices.ContainsFunc(builtin, taken) || slices.ContainsFunc(rules, taken) {
+			return nil, fmt.Errorf("policy: custom rule %s: duplicate rule name", cr.Name)
+		}
+
+		severity := SeverityError
+		if cr.Severity != "" {
+			if severity, err = ParseSeverity(cr.Severity); err != nil {
+				return nil, fmt.Errorf("policy: custom rule %s: %w", cr.Name, err)
+			}
+		}
+
+		ast, issues := env.Compile(cr.Expression)
+		if issues.Err() != nil {
+			return nil, fmt.Errorf("policy: custom rule %s: %w", cr.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType {
+			return nil, fmt.Errorf("policy: custom rule %s: expression must return a bool, not %s", cr.Name, ast.OutputType())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("policy: custom rule %s: %w", cr.Name, err)
+		}
+
+		message := cr.Message
+		if message == "" {
+			message = "custom rule " + cr.Name + " failed"
+		}
+		rules = append(rules, rule{
+			name:     cr.Name,
+			severity: severity,
+			check: func(t Target, spec corev1.Container, image inventory.Image) (string, bool) {
+				out, _, err := program.Eval(map[string]any{
+					"object":    t.object,
+					"container": toUnstructured(&spec),
+					"image": map[string]string{
+						"reference":  image.Reference,
+						"registry":   image.Registry,
+						"repository": image.Repository,
+						"tag":        image.Tag,
+						"digest":     image.Digest,
+					},
+					"namespaceLabels": t.NamespaceLabels,
+				})
+				if err != nil {
+					return fmt.Sprintf("%s (evaluation error: %v)", message, err), true
+				}
+				if complies, ok := out.Value().(bool); !ok || !complies {
+					return message, true
+				}
+				return "", false
+			},
+		})
+	}
+	return rules, nil
+}
+
+// workloadObject converts the workload object of a target for the custom rules
+func workloadObject(t Target) map[string]any {
+	object := map[string]any{}
+	if t.Object != nil {
+		object = toUnstructured(t.Object)
+	}
+	// Objects from informers come without their type meta
+	if _, ok := object["kind"]; !ok {
+		object["kind"] = t.Workload.Kind
+	}
+	return object
+}
+
+// toUnstructured converts a Kubernetes object (or any struct with json tags) to a map, as served by the API
+func toUnstructured(obj any) map[string]any {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return map[string]any{}
+	}
+	return u
+}
@@ -0,0 +1,261 @@
+/*
+Image policy checks.
+
+SCOPE:
+- Evaluate rules against the containers of a workload and report violations with a rule name and a severity
+- The same engine serves the offline scan of manifests (sentinel scan) and the live controller
+
+RULES (configured by the policy section of the Sentinel config):
+  - missing-tag:           the image has neither a tag nor a digest, so it silently runs "latest"
+  - forbidden-tag:         the image uses a forbidden tag (forbiddenTags, "latest" by default)
+  - registry-not-allowed:  the image comes from a registry outside allowedRegistries (globs; empty allows any)
+  - missing-digest:        the image isn't pinned by digest, in the namespaces matching requireDigest.namespaceSelector
+  - forbidden-pull-policy: the container uses a forbidden imagePullPolicy (forbiddenPullPolicies, "Never" by default)
+
+Every rule has a default severity, which severities overrides; disabledRules turns rules off.
+Custom rules (customRules) are CEL expressions, compiled and type-checked when the engine is built (see cel.go).
+*/
+
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Severity of a violation
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// rank orders severities, an unknown severity ranks below info
+func (s Severity) rank() int {
+	switch s {
+	case SeverityError:
+		return 3
+	case SeverityWarning:
+		return 2
+	case SeverityInfo:
+		return 1
+	}
+	return 0
+}
+
+// AtLeast reports whether s is as severe as min or more
+func (s Severity) AtLeast(min Severity) bool {
+	return s.rank() >= min.rank()
+}
+
+// ParseSeverity validates a severity name
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(s)
+	if severity.rank() == 0 {
+		return "", fmt.Errorf("unknown severity %q (supported: error, warning, info)", s)
+	}
+	return severity, nil
+}
+
+// Violation is a container breaking a rule
+type Violation struct {
+	Rule      string   `json:"rule"`
+	Severity  Severity `json:"severity"`
+	Namespace string   `json:"namespace"`
+	Kind      string   `json:"kind"`
+	Workload  string   `json:"workload"`
+	Container string   `json:"container"`
+	Image     string   `json:"image"`
+	Message   string   `json:"message"`
+}
+
+// Target is a workload to evaluate
+type Target struct {
+	Workload        inventory.Workload // Parsed images and extra labels
+	Containers      []corev1.Container // Pod template containers, for the fields the inventory doesn't keep (e.g. pull policy)
+	NamespaceLabels map[string]string  // Labels of the workload namespace, when known
+	Object          metav1.Object      // The workload object, for the custom rules
+
+	object map[string]any // Object as a map, converted once per evaluation when there are custom rules
+}
+
+// rule checks a single container; it returns a message when the container breaks the rule
+type rule struct {
+	name     string
+	severity Severity
+	check    func(t Target, spec corev1.Container, image inventory.Image) (string, bool)
+}
+
+// Engine evaluates a set of rules
+type Engine struct {
+	rules  []rule
+	custom bool // Some rules are custom (CEL) rules
+}
+
+// Rule names
+const (
+	RuleMissingTag          = "missing-tag"
+	RuleForbiddenTag        = "forbidden-tag"
+	RuleRegistryNotAllowed  = "registry-not-allowed"
+	RuleMissingDigest       = "missing-digest"
+	RuleForbiddenPullPolicy = "forbidden-pull-policy"
+)
+
+// NewEngine builds an engine with the built-in rules, configured by the policy section of the Sentinel config
+func NewEngine(cfg shared.PolicyConfig) (*Engine, error) {
+	rules := []rule{
+		{
+			name:     RuleMissingTag,
+			severity: SeverityError,
+			check: func(t Target, spec corev1.Container, image inventory.Image) (string, bool) {
+				if image.TagDefaulted() {
+					return "image has no tag nor digest and defaults to \"latest\"", true
+				}
+				return "", false
+			},
+		},
+		{
+			name:     RuleForbiddenTag,
+			severity: SeverityError,
+			check: func(t Target, spec corev1.Container, image inventory.Image) (string, bool) {
+				if !image.TagDefaulted() && slices.ContainsFunc(cfg.ForbiddenTags, func(glob string) bool { return inventory.MatchGlob(glob, image.Tag) }) {
+					return fmt.Sprintf("tag %q is forbidden", image.Tag), true
+				}
+				return "", false
+			},
+		},
+		{
+			name:     RuleRegistryNotAllowed,
+			severity: SeverityError,
+			check: func(t Target, spec corev1.Container, image inventory.Image) (string, bool) {
+				if len(cfg.AllowedRegistries) == 0 || slices.ContainsFunc(cfg.AllowedRegistries, func(glob string) bool { return registryAllowed(glob, image) }) {
+					return "", false
+				}
+				return fmt.Sprintf("registry %q is not allowed", image.Registry), true
+			},
+		},
+		{
+			name:     RuleMissingDigest,
+			severity: SeverityWarning,
+			check: func(t Target, spec corev1.Container, image inventory.Image) (string, bool) {
+				if !cfg.RequireDigest.Enabled || image.Digest != "" || !matchesSelector(t.NamespaceLabels, cfg.RequireDigest.NamespaceSelector) {
+					return "", false
+				}
+				return "image is not pinned by digest", true
+			},
+		},
+		{
+			name:     RuleForbiddenPullPolicy,
+			severity: SeverityWarning,
+			check: func(t Target, spec corev1.Container, image inventory.Image) (string, bool) {
+				pullPolicy := effectivePullPolicy(spec, image)
+				if slices.ContainsFunc(cfg.ForbiddenPullPolicies, func(p string) bool { return strings.EqualFold(p, string(pullPolicy)) }) {
+					return fmt.Sprintf("imagePullPolicy %s is forbidden", pullPolicy), true
+				}
+				return "", false
+			},
+		},
+	}
+
+	for name, severity := range cfg.Severities {
+		i := slices.IndexFunc(rules, func(r rule) bool { return r.name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("policy: unknown rule %q in severities", name)
+		}
+		parsed, err := ParseSeverity(severity)
+		if err != nil {
+			return nil, fmt.Errorf("policy: rule %s: %w", name, err)
+		}
+		rules[i].severity = parsed
+	}
+	for _, name := range cfg.DisabledRules {
+		if !slices.ContainsFunc(rules, func(r rule) bool { return r.name == name }) {
+			return nil, fmt.Errorf("policy: unknown rule %q in disabledRules", name)
+		}
+	}
+	rules = slices.DeleteFunc(rules, func(r rule) bool { return slices.Contains(cfg.DisabledRules, r.name) })
+
+	customRules, err := compileCustomRules(cfg.CustomRules, rules)
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{rules: append(rules, customRules...), custom: len(customRules) > 0}, nil
+}
+
+// registryAllowed matches an image against an allowedRegistries glob: a glob with a '/' is matched against
+// "registry/repository" (e.g. "ghcr.io/acme/*"), otherwise against the registry (e.g. "*.dkr.ecr.*.amazonaws.com")
+func registryAllowed(glob string, image inventory.Image) bool {
+	if strings.Contains(glob, "/") {
+		return inventory.MatchGlob(glob, image.RepositoryKey())
+	}
+	return inventory.MatchGlob(glob, image.Registry)
+}
+
+// matchesSelector reports whether labels match every key/value of a selector (an empty selector matches everything)
+func matchesSelector(labels, selector map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// effectivePullPolicy returns the pull policy of a container, with the Kubernetes default when it isn't set
+// (manifests usually leave it empty): Always for "latest", IfNotPresent otherwise
+func effectivePullPolicy(spec corev1.Container, image inventory.Image) corev1.PullPolicy {
+	if spec.ImagePullPolicy != "" {
+		return spec.ImagePullPolicy
+	}
+	if image.Tag == "latest" && image.Digest == "" {
+		return corev1.PullAlways
+	}
+	return corev1.PullIfNotPresent
+}
+
+// Evaluate returns the violations of a workload, sorted by container then rule
+func (e *Engine) Evaluate(t Target) []Violation {
+	violations := []Violation{}
+	if e.custom {
+		t.object = workloadObject(t)
+		if t.NamespaceLabels == nil {
+			t.NamespaceLabels = map[string]string{}
+		}
+	}
+	for _, spec := range t.Containers {
+		image := inventory.ParseImage(spec.Image)
+		for _, r := range e.rules {
+			message, violated := r.check(t, spec, image)
+			if !violated {
+				continue
+			}
+			violations = append(violations, Violation{
+				Rule:      r.name,
+				Severity:  r.severity,
+				Namespace: t.Workload.Namespace,
+				Kind:      t.Workload.Kind,
+				Workload:  t.Workload.Name,
+				Container: spec.Name,
+				Image:     spec.Image,
+				Message:   message,
+			})
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Container != violations[j].Container {
+			return violations[i].Container < violations[j].Container
+		}
+		return violations[i].Rule < violations[j].Rule
+	})
+	return violations
+}
@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+)
+
+// Recorder keeps the latest violations of every live workload and their sentinel_policy_violation series
+type Recorder struct {
+	series *SentinelPrometheus.SeriesSet // By workload key
+
+	mu         sync.Mutex
+	violations map[string][]Violation // workload key -> violations
+}
+
+// NewRecorder builds an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{
+		series:     SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelPolicyViolation),
+		violations: make(map[string][]Violation),
+	}
+}
+
+// Record replaces the violations of a workload (none once it is deleted)
+func (r *Recorder) Record(namespace, kind, name string, violations []Violation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := inventory.WorkloadKey(namespace, kind, name)
+	series := r.series.Update(key)
+	defer series.Commit() // Only the series of the violations that are gone are deleted
+
+	if len(violations) == 0 {
+		delete(r.violations, key)
+		return
+	}
+
+	r.violations[key] = violations
+	for _, v := range violations {
+		slog.Debug("Policy violation",
+			slog.String("ns/workload", v.Namespace+"/"+v.Workload),
+			slog.String("container", v.Container),
+			slog.String("rule", v.Rule),
+			slog.String("severity", string(v.Severity)),
+			slog.String("message", v.Message))
+		series.Set(1, v.Namespace, v.Kind, v.Workload, v.Container, v.Image, v.Rule, string(v.Severity))
+	}
+}
+
+// ContainerViolations returns the violations of one container of a workload
+func (r *Recorder) ContainerViolations(namespace, kind, name, container string) []Violation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var violations []Violation
+	for _, v := range r.violations[inventory.WorkloadKey(namespace, kind, name)] {
+		if v.Container == container {
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// Violations returns the violations of a namespace ("" for all) at or above a severity, sorted by workload
+func (r *Recorder) Violations(namespace string, min Severity) []Violation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	violations := []Violation{}
+	for _, workloadViolations := range r.violations {
+		for _, v := range workloadViolations {
+			if (namespace == "" || v.Namespace == namespace) && v.Severity.AtLeast(min) {
+				violations = append(violations, v)
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		ki := inventory.WorkloadKey(violations[i].Namespace, violations[i].Kind, violations[i].Workload)
+		kj := inventory.WorkloadKey(violations[j].Namespace, violations[j].Kind, violations[j].Workload)
+		if ki != kj {
+			return ki < kj
+		}
+		if violations[i].Container != violations[j].Container {
+			return violations[i].Container < violations[j].Container
+		}
+		return violations[i].Rule < violations[j].Rule
+	})
+	return violations
+}
@@ -29,9 +29,81 @@ METRICS Definition
 		# Dynamic labels from extraLabels config are appended here
 	  } 1
 
+ 2. SentinelImageChangesTotal:
+	-> sentinel_image_changes_total{workload_namespace, workload_type, workload_name, container_name, old_image_tag, new_image_tag}
+
+ 3. Notification delivery (notifier="webhook|cloudevents|chat"):
+	-> sentinel_notification_deliveries_total{notifier="webhook", endpoint="release-tracker", result="success|failure|dropped"}
+	-> sentinel_notification_retries_total{notifier="webhook", endpoint="release-tracker"}
+	-> sentinel_notification_queue_length{notifier="webhook", endpoint="release-tracker"}
+
+ 4. SentinelImagePreviousInfo (reconstructed from ReplicaSets / ControllerRevisions):
+	-> sentinel_image_previous_info{workload_namespace, workload_type, workload_name, container_name, image, image_registry, image_repository, image_tag, revision="3"} 1
+	   One series per image a container ran before its current one; revision is the latest revision that used it.
+
+ 5. GitOps drift (drift.enabled):
+	-> sentinel_image_drift{workload_namespace, workload_type, workload_name, container_name, drift_type="image|missing_in_cluster|missing_in_git", desired_image, live_image} 1
+	-> sentinel_drift_last_evaluation_timestamp_seconds
+
+ 6. BOM compliance (bom.enabled), only for the containers a BOM component governs:
+	-> sentinel_bom_compliance{workload_namespace, workload_type, workload_name, container_name, component, image_tag, status="compliant|outdated|unknown"} 1
+	-> sentinel_bom_instances{component}
+	-> sentinel_bom_outdated_instances{component}
+
+ 7. Vulnerability reports (vulnerabilities.enabled), joined with the running containers by image digest or reference:
+	-> sentinel_image_vulnerabilities{workload_namespace, workload_type, workload_name, container_name, image, severity="critical|high|medium|low|unknown"}
+	-> sentinel_image_vulnerability_report_missing{workload_namespace, workload_type, workload_name, container_name, image} 1
+	-> sentinel_vulnerability_reports
+
+ 8. End-of-life (eol.enabled), only for the containers whose image and release cycle the EOL catalogue knows:
+	-> sentinel_image_eol{workload_namespace, workload_type, workload_name, container_name, image, product, cycle} 1 (EOL) or 0
+	-> sentinel_image_days_until_eol{...same labels} (negative once EOL; absent when the catalogue has no EOL date)
+
+ 9. Image policies (policy.enabled), one series per violated rule:
+	-> sentinel_policy_violation{workload_namespace, workload_type, workload_name, container_name, image, rule="forbidden-tag", severity="error|warning|info"} 1
+
+ 10. Admission webhook (sentinel webhook):
+	-> sentinel_admission_reviews_total{kind, operation="CREATE|UPDATE", decision="allowed|warned|denied"}
+	-> sentinel_admission_violations_total{rule, severity, action="enforce|warn|dryrun"}
+	-> sentinel_admission_pinned_images_total{registry, result="pinned|failed"}
+
+ 11. Image metadata read from the registries (imageMetadata.enabled), for the containers whose image could be looked up:
+	-> sentinel_image_created_timestamp_seconds{workload_namespace, workload_type, workload_name, container_name, image, image_digest}
+	-> sentinel_image_compressed_size_bytes{...same labels}
+	-> sentinel_image_source_info{workload_namespace, workload_type, workload_name, container_name, image, source, revision, version} 1
+	   Only with imageMetadata.sourceInfo, from the OCI annotations of the image, or of the workload as a fallback.
+
+ 12. Newer versions (updates.enabled), for the containers running a version tag of an allowed repository:
+	-> sentinel_image_versions_behind{workload_namespace, workload_type, workload_name, container_name, image, latest_version, level="major|minor|patch"}
+
+ 13. Signatures (signatures.enabled), for the containers whose image digest could be resolved:
+	-> sentinel_image_signature_verified{workload_namespace, workload_type, workload_name, container_name, image, image_digest} 1 (signed by a configured key) or 0
+	-> sentinel_image_provenance_verified{...same labels} 1 (signed SLSA provenance attestation) or 0
+
 
 */
 
+// eolLabels are the labels of the EOL metrics
+var eolLabels = []string{
+	"workload_namespace",
+	"workload_type",
+	"workload_name",
+	"container_name",
+	"image",
+	"product",
+	"cycle",
+}
+
+// imageMetadataLabels are the labels of the image metadata metrics
+var imageMetadataLabels = []string{
+	"workload_namespace",
+	"workload_type",
+	"workload_name",
+	"container_name",
+	"image",
+	"image_digest",
+}
+
 var (
 	/*
 	 SentinelContainerImageInfo is built dynamically based on extraLabels configuration
@@ -55,6 +127,285 @@ var (
 			"new_image_tag",
 		},
 	)
+
+	// SentinelImagePreviousInfo lists the images containers ran in previous revisions of their workload
+	SentinelImagePreviousInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_image_previous_info",
+			Help: "Images used by containers in previous revisions of their workload",
+		},
+		[]string{
+			"workload_namespace",
+			"workload_type",
+			"workload_name",
+			"container_name",
+			"image",
+			"image_registry",
+			"image_repository",
+			"image_tag",
+			"revision",
+		},
+	)
+
+	// SentinelImageDrift lists the workloads/containers whose live image differs from the desired state
+	SentinelImageDrift = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_image_drift",
+			Help: "Workloads and containers whose live image differs from the desired state manifests",
+		},
+		[]string{
+			"workload_namespace",
+			"workload_type",
+			"workload_name",
+			"container_name",
+			"drift_type",
+			"desired_image",
+			"live_image",
+		},
+	)
+
+	// SentinelDriftLastEvaluation is the time of the latest successful drift evaluation
+	SentinelDriftLastEvaluation = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "sentinel_drift_last_evaluation_timestamp_seconds",
+			Help: "Unix time of the latest successful comparison with the desired state manifests",
+		},
+	)
+
+	// SentinelBOMCompliance tells whether each governed container runs a version allowed by the BOM
+	SentinelBOMCompliance = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_bom_compliance",
+			Help: "Compliance of containers with the desired version BOM (status: compliant, outdated, unknown)",
+		},
+		[]string{
+			"workload_namespace",
+			"workload_type",
+			"workload_name",
+			"container_name",
+			"component",
+			"image_tag",
+			"status",
+		},
+	)
+
+	// SentinelBOMInstances counts the containers governed by each BOM component
+	SentinelBOMInstances = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_bom_instances",
+			Help: "Number of containers running a BOM component",
+		},
+		[]string{"component"},
+	)
+
+	// SentinelBOMOutdatedInstances counts the containers of each BOM component not running an allowed version
+	SentinelBOMOutdatedInstances = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_bom_outdated_instances",
+			Help: "Number of containers running a BOM component in a version the BOM doesn't allow",
+		},
+		[]string{"component"},
+	)
+
+	// SentinelImageVulnerabilities counts the vulnerabilities of the image of each container, per severity
+	SentinelImageVulnerabilities = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_image_vulnerabilities",
+			Help: "Number of distinct vulnerabilities of the image of a container, per severity, from its vulnerability report",
+		},
+		[]string{
+			"workload_namespace",
+			"workload_type",
+			"workload_name",
+			"container_name",
+			"image",
+			"severity",
+		},
+	)
+
+	// SentinelImageVulnerabilityReportMissing flags the containers whose image has no vulnerability report
+	SentinelImageVulnerabilityReportMissing = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_image_vulnerability_report_missing",
+			Help: "Set for containers whose image no vulnerability report covers",
+		},
+		[]string{
+			"workload_namespace",
+			"workload_type",
+			"workload_name",
+			"container_name",
+			"image",
+		},
+	)
+
+	// SentinelVulnerabilityReports counts the vulnerability reports loaded
+	SentinelVulnerabilityReports = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "sentinel_vulnerability_reports",
+			Help: "Number of vulnerability reports loaded",
+		},
+	)
+
+	// SentinelImageEOL tells whether the release cycle of the image of each container reached its end of life
+	SentinelImageEOL = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_image_eol",
+			Help: "1 when the release cycle of the image of a container reached its end of life, 0 otherwise",
+		},
+		eolLabels,
+	)
+
+	// SentinelImageDaysUntilEOL counts the days until the release cycle of the image of each container reaches its end of life
+	SentinelImageDaysUntilEOL = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_image_days_until_eol",
+			Help: "Days until the release cycle of the image of a container reaches its end of life (negative once EOL)",
+		},
+		eolLabels,
+	)
+
+	// SentinelImageCreatedTimestamp is the creation date of the image of each container, from its image config
+	SentinelImageCreatedTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_image_created_timestamp_seconds",
+			Help: "Creation date of the image of a container (Unix time), read from the image config in the registry",
+		},
+		imageMetadataLabels,
+	)
+
+	// SentinelImageCompressedSize is the compressed size of the image of each container
+	SentinelImageCompressedSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_image_compressed_size_bytes",
+			Help: "Sum of the compressed layers of the image of a container (of the linux/amd64 image for multi-platform images)",
+		},
+		imageMetadataLabels,
+	)
+
+	// SentinelImageSignatureVerified tells whether the image of each container is signed by one of the configured keys
+	SentinelImageSignatureVerified = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_image_signature_verified",
+			Help: "1 if the image digest of a container has a cosign signature verified with a configured public key, 0 otherwise",
+		},
+		imageMetadataLabels,
+	)
+
+	// SentinelImageProvenanceVerified tells whether the image of each container has a signed SLSA provenance attestation
+	SentinelImageProvenanceVerified = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_image_provenance_verified",
+			Help: "1 if the image digest of a container has a SLSA provenance attestation verified with a configured public key, 0 otherwise",
+		},
+		imageMetadataLabels,
+	)
+
+	// SentinelImageSourceInfo links the image of each container to its source repository and commit
+	SentinelImageSourceInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_image_source_info",
+			Help: "Source repository, revision and version of the image of a container, from the OCI annotations of the image or of the workload",
+		},
+		[]string{
+			"workload_namespace",
+			"workload_type",
+			"workload_name",
+			"container_name",
+			"image",
+			"source",
+			"revision",
+			"version",
+		},
+	)
+
+	// SentinelImageVersionsBehind counts the versions released after the one each container runs
+	SentinelImageVersionsBehind = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_image_versions_behind",
+			Help: "Number of major, minor or patch versions released in the registry after the version a container runs",
+		},
+		[]string{
+			"workload_namespace",
+			"workload_type",
+			"workload_name",
+			"container_name",
+			"image",
+			"latest_version",
+			"level",
+		},
+	)
+
+	// SentinelPolicyViolation flags the containers breaking an image policy rule
+	SentinelPolicyViolation = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_policy_violation",
+			Help: "Set for each image policy rule a container breaks, with the rule name and severity",
+		},
+		[]string{
+			"workload_namespace",
+			"workload_type",
+			"workload_name",
+			"container_name",
+			"image",
+			"rule",
+			"severity",
+		},
+	)
+
+	// SentinelAdmissionReviewsTotal counts the workloads reviewed by the admission webhook, by decision
+	SentinelAdmissionReviewsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sentinel_admission_reviews_total",
+			Help: "Total number of workloads reviewed by the admission webhook by kind, operation and decision (allowed, warned, denied)",
+		},
+		[]string{"kind", "operation", "decision"},
+	)
+
+	// SentinelAdmissionViolationsTotal counts the policy violations found at admission, by the action taken
+	SentinelAdmissionViolationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sentinel_admission_violations_total",
+			Help: "Total number of image policy violations found by the admission webhook by rule, severity and action (enforce, warn, dryrun)",
+		},
+		[]string{"rule", "severity", "action"},
+	)
+
+	// SentinelAdmissionPinnedImagesTotal counts the images the mutating webhook pinned to a digest, or failed to
+	SentinelAdmissionPinnedImagesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sentinel_admission_pinned_images_total",
+			Help: "Total number of images the admission webhook pinned to their digest (pinned) or could not resolve (failed), by registry",
+		},
+		[]string{"registry", "result"},
+	)
+
+	// SentinelNotificationDeliveriesTotal counts notifications by outcome:
+	// success (delivered), failure (gave up after retries) or dropped (queue full)
+	SentinelNotificationDeliveriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sentinel_notification_deliveries_total",
+			Help: "Total number of notifications by notifier, endpoint and result (success, failure, dropped)",
+		},
+		[]string{"notifier", "endpoint", "result"},
+	)
+
+	// SentinelNotificationRetriesTotal counts delivery attempts that had to be retried
+	SentinelNotificationRetriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sentinel_notification_retries_total",
+			Help: "Total number of notification delivery retries",
+		},
+		[]string{"notifier", "endpoint"},
+	)
+
+	// SentinelNotificationQueueLength is the number of notifications waiting to be delivered
+	SentinelNotificationQueueLength = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sentinel_notification_queue_length",
+			Help: "Number of notifications waiting for delivery",
+		},
+		[]string{"notifier", "endpoint"},
+	)
 )
 
 /*
@@ -3,6 +3,7 @@ This is a webserver listening on /metrics.
 
 SCOPE:
 - Expose Prometheus metrics coming from Sentinel
+- Serve the Sentinel API handlers registered on the default mux (see pkg/api)
 */
 
 package prometheus
@@ -24,6 +25,30 @@ func Init(metricsPort string, extraLabels []shared.ExtraLabel) {
 	// Register metrics with Prometheus
 	prometheus.MustRegister(SentinelContainerImageInfo)
 	prometheus.MustRegister(SentinelImageChangesTotal)
+	prometheus.MustRegister(SentinelImagePreviousInfo)
+	prometheus.MustRegister(SentinelImageDrift)
+	prometheus.MustRegister(SentinelDriftLastEvaluation)
+	prometheus.MustRegister(SentinelBOMCompliance)
+	prometheus.MustRegister(SentinelBOMInstances)
+	prometheus.MustRegister(SentinelBOMOutdatedInstances)
+	prometheus.MustRegister(SentinelImageVulnerabilities)
+	prometheus.MustRegister(SentinelImageVulnerabilityReportMissing)
+	prometheus.MustRegister(SentinelVulnerabilityReports)
+	prometheus.MustRegister(SentinelImageEOL)
+	prometheus.MustRegister(SentinelImageDaysUntilEOL)
+	prometheus.MustRegister(SentinelImageCreatedTimestamp)
+	prometheus.MustRegister(SentinelImageCompressedSize)
+	prometheus.MustRegister(SentinelImageSourceInfo)
+	prometheus.MustRegister(SentinelImageVersionsBehind)
+	prometheus.MustRegister(SentinelImageSignatureVerified)
+	prometheus.MustRegister(SentinelImageProvenanceVerified)
+	prometheus.MustRegister(SentinelPolicyViolation)
+	prometheus.MustRegister(SentinelAdmissionReviewsTotal)
+	prometheus.MustRegister(SentinelAdmissionViolationsTotal)
+	prometheus.MustRegister(SentinelAdmissionPinnedImagesTotal)
+	prometheus.MustRegister(SentinelNotificationDeliveriesTotal)
+	prometheus.MustRegister(SentinelNotificationRetriesTotal)
+	prometheus.MustRegister(SentinelNotificationQueueLength)
 
 	// Start HTTP server in their Go Routine so that it does not block the main thread
 	go func() {
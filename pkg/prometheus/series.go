@@ -0,0 +1,68 @@
+/*
+Series kept in sync with evaluation results.
+
+SCOPE:
+- Remember the series each group (e.g. a workload) was given by its latest evaluation
+- Delete only the series that are gone once a new evaluation is done, instead of clearing them before it:
+  a scrape during an evaluation never sees the series vanish
+*/
+
+package prometheus
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SeriesSet tracks the series of a GaugeVec by group. It is safe for concurrent use.
+type SeriesSet struct {
+	vec *prometheus.GaugeVec
+
+	mu     sync.Mutex
+	groups map[string]map[string][]string // group -> joined label values -> label values
+}
+
+// NewSeriesSet tracks the series of a GaugeVec
+func NewSeriesSet(vec *prometheus.GaugeVec) *SeriesSet {
+	return &SeriesSet{vec: vec, groups: make(map[string]map[string][]string)}
+}
+
+/*
+Update starts the new evaluation of a group: the series set through it are kept, the other series the group had
+are deleted by Commit. The updates of a same group must not run concurrently.
+*/
+func (s *SeriesSet) Update(group string) *SeriesUpdate {
+	return &SeriesUpdate{set: s, group: group, series: make(map[string][]string)}
+}
+
+// SeriesUpdate is the new evaluation of the series of a group
+type SeriesUpdate struct {
+	set    *SeriesSet
+	group  string
+	series map[string][]string
+}
+
+// Set sets a series of the group
+func (u *SeriesUpdate) Set(value float64, labelValues ...string) {
+	u.set.vec.WithLabelValues(labelValues...).Set(value)
+	u.series[strings.Join(labelValues, "\x00")] = labelValues
+}
+
+// Commit deletes the series of the group that weren't set by this update
+func (u *SeriesUpdate) Commit() {
+	u.set.mu.Lock()
+	defer u.set.mu.Unlock()
+
+	for key, labelValues := range u.set.groups[u.group] {
+		if _, ok := u.series[key]; !ok {
+			u.set.vec.DeleteLabelValues(labelValues...)
+		}
+	}
+	if len(u.series) == 0 {
+		delete(u.set.groups, u.group)
+		return
+	}
+	u.set.groups[u.group] = u.series
+}
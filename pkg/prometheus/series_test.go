@@ -0,0 +1,44 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSeriesSet(t *testing.T) {
+	vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_series"}, []string{"workload", "image"})
+	set := NewSeriesSet(vec)
+
+	update := set.Update("prod/api")
+	update.Set(1, "prod/api", "nginx:1.27")
+	update.Set(1, "prod/api", "envoy:1.31")
+	update.Commit()
+	update = set.Update("prod/web")
+	update.Set(1, "prod/web", "nginx:1.27")
+	update.Commit()
+
+	// A new evaluation keeps the series still there (with their new value) and deletes the other ones of the group only
+	update = set.Update("prod/api")
+	update.Set(2, "prod/api", "nginx:1.27")
+	if got := testutil.CollectAndCount(vec); got != 3 {
+		t.Fatalf("got %d series before the commit, want 3", got)
+	}
+	update.Commit()
+	if got := testutil.CollectAndCount(vec); got != 2 {
+		t.Fatalf("got %d series, want 2", got)
+	}
+	if got := testutil.ToFloat64(vec.WithLabelValues("prod/api", "nginx:1.27")); got != 2 {
+		t.Fatalf("got value %v, want 2", got)
+	}
+
+	// An empty evaluation deletes every series of the group
+	set.Update("prod/web").Commit()
+	if got := testutil.CollectAndCount(vec); got != 1 {
+		t.Fatalf("got %d series, want 1", got)
+	}
+	if _, ok := set.groups["prod/web"]; ok {
+		t.Fatal("an empty group is still tracked")
+	}
+}
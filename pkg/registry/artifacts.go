@@ -0,0 +1,43 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+)
+
+// Layer is a layer of an artifact stored next to an image, with its content
+type Layer struct {
+	MediaType   string
+	Digest      string
+	Annotations map[string]string
+	Data        []byte
+}
+
+/*
+ArtifactLayers reads the layers of an artifact stored under a tag of the repository of an image,
+e.g. the cosign signatures of sha256:abc... under the tag "sha256-abc....sig". found is false when the tag doesn't exist.
+*/
+func (c *Client) ArtifactLayers(ctx context.Context, image inventory.Image, tag string, keychain Keychain) (layers []Layer, found bool, err error) {
+	host, repository := apiHost(image.Registry), apiRepository(image)
+	creds := c.credentialsFor(host, keychain)
+
+	m, _, _, err := c.manifest(ctx, host, repository, tag, creds)
+	if errors.Is(err, ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", image.RepositoryKey(), err)
+	}
+
+	for _, d := range m.Layers {
+		data, err := c.blobBytes(ctx, host, repository, d.Digest, creds)
+		if err != nil {
+			return nil, true, fmt.Errorf("%s: %w", image.RepositoryKey(), err)
+		}
+		layers = append(layers, Layer{MediaType: d.MediaType, Digest: d.Digest, Annotations: d.Annotations, Data: data})
+	}
+	return layers, true, nil
+}
@@ -0,0 +1,108 @@
+package registry
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache is a size-bounded LRU cache whose entries also expire after a TTL. It is safe for concurrent use.
+type Cache[V any] struct {
+	mu       sync.Mutex
+	max      int
+	entries  map[string]*list.Element
+	order    *list.List          // Front: most recently used
+	inflight map[string]*load[V] // key -> load in progress
+}
+
+// load is a lookup in progress, shared by the callers of Load asking for the same key
+type load[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// cacheEntry is a cached value, or the error of the lookup that produced it
+type cacheEntry[V any] struct {
+	key     string
+	value   V
+	err     error
+	expires time.Time
+}
+
+// NewCache returns an empty cache holding at most max entries
+func NewCache[V any](max int) *Cache[V] {
+	if max <= 0 {
+		max = 1
+	}
+	return &Cache[V]{max: max, entries: make(map[string]*list.Element), order: list.New(), inflight: make(map[string]*load[V])}
+}
+
+// Get returns the value (or error) cached for a key, if not expired
+func (c *Cache[V]) Get(key string) (V, error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	element, ok := c.entries[key]
+	if !ok {
+		return zero, nil, false
+	}
+	entry := element.Value.(*cacheEntry[V])
+	if time.Now().After(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return zero, nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, entry.err, true
+}
+
+// Add caches a value (or the error of its lookup) for ttl, evicting the least recently used entry when full
+func (c *Cache[V]) Add(key string, value V, err error, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry[V]{key: key, value: value, err: err, expires: time.Now().Add(ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry[V]).key)
+	}
+}
+
+/*
+Load returns the value (or error) cached for a key, or calls fetch and caches its result for the TTL it returns.
+Concurrent calls for the same key wait for the first one, so that a value is never fetched twice at the same time.
+*/
+func (c *Cache[V]) Load(key string, fetch func() (V, error, time.Duration)) (V, error) {
+	if value, err, ok := c.Get(key); ok {
+		return value, err
+	}
+
+	c.mu.Lock()
+	if l, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-l.done
+		return l.value, l.err
+	}
+	l := &load[V]{done: make(chan struct{})}
+	c.inflight[key] = l
+	c.mu.Unlock()
+
+	var ttl time.Duration
+	l.value, l.err, ttl = fetch()
+	c.Add(key, l.value, l.err, ttl)
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	c.mu.Unlock()
+	close(l.done)
+	return l.value, l.err
+}
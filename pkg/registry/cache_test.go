@@ -0,0 +1,104 @@
+package registry
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheTTL(t *testing.T) {
+	c := NewCache[string](10)
+	c.Add("short", "a", nil, 20*time.Millisecond)
+	c.Add("long", "b", nil, time.Hour)
+
+	if value, _, ok := c.Get("short"); !ok || value != "a" {
+		t.Fatalf("got %q (%t), want a fresh entry", value, ok)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, _, ok := c.Get("short"); ok {
+		t.Fatal("expired entry still returned")
+	}
+	if value, _, ok := c.Get("long"); !ok || value != "b" {
+		t.Fatalf("got %q (%t), want b", value, ok)
+	}
+}
+
+func TestCacheLRU(t *testing.T) {
+	c := NewCache[int](2)
+	c.Add("a", 1, nil, time.Hour)
+	c.Add("b", 2, nil, time.Hour)
+	c.Get("a") // b is now the least recently used
+	c.Add("c", 3, nil, time.Hour)
+
+	if _, _, ok := c.Get("b"); ok {
+		t.Fatal("least recently used entry not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, _, ok := c.Get(key); !ok {
+			t.Fatalf("entry %s evicted", key)
+		}
+	}
+}
+
+func TestCacheLoad(t *testing.T) {
+	c := NewCache[string](10)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fetch := func() (string, error, time.Duration) {
+		calls.Add(1)
+		<-release
+		return "digest", nil, time.Hour
+	}
+
+	// Concurrent loads of the same key share one fetch
+	var wg sync.WaitGroup
+	values := make([]string, 8)
+	for i := range values {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values[i], _ = c.Load("key", fetch)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("got %d fetches, want 1", calls.Load())
+	}
+	for _, value := range values {
+		if value != "digest" {
+			t.Fatalf("got %q, want digest", value)
+		}
+	}
+	if value, _ := c.Load("key", fetch); value != "digest" || calls.Load() != 1 {
+		t.Fatalf("cached value not reused: %q after %d fetches", value, calls.Load())
+	}
+}
+
+func TestCacheLoadError(t *testing.T) {
+	c := NewCache[string](10)
+	failure := errors.New("registry down")
+	var calls int
+	fetch := func() (string, error, time.Duration) {
+		calls++
+		return "", failure, 20 * time.Millisecond
+	}
+
+	for range 2 {
+		if _, err := c.Load("key", fetch); !errors.Is(err, failure) {
+			t.Fatalf("got %v, want the fetch error", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("got %d fetches, want the error cached", calls)
+	}
+	time.Sleep(40 * time.Millisecond)
+	c.Load("key", fetch)
+	if calls != 2 {
+		t.Fatalf("got %d fetches, want a retry once the error expired", calls)
+	}
+}
@@ -0,0 +1,372 @@
+/*
+Client of the OCI distribution API (the Docker Registry HTTP API v2).
+
+SCOPE:
+- Talk to any registry implementing the distribution spec: Docker Hub, GHCR, ECR, GCR/Artifact Registry, Harbor...
+- Authenticate anonymously, with HTTP basic auth, or with the bearer token flow (the WWW-Authenticate challenge),
+  with the configured credentials or those of the imagePullSecrets of a workload (pullsecrets.go)
+- Resolve tags to the digest of their manifest (or image index, for multi-platform images)
+- Read the metadata of images from their manifest and config (metadata.go)
+*/
+
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+)
+
+// manifestMediaTypes are the manifest formats Sentinel accepts, image indexes first so that tags resolve to the digest the kubelet pulls
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// maxManifestBytes bounds the size of a manifest read from a registry
+const maxManifestBytes = 4 << 20
+
+// Credentials authenticate to a registry
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Client talks to container registries. It is safe for concurrent use.
+type Client struct {
+	http        *http.Client
+	credentials map[string]Credentials   // registry host -> credentials
+	insecure    map[string]bool          // registry hosts served over plain HTTP
+	interval    map[string]time.Duration // registry host -> minimum time between two requests (rate limit)
+
+	mu       sync.Mutex
+	tokens   map[string]token     // "<host> <scope> <credentials identity>" -> Authorization header
+	nextSlot map[string]time.Time // registry host -> earliest time of its next request
+}
+
+// token is a cached Authorization header and its expiry
+type token struct {
+	value   string
+	expires time.Time
+}
+
+// NewClient builds a client with the credentials of the configured registries
+func NewClient(registries []shared.RegistryConfig) (*Client, error) {
+	c := &Client{
+		http:        &http.Client{Timeout: 10 * time.Second},
+		credentials: make(map[string]Credentials),
+		insecure:    make(map[string]bool),
+		interval:    make(map[string]time.Duration),
+		tokens:      make(map[string]token),
+		nextSlot:    make(map[string]time.Time),
+	}
+	for _, r := range registries {
+		if r.Host == "" {
+			return nil, fmt.Errorf("registries: host is required")
+		}
+		password := r.Password
+		if r.PasswordEnv != "" {
+			value, ok := os.LookupEnv(r.PasswordEnv)
+			if !ok {
+				return nil, fmt.Errorf("registry %s: password environment variable %q is not set", r.Host, r.PasswordEnv)
+			}
+			password = value
+		}
+		host := apiHost(r.Host)
+		if r.Username != "" {
+			c.credentials[host] = Credentials{Username: r.Username, Password: password}
+		}
+		c.insecure[host] = r.Insecure
+		if r.RateLimit > 0 {
+			c.interval[host] = time.Minute / time.Duration(r.RateLimit)
+		}
+	}
+	return c, nil
+}
+
+// apiHost returns the host serving the registry API (Docker Hub images are named docker.io but served elsewhere)
+func apiHost(registry string) string {
+	if registry == "docker.io" || registry == "index.docker.io" {
+		return "registry-1.docker.io"
+	}
+	return registry
+}
+
+// apiRepository returns the repository as named by the registry API (official Docker Hub images live under library/)
+func apiRepository(image inventory.Image) string {
+	if image.Registry == "docker.io" && !strings.Contains(image.Repository, "/") {
+		return "library/" + image.Repository
+	}
+	return image.Repository
+}
+
+// credentialsFor returns the credentials of a registry host: those of the keychain first, then the configured ones
+func (c *Client) credentialsFor(host string, keychain Keychain) *Credentials {
+	if creds, ok := keychain[host]; ok {
+		return &creds
+	}
+	if creds, ok := c.credentials[host]; ok {
+		return &creds
+	}
+	return nil
+}
+
+/*
+CacheKey prefixes the key of something read from the registry of an image with the identity of the credentials the
+client uses there, so that what a set of credentials could (or couldn't) read is never served to another one.
+*/
+func (c *Client) CacheKey(image inventory.Image, keychain Keychain, key string) string {
+	return identity(c.credentialsFor(apiHost(image.Registry), keychain)) + " " + key
+}
+
+// identity names a set of credentials without revealing them: "anon", or a hash of the username and password
+func identity(creds *Credentials) string {
+	if creds == nil {
+		return "anon"
+	}
+	sum := sha256.Sum256([]byte(creds.Username + "\x00" + creds.Password))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ResolveDigest returns the digest of the manifest a tag points to
+func (c *Client) ResolveDigest(ctx context.Context, image inventory.Image, keychain Keychain) (string, error) {
+	if image.Tag == "" {
+		return "", fmt.Errorf("%s has no tag", image.Reference)
+	}
+	host, repository := apiHost(image.Registry), apiRepository(image)
+	path := "/v2/" + repository + "/manifests/" + url.PathEscape(image.Tag)
+	accept := strings.Join(manifestMediaTypes, ", ")
+	creds := c.credentialsFor(host, keychain)
+
+	// HEAD is not counted by the Docker Hub pull rate limit
+	resp, err := c.do(ctx, http.MethodHead, host, repository, path, accept, creds)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if err := checkStatus(resp, image); err != nil {
+		return "", err
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// Registries may omit the header: the digest is the hash of the manifest as served
+	resp, err = c.do(ctx, http.MethodGet, host, repository, path, accept, creds)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, image); err != nil {
+		return "", err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// checkStatus turns an error response into an error
+func checkStatus(resp *http.Response, image inventory.Image) error {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s: manifest unknown", image.Reference)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%s: access denied by %s (%s)", image.Reference, image.Registry, resp.Status)
+	default:
+		return fmt.Errorf("%s: registry %s answered %s", image.Reference, image.Registry, resp.Status)
+	}
+}
+
+/*
+do sends a request to a registry, answering its authentication challenge when it asks for one.
+The bearer tokens are cached per repository, so only the first request of a repository pays for the challenge.
+*/
+func (c *Client) do(ctx context.Context, method, host, repository, path, accept string, creds *Credentials) (*http.Response, error) {
+	scheme := "https"
+	if c.insecure[host] {
+		scheme = "http"
+	}
+	scope := "repository:" + repository + ":pull"
+	cacheKey := host + " " + scope + " " + identity(creds)
+
+	send := func(authorization string) (*http.Response, error) {
+		if err := c.wait(ctx, host); err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, scheme+"://"+host+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", accept)
+		req.Header.Set("User-Agent", "sentinel")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		return c.http.Do(req)
+	}
+
+	resp, err := send(c.cachedAuthorization(cacheKey))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	authorization, err := c.authorize(ctx, host, scope, challenge, creds, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	return send(authorization)
+}
+
+// wait blocks until the rate limit of a registry allows one more request
+func (c *Client) wait(ctx context.Context, host string) error {
+	interval, limited := c.interval[host]
+	if !limited {
+		return nil
+	}
+	c.mu.Lock()
+	slot := c.nextSlot[host]
+	if now := time.Now(); slot.Before(now) {
+		slot = now
+	}
+	c.nextSlot[host] = slot.Add(interval)
+	c.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// cachedAuthorization returns the Authorization header of a previous challenge, if still valid
+func (c *Client) cachedAuthorization(cacheKey string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t, ok := c.tokens[cacheKey]; ok && time.Now().Before(t.expires) {
+		return t.value
+	}
+	return ""
+}
+
+// remember caches the Authorization header of a scope
+func (c *Client) remember(cacheKey, authorization string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Expired tokens are dropped here, so that the cache doesn't grow with every repository ever queried
+	for key, t := range c.tokens {
+		if time.Now().After(t.expires) {
+			delete(c.tokens, key)
+		}
+	}
+	c.tokens[cacheKey] = token{value: authorization, expires: time.Now().Add(ttl)}
+}
+
+// authorize answers a WWW-Authenticate challenge and returns the Authorization header to retry with
+func (c *Client) authorize(ctx context.Context, host, scope, challenge string, creds *Credentials, cacheKey string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if creds == nil {
+			return "", fmt.Errorf("registry %s requires credentials", host)
+		}
+		authorization := "Basic " + base64.StdEncoding.EncodeToString([]byte(creds.Username+":"+creds.Password))
+		c.remember(cacheKey, authorization, time.Hour)
+		return authorization, nil
+
+	case "bearer":
+		realm := params["realm"]
+		if realm == "" {
+			return "", fmt.Errorf("registry %s: bearer challenge without realm", host)
+		}
+		query := url.Values{}
+		if service := params["service"]; service != "" {
+			query.Set("service", service)
+		}
+		query.Set("scope", scope)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+query.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		if creds != nil {
+			req.SetBasicAuth(creds.Username, creds.Password)
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("registry %s: token endpoint answered %s", host, resp.Status)
+		}
+
+		var body struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int    `json:"expires_in"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestBytes)).Decode(&body); err != nil {
+			return "", fmt.Errorf("registry %s: invalid token response: %w", host, err)
+		}
+		value := body.Token
+		if value == "" {
+			value = body.AccessToken
+		}
+		if value == "" {
+			return "", fmt.Errorf("registry %s: token endpoint returned no token", host)
+		}
+		if body.ExpiresIn <= 0 {
+			body.ExpiresIn = 60 // Default of the token spec
+		}
+
+		authorization := "Bearer " + value
+		// Refresh a little early, so that a token never expires between the check and the request
+		c.remember(cacheKey, authorization, time.Duration(body.ExpiresIn)*time.Second-10*time.Second)
+		return authorization, nil
+	}
+	return "", fmt.Errorf("registry %s: unsupported authentication challenge %q", host, challenge)
+}
+
+// parseChallenge splits a WWW-Authenticate header like `Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.TrimSpace(key); key != "" {
+			params[strings.ToLower(key)] = value
+		}
+	}
+	return scheme, params
+}
@@ -0,0 +1,367 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+)
+
+// testRegistry is an in-memory registry serving manifests, blobs and tags, behind an optional authentication
+type testRegistry struct {
+	*httptest.Server
+	auth       string            // "", "basic" or "bearer"
+	manifests  map[string][]byte // "<repository>/<tag or digest>" -> manifest
+	blobs      map[string][]byte // digest -> content
+	tags       []string
+	pageSize   int  // Tags per page
+	noDigest   bool // Omit Docker-Content-Digest
+	tokenCalls atomic.Int32
+}
+
+const (
+	testUsername = "acme-bot"
+	testPassword = "s3cret"
+	testToken    = "t0k3n"
+)
+
+func newTestRegistry(t *testing.T, auth string) *testRegistry {
+	t.Helper()
+	r := &testRegistry{auth: auth, manifests: make(map[string][]byte), blobs: make(map[string][]byte), pageSize: 2}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serve))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// host returns the registry host, as written in image references
+func (r *testRegistry) host() string {
+	return strings.TrimPrefix(r.URL, "http://")
+}
+
+// client returns a client talking plain HTTP to the registry, with its credentials when it asks for some
+func (r *testRegistry) client(t *testing.T, withCredentials bool) *Client {
+	t.Helper()
+	cfg := shared.RegistryConfig{Host: r.host(), Insecure: true}
+	if withCredentials {
+		cfg.Username, cfg.Password = testUsername, testPassword
+	}
+	client, err := NewClient([]shared.RegistryConfig{cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// image parses a reference of the registry
+func (r *testRegistry) image(reference string) inventory.Image {
+	return inventory.ParseImage(r.host() + "/" + reference)
+}
+
+// addBlob stores a blob and returns its digest
+func (r *testRegistry) addBlob(data []byte) string {
+	sum := sha256.Sum256(data)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	r.blobs[digest] = data
+	return digest
+}
+
+// addManifest stores a manifest under its digest and the given tags, and returns its digest
+func (r *testRegistry) addManifest(t *testing.T, repository string, m any, tags ...string) string {
+	t.Helper()
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := r.addBlob(data)
+	r.manifests[repository+"/"+digest] = data
+	for _, tag := range tags {
+		r.manifests[repository+"/"+tag] = data
+	}
+	return digest
+}
+
+func (r *testRegistry) serve(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		r.tokenCalls.Add(1)
+		if user, password, ok := req.BasicAuth(); !ok || user != testUsername || password != testPassword {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"token": %q, "expires_in": 300}`, testToken)
+		return
+	}
+
+	switch r.auth {
+	case "basic":
+		if user, password, ok := req.BasicAuth(); !ok || user != testUsername || password != testPassword {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	case "bearer":
+		if req.Header.Get("Authorization") != "Bearer "+testToken {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, r.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case strings.Contains(path, "/manifests/"):
+		repository, reference, _ := strings.Cut(path, "/manifests/")
+		data, ok := r.manifests[repository+"/"+reference]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var m manifest
+		json.Unmarshal(data, &m)
+		w.Header().Set("Content-Type", m.MediaType)
+		if !r.noDigest {
+			sum := sha256.Sum256(data)
+			w.Header().Set("Docker-Content-Digest", "sha256:"+hex.EncodeToString(sum[:]))
+		}
+		if req.Method != http.MethodHead {
+			w.Write(data)
+		}
+	case strings.Contains(path, "/blobs/"):
+		_, digest, _ := strings.Cut(path, "/blobs/")
+		data, ok := r.blobs[digest]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case strings.HasSuffix(path, "/tags/list"):
+		// Pages of pageSize tags after the last one received, as the distribution spec does
+		start := 0
+		if last := req.URL.Query().Get("last"); last != "" {
+			for i, tag := range r.tags {
+				if tag == last {
+					start = i + 1
+				}
+			}
+		}
+		end := min(start+r.pageSize, len(r.tags))
+		if end < len(r.tags) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?last=%s&n=%d>; rel="next"`, req.URL.Path, r.tags[end-1], r.pageSize))
+		}
+		json.NewEncoder(w).Encode(map[string]any{"tags": r.tags[start:end]})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// platform returns the platform of an image index entry
+func platform(os, architecture, variant string) *struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant"`
+} {
+	return &struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+		Variant      string `json:"variant"`
+	}{OS: os, Architecture: architecture, Variant: variant}
+}
+
+// singleImage stores an image manifest and its config under some tags, and returns the manifest digest
+func singleImage(t *testing.T, r *testRegistry, repository, created, arch string, layerSize int64, tags ...string) string {
+	t.Helper()
+	config := r.addBlob([]byte(fmt.Sprintf(`{"created": %q, "os": "linux", "architecture": %q, "config": {"Labels": {"org.opencontainers.image.source": "https://github.com/acme/api"}}}`, created, arch)))
+	return r.addManifest(t, repository, manifest{
+		MediaType: "application/vnd.oci.image.manifest.v1+json",
+		Config:    descriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: config},
+		Layers:    []descriptor{{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: "sha256:00", Size: layerSize}},
+	}, tags...)
+}
+
+func TestResolveDigestAuth(t *testing.T) {
+	tests := []struct {
+		name        string
+		auth        string
+		credentials bool
+		wantErr     bool
+	}{
+		{"anonymous", "", false, false},
+		{"basic", "basic", true, false},
+		{"basic without credentials", "basic", false, true},
+		{"bearer", "bearer", true, false},
+		{"bearer without credentials", "bearer", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRegistry(t, tt.auth)
+			want := singleImage(t, r, "acme/api", "2025-01-02T03:04:05Z", "amd64", 100, "1.0.0")
+			client := r.client(t, tt.credentials)
+
+			for range 2 {
+				digest, err := client.ResolveDigest(context.Background(), r.image("acme/api:1.0.0"), nil)
+				if tt.wantErr {
+					if err == nil {
+						t.Fatal("expected an error")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if digest != want {
+					t.Fatalf("got %s, want %s", digest, want)
+				}
+			}
+			if tt.auth == "bearer" && r.tokenCalls.Load() != 1 {
+				t.Fatalf("got %d token requests, want 1 (the token is cached)", r.tokenCalls.Load())
+			}
+		})
+	}
+}
+
+func TestResolveDigestKeychain(t *testing.T) {
+	r := newTestRegistry(t, "bearer")
+	want := singleImage(t, r, "acme/api", "2025-01-02T03:04:05Z", "amd64", 100, "1.0.0")
+	client := r.client(t, false)
+
+	keychain := Keychain{r.host(): {Username: testUsername, Password: testPassword}}
+	digest, err := client.ResolveDigest(context.Background(), r.image("acme/api:1.0.0"), keychain)
+	if err != nil || digest != want {
+		t.Fatalf("got %s (%v), want %s", digest, err, want)
+	}
+}
+
+func TestResolveDigestWithoutHeader(t *testing.T) {
+	r := newTestRegistry(t, "")
+	r.noDigest = true
+	want := singleImage(t, r, "acme/api", "2025-01-02T03:04:05Z", "amd64", 100, "1.0.0")
+
+	digest, err := r.client(t, false).ResolveDigest(context.Background(), r.image("acme/api:1.0.0"), nil)
+	if err != nil || digest != want {
+		t.Fatalf("got %s (%v), want the hash of the manifest %s", digest, err, want)
+	}
+	if _, err := r.client(t, false).ResolveDigest(context.Background(), r.image("acme/api:2.0.0"), nil); err == nil {
+		t.Fatal("expected an error for an unknown tag")
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	r := newTestRegistry(t, "")
+	single := singleImage(t, r, "acme/api", "2025-01-02T03:04:05Z", "arm64", 100, "1.0.0")
+
+	amd64 := singleImage(t, r, "acme/web", "2025-02-01T00:00:00Z", "amd64", 300)
+	arm64 := singleImage(t, r, "acme/web", "2025-02-01T00:00:00Z", "arm64", 200)
+	index := r.addManifest(t, "acme/web", manifest{
+		MediaType:   "application/vnd.oci.image.index.v1+json",
+		Annotations: map[string]string{"org.opencontainers.image.revision": "abc123"},
+		Manifests: []descriptor{
+			{MediaType: "application/vnd.oci.image.manifest.v1+json", Digest: arm64, Platform: platform("linux", "arm64", "v8")},
+			{MediaType: "application/vnd.oci.image.manifest.v1+json", Digest: amd64, Platform: platform("linux", "amd64", "")},
+			{MediaType: "application/vnd.oci.image.manifest.v1+json", Digest: "sha256:att", Platform: platform("unknown", "unknown", "")},
+		},
+	}, "2.0.0")
+	client := r.client(t, false)
+
+	tests := []struct {
+		name      string
+		image     string
+		digest    string
+		size      int64
+		platforms []string
+		revision  string
+	}{
+		{"manifest", "acme/api:1.0.0", single, 100, []string{"linux/arm64"}, ""},
+		{"manifest by digest", "acme/api@" + single, single, 100, []string{"linux/arm64"}, ""},
+		{"index", "acme/web:2.0.0", index, 300, []string{"linux/arm64/v8", "linux/amd64"}, "abc123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := client.Metadata(context.Background(), r.image(tt.image), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if metadata.Digest != tt.digest || metadata.CompressedSize != tt.size || strings.Join(metadata.Platforms, ",") != strings.Join(tt.platforms, ",") {
+				t.Fatalf("got digest %s, size %d, platforms %v; want %s, %d, %v", metadata.Digest, metadata.CompressedSize, metadata.Platforms, tt.digest, tt.size, tt.platforms)
+			}
+			source := metadata.Source()
+			if metadata.Created == nil || source.Source != "https://github.com/acme/api" || source.Revision != tt.revision {
+				t.Fatalf("unexpected created %v and source %+v", metadata.Created, source)
+			}
+		})
+	}
+}
+
+// TestMetadataKeychains looks up the same private image with several keychains: no keychain's result is served to another one
+func TestMetadataKeychains(t *testing.T) {
+	r := newTestRegistry(t, "basic")
+	want := singleImage(t, r, "acme/api", "2025-01-02T03:04:05Z", "amd64", 100, "1.0.0")
+	enricher := &Enricher{client: r.client(t, false), cacheTTL: time.Minute, cache: NewCache[Metadata](16), slots: make(chan struct{}, 1)}
+
+	valid := Keychain{r.host(): {Username: testUsername, Password: testPassword}}
+	invalid := Keychain{r.host(): {Username: testUsername, Password: "wrong"}}
+	tests := []struct {
+		name     string
+		keychain Keychain
+		wantErr  bool
+	}{
+		{"anonymous", nil, true},
+		{"valid", valid, false},
+		{"invalid", invalid, true},
+		{"valid again", valid, false},
+		{"anonymous again", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := enricher.metadata(r.image("acme/api:1.0.0"), tt.keychain)
+			if tt.wantErr != (err != nil) || (err == nil && metadata.Digest != want) {
+				t.Fatalf("got digest %s (%v), want error=%t", metadata.Digest, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListTags(t *testing.T) {
+	r := newTestRegistry(t, "bearer")
+	r.tags = []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0", "latest"}
+
+	tags, err := r.client(t, true).ListTags(context.Background(), r.image("acme/api:1.0.0"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tags, ",") != strings.Join(r.tags, ",") {
+		t.Fatalf("got %v, want every page %v", tags, r.tags)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{`</v2/library/nginx/tags/list?last=1.25&n=1000>; rel="next"`, "/v2/library/nginx/tags/list?last=1.25&n=1000"},
+		{`<https://registry.example.com/v2/acme/api/tags/list?last=b&n=2>; rel="next"`, "/v2/acme/api/tags/list?last=b&n=2"},
+		{`</v2/acme/api/tags/list?last=b>; rel="prev"`, ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := nextPage(tt.link); got != tt.want {
+			t.Errorf("nextPage(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`)
+	if scheme != "Bearer" || params["realm"] != "https://auth.docker.io/token" || params["service"] != "registry.docker.io" || params["scope"] != "repository:library/nginx:pull" {
+		t.Fatalf("got %s %v", scheme, params)
+	}
+}
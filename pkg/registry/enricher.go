@@ -0,0 +1,252 @@
+package registry
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// lookupTimeout bounds the registry requests of one image
+	lookupTimeout = 30 * time.Second
+	// maxErrorTTL bounds how long a failed lookup is cached, so that a registry outage heals quickly
+	maxErrorTTL = 5 * time.Minute
+)
+
+// MetadataResult is the registry metadata of the image of one container
+type MetadataResult struct {
+	Namespace string                 `json:"namespace"`
+	Kind      string                 `json:"kind"`
+	Workload  string                 `json:"workload"`
+	Container string                 `json:"container"`
+	Image     inventory.Image        `json:"image"`
+	Metadata  *Metadata              `json:"metadata,omitempty"`
+	Source    *inventory.ImageSource `json:"source,omitempty"` // From the image, or the workload annotations (only with sourceInfo)
+	Error     string                 `json:"error,omitempty"`  // Why the lookup failed
+}
+
+// Enricher looks up the images of the inventory in their registries and keeps the metadata metrics up to date
+type Enricher struct {
+	client         *Client
+	store          *inventory.Store
+	clientset      kubernetes.Interface
+	usePullSecrets bool
+	sourceInfo     bool
+	cacheTTL       time.Duration
+	cache          *Cache[Metadata]
+	slots          chan struct{}                 // Limits the lookups in flight
+	createdSeries  *SentinelPrometheus.SeriesSet // By workload key
+	sizeSeries     *SentinelPrometheus.SeriesSet // By workload key
+	sourceSeries   *SentinelPrometheus.SeriesSet // By workload key
+
+	mu          sync.Mutex
+	results     map[string][]MetadataResult // workload key -> results of its containers
+	generations map[string]uint64           // workload key -> evaluation counter, so that a slow lookup never overwrites a newer one
+}
+
+/*
+InitEnricher looks up every workload added or changed in the inventory, and the whole inventory every refreshInterval
+(the cache decides what is actually fetched again: tags expire after cacheTTL, images pinned by digest only get evicted).
+*/
+func InitEnricher(cfg shared.ImageMetadataConfig, client *Client, store *inventory.Store, broker *events.Broker, clientset kubernetes.Interface) *Enricher {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	enricher := &Enricher{
+		client:         client,
+		store:          store,
+		clientset:      clientset,
+		usePullSecrets: cfg.UsePullSecrets,
+		sourceInfo:     cfg.SourceInfo,
+		cacheTTL:       cfg.CacheTTL,
+		cache:          NewCache[Metadata](cfg.CacheSize),
+		slots:          make(chan struct{}, cfg.Concurrency),
+		createdSeries:  SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelImageCreatedTimestamp),
+		sizeSeries:     SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelImageCompressedSize),
+		sourceSeries:   SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelImageSourceInfo),
+		results:        make(map[string][]MetadataResult),
+		generations:    make(map[string]uint64),
+	}
+
+	broker.Consume("image-metadata", 256, func(e events.Event) {
+		go enricher.evaluateWorkload(e.Namespace, e.Kind, e.Workload)
+	})
+	go func() {
+		for {
+			time.Sleep(cfg.RefreshInterval)
+			for _, w := range store.List() {
+				enricher.evaluateWorkload(w.Namespace, w.Kind, w.Name)
+			}
+		}
+	}()
+	return enricher
+}
+
+// EvaluateNamespace evaluates every workload of a namespace, after its initial listing (which publishes no event)
+func (e *Enricher) EvaluateNamespace(namespace string) {
+	for _, w := range e.store.ListNamespace(namespace) {
+		e.evaluateWorkload(w.Namespace, w.Kind, w.Name)
+	}
+}
+
+// evaluateWorkload looks up the images of one workload after a change, or forgets it once deleted
+func (e *Enricher) evaluateWorkload(namespace, kind, name string) {
+	key := inventory.WorkloadKey(namespace, kind, name)
+	e.mu.Lock()
+	e.generations[key]++
+	generation := e.generations[key]
+	e.mu.Unlock()
+
+	var results []MetadataResult
+	w, ok := e.store.Get(namespace, kind, name)
+	if ok {
+		results = e.lookupWorkload(w)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.generations[key] != generation {
+		return // A newer evaluation of the workload started meanwhile
+	}
+	if !ok {
+		delete(e.generations, key)
+	}
+	e.setResults(namespace, kind, name, results)
+}
+
+// lookupWorkload returns the metadata of the images of every container of a workload
+func (e *Enricher) lookupWorkload(w inventory.Workload) []MetadataResult {
+	var keychain Keychain
+	if e.usePullSecrets && len(w.ImagePullSecrets) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+		keychain = PullSecretKeychain(ctx, e.clientset, w.Namespace, w.ImagePullSecrets)
+		cancel()
+	}
+
+	results := make([]MetadataResult, len(w.Containers))
+	var wg sync.WaitGroup
+	for i, c := range w.Containers {
+		results[i] = MetadataResult{Namespace: w.Namespace, Kind: w.Kind, Workload: w.Name, Container: c.Name, Image: c.Image}
+		wg.Add(1)
+		go func(r *MetadataResult, fallback *inventory.ImageSource) {
+			defer wg.Done()
+			metadata, err := e.metadata(r.Image, keychain)
+			if e.sourceInfo {
+				r.Source = e.source(r.Image, metadata, err == nil, fallback)
+			}
+			if err != nil {
+				slog.Debug("Image metadata lookup failed",
+					slog.String("ns/workload", w.Namespace+"/"+w.Name),
+					slog.String("container", r.Container),
+					slog.String("image", r.Image.Reference),
+					slog.Any("error", err))
+				r.Error = err.Error()
+				return
+			}
+			r.Metadata = &metadata
+		}(&results[i], c.Source)
+	}
+	wg.Wait()
+	return results
+}
+
+/*
+source records the OCI source annotations of an image in the inventory, and returns them, or those of the workload
+when the image has none. When the lookup failed, the inventory keeps what it knew.
+*/
+func (e *Enricher) source(image inventory.Image, metadata Metadata, found bool, fallback *inventory.ImageSource) *inventory.ImageSource {
+	var source inventory.ImageSource
+	if found {
+		source = metadata.Source()
+		e.store.SetImageSource(image.Reference, source)
+	}
+	if source.IsZero() && fallback != nil {
+		source = *fallback
+	}
+	if source.IsZero() {
+		return nil
+	}
+	return &source
+}
+
+// metadata returns the metadata of an image from the cache, or from its registry (at most once at a time per image
+// and set of credentials)
+func (e *Enricher) metadata(image inventory.Image, keychain Keychain) (Metadata, error) {
+	key := image.RepositoryKey() + ":" + image.Tag
+	if image.Digest != "" {
+		key = image.RepositoryKey() + "@" + image.Digest
+	}
+	return e.cache.Load(e.client.CacheKey(image, keychain, key), func() (Metadata, error, time.Duration) {
+		e.slots <- struct{}{}
+		defer func() { <-e.slots }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+		defer cancel()
+		metadata, err := e.client.Metadata(ctx, image, keychain)
+		switch {
+		case err != nil:
+			return metadata, err, min(e.cacheTTL, maxErrorTTL)
+		case image.Digest != "":
+			return metadata, nil, 24 * time.Hour * 365 // The content behind a digest never changes: only the LRU evicts it
+		}
+		return metadata, nil, e.cacheTTL
+	})
+}
+
+// setResults replaces the results and the series of a workload. Must be called with the lock held.
+func (e *Enricher) setResults(namespace, kind, name string, results []MetadataResult) {
+	key := inventory.WorkloadKey(namespace, kind, name)
+	created, size, source := e.createdSeries.Update(key), e.sizeSeries.Update(key), e.sourceSeries.Update(key)
+	for _, r := range results {
+		if r.Source != nil {
+			source.Set(1, r.Namespace, r.Kind, r.Workload, r.Container, r.Image.Reference, r.Source.Source, r.Source.Revision, r.Source.Version)
+		}
+		if r.Metadata == nil {
+			continue
+		}
+		labels := []string{r.Namespace, r.Kind, r.Workload, r.Container, r.Image.Reference, r.Metadata.Digest}
+		if r.Metadata.Created != nil {
+			created.Set(float64(r.Metadata.Created.Unix()), labels...)
+		}
+		size.Set(float64(r.Metadata.CompressedSize), labels...)
+	}
+	created.Commit()
+	size.Commit()
+	source.Commit()
+
+	if len(results) == 0 {
+		delete(e.results, key)
+		return
+	}
+	e.results[key] = results
+}
+
+// Results returns the metadata of the images of the containers of a namespace ("" for all), sorted by workload
+func (e *Enricher) Results(namespace string) []MetadataResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	results := []MetadataResult{}
+	for _, workloadResults := range e.results {
+		for _, r := range workloadResults {
+			if namespace == "" || r.Namespace == namespace {
+				results = append(results, r)
+			}
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		ki := inventory.WorkloadKey(results[i].Namespace, results[i].Kind, results[i].Workload) + "/" + results[i].Container
+		kj := inventory.WorkloadKey(results[j].Namespace, results[j].Kind, results[j].Workload) + "/" + results[j].Container
+		return ki < kj
+	})
+	return results
+}
@@ -0,0 +1,211 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+)
+
+// ErrNotFound is returned for a manifest the registry doesn't have
+var ErrNotFound = errors.New("manifest unknown")
+
+// Metadata is what the registry knows about an image
+type Metadata struct {
+	Digest         string            `json:"digest"`                // Digest of the manifest, or of the image index for multi-platform images
+	Created        *time.Time        `json:"created,omitempty"`     // Creation date from the image config
+	CompressedSize int64             `json:"compressedSize"`        // Sum of the compressed layers (of the linux/amd64 image for an index)
+	Platforms      []string          `json:"platforms,omitempty"`   // e.g. ["linux/amd64", "linux/arm64/v8"]
+	Labels         map[string]string `json:"labels,omitempty"`      // Labels of the image config (LABEL instructions)
+	Annotations    map[string]string `json:"annotations,omitempty"` // Annotations of the manifest (merged with those of the index)
+}
+
+// Source returns the OCI source annotations of the image, the manifest annotations winning over the config labels
+func (m Metadata) Source() inventory.ImageSource {
+	return inventory.SourceFromAnnotations(m.Annotations, m.Labels)
+}
+
+// descriptor points to a manifest or a blob
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+		Variant      string `json:"variant"`
+	} `json:"platform,omitempty"`
+}
+
+// manifest is an image manifest or an image index (manifest list), whichever the registry served
+type manifest struct {
+	MediaType   string            `json:"mediaType"`
+	Annotations map[string]string `json:"annotations"`
+	Manifests   []descriptor      `json:"manifests"` // Image index
+	Config      descriptor        `json:"config"`    // Image manifest
+	Layers      []descriptor      `json:"layers"`    // Image manifest
+}
+
+// imageConfig holds the fields of an image config Sentinel reads
+type imageConfig struct {
+	Created      *time.Time `json:"created"`
+	OS           string     `json:"os"`
+	Architecture string     `json:"architecture"`
+	Variant      string     `json:"variant"`
+	Config       struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
+// isIndex reports whether a manifest is an image index
+func (m manifest) isIndex(contentType string) bool {
+	mediaType := m.MediaType
+	if mediaType == "" {
+		mediaType = contentType
+	}
+	return strings.Contains(mediaType, "index") || strings.Contains(mediaType, "manifest.list") || len(m.Manifests) > 0
+}
+
+/*
+Metadata reads the metadata of an image: its manifest (by digest when the image is pinned, by tag otherwise), then its config.
+For a multi-platform image, the size, date and labels are those of the linux/amd64 image, or of the first one listed.
+*/
+func (c *Client) Metadata(ctx context.Context, image inventory.Image, keychain Keychain) (Metadata, error) {
+	reference := image.Digest
+	if reference == "" {
+		reference = image.Tag
+	}
+	host, repository := apiHost(image.Registry), apiRepository(image)
+	creds := c.credentialsFor(host, keychain)
+
+	top, digest, contentType, err := c.manifest(ctx, host, repository, reference, creds)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("%s: %w", image.Reference, err)
+	}
+	metadata := Metadata{Digest: digest, Annotations: top.Annotations}
+
+	m := top
+	if top.isIndex(contentType) {
+		var chosen *descriptor
+		for i, d := range top.Manifests {
+			if d.Platform == nil || d.Platform.OS == "unknown" { // Attestations are stored as unknown/unknown images
+				continue
+			}
+			platform := d.Platform.OS + "/" + d.Platform.Architecture
+			if d.Platform.Variant != "" {
+				platform += "/" + d.Platform.Variant
+			}
+			metadata.Platforms = append(metadata.Platforms, platform)
+			if chosen == nil || (d.Platform.OS == "linux" && d.Platform.Architecture == "amd64" && chosen.Platform.Architecture != "amd64") {
+				chosen = &top.Manifests[i]
+			}
+		}
+		if chosen == nil {
+			return metadata, nil
+		}
+		if m, _, _, err = c.manifest(ctx, host, repository, chosen.Digest, creds); err != nil {
+			return Metadata{}, fmt.Errorf("%s: %w", image.Reference, err)
+		}
+		if len(m.Annotations) > 0 {
+			merged := make(map[string]string, len(top.Annotations)+len(m.Annotations))
+			maps.Copy(merged, top.Annotations)
+			maps.Copy(merged, m.Annotations) // The image of the platform knows best
+			metadata.Annotations = merged
+		}
+	}
+
+	for _, layer := range m.Layers {
+		metadata.CompressedSize += layer.Size
+	}
+	if m.Config.Digest == "" {
+		return metadata, nil
+	}
+
+	var config imageConfig
+	if err := c.blob(ctx, host, repository, m.Config.Digest, creds, &config); err != nil {
+		return Metadata{}, fmt.Errorf("%s: %w", image.Reference, err)
+	}
+	metadata.Created, metadata.Labels = config.Created, config.Config.Labels
+	if len(metadata.Platforms) == 0 && config.OS != "" {
+		platform := config.OS + "/" + config.Architecture
+		if config.Variant != "" {
+			platform += "/" + config.Variant
+		}
+		metadata.Platforms = []string{platform}
+	}
+	return metadata, nil
+}
+
+// manifest fetches a manifest by tag or digest, and returns it with its digest and content type
+func (c *Client) manifest(ctx context.Context, host, repository, reference string, creds *Credentials) (manifest, string, string, error) {
+	path := "/v2/" + repository + "/manifests/" + url.PathEscape(reference)
+	resp, err := c.do(ctx, http.MethodGet, host, repository, path, strings.Join(manifestMediaTypes, ", "), creds)
+	if err != nil {
+		return manifest{}, "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return manifest{}, "", "", fmt.Errorf("manifest %s: %w", reference, ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return manifest{}, "", "", fmt.Errorf("manifest %s: registry answered %s", reference, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes))
+	if err != nil {
+		return manifest{}, "", "", err
+	}
+
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return manifest{}, "", "", fmt.Errorf("manifest %s: %w", reference, err)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		sum := sha256.Sum256(body)
+		digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return m, digest, resp.Header.Get("Content-Type"), nil
+}
+
+// blob fetches a JSON blob by digest
+func (c *Client) blob(ctx context.Context, host, repository, digest string, creds *Credentials, into any) error {
+	data, err := c.blobBytes(ctx, host, repository, digest, creds)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, into); err != nil {
+		return fmt.Errorf("blob %s: %w", digest, err)
+	}
+	return nil
+}
+
+// blobBytes fetches a small blob by digest (registries often redirect to a storage bucket, followed by the HTTP client)
+func (c *Client) blobBytes(ctx context.Context, host, repository, digest string, creds *Credentials) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, host, repository, "/v2/"+repository+"/blobs/"+digest, "*/*", creds)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("blob %s: registry answered %s", digest, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxManifestBytes {
+		return nil, fmt.Errorf("blob %s: larger than %d bytes", digest, maxManifestBytes)
+	}
+	return data, nil
+}
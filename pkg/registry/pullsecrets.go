@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Keychain holds credentials by registry API host, e.g. those of the imagePullSecrets of a workload
+type Keychain map[string]Credentials
+
+// dockerAuth is an entry of the auths of a Docker config file
+type dockerAuth struct {
+	Auth     string `json:"auth"` // base64("username:password")
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+/*
+PullSecretKeychain reads the imagePullSecrets of a workload, the same way the kubelet does:
+kubernetes.io/dockerconfigjson and legacy kubernetes.io/dockercfg secrets. Missing or invalid secrets are skipped.
+*/
+func PullSecretKeychain(ctx context.Context, clientset kubernetes.Interface, namespace string, names []string) Keychain {
+	keychain := Keychain{}
+	for _, name := range names {
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			continue
+		}
+		var auths map[string]dockerAuth
+		switch secret.Type {
+		case corev1.SecretTypeDockerConfigJson:
+			var config struct {
+				Auths map[string]dockerAuth `json:"auths"`
+			}
+			err = json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config)
+			auths = config.Auths
+		case corev1.SecretTypeDockercfg:
+			err = json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths)
+		default:
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		for server, auth := range auths {
+			creds, err := auth.credentials()
+			if err != nil {
+				continue
+			}
+			// The first secret listed wins, as for the kubelet
+			if host := apiHost(registryHost(server)); keychain[host] == (Credentials{}) {
+				keychain[host] = creds
+			}
+		}
+	}
+	return keychain
+}
+
+// credentials decodes the username and password of an entry
+func (a dockerAuth) credentials() (Credentials, error) {
+	if a.Auth == "" {
+		return Credentials{Username: a.Username, Password: a.Password}, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+	if err != nil {
+		return Credentials{}, err
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return Credentials{}, fmt.Errorf("invalid auth")
+	}
+	return Credentials{Username: username, Password: password}, nil
+}
+
+// registryHost extracts the host of a Docker config server key: "https://index.docker.io/v1/" -> "index.docker.io"
+func registryHost(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	host, _, _ := strings.Cut(server, "/")
+	return host
+}
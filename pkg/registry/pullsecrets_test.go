@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPullSecretKeychain(t *testing.T) {
+	auth := func(username, password string) string {
+		return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	}
+	secret := func(name string, secretType corev1.SecretType, key, data string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "prod"},
+			Type:       secretType,
+			Data:       map[string][]byte{key: []byte(data)},
+		}
+	}
+	clientset := fake.NewClientset(
+		secret("hub", corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey,
+			`{"auths": {"https://index.docker.io/v1/": {"auth": "`+auth("hub-user", "hub-pass")+`"}, "ghcr.io": {"username": "gh-user", "password": "gh-pass"}}}`),
+		secret("ghcr-other", corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey,
+			`{"auths": {"ghcr.io": {"auth": "`+auth("other", "other")+`"}}}`),
+		secret("legacy", corev1.SecretTypeDockercfg, corev1.DockerConfigKey,
+			`{"registry.example.com:5000": {"auth": "`+auth("legacy-user", "legacy-pass")+`"}}`),
+		secret("opaque", corev1.SecretTypeOpaque, "token", "x"),
+		secret("broken", corev1.SecretTypeDockerConfigJson, corev1.DockerConfigJsonKey, "{"),
+	)
+
+	keychain := PullSecretKeychain(context.Background(), clientset, "prod", []string{"missing", "broken", "opaque", "hub", "ghcr-other", "legacy"})
+	want := Keychain{
+		"registry-1.docker.io":      {Username: "hub-user", Password: "hub-pass"},
+		"ghcr.io":                   {Username: "gh-user", Password: "gh-pass"}, // The first secret listed wins
+		"registry.example.com:5000": {Username: "legacy-user", Password: "legacy-pass"},
+	}
+	if len(keychain) != len(want) {
+		t.Fatalf("got %v, want %v", keychain, want)
+	}
+	for host, creds := range want {
+		if keychain[host] != creds {
+			t.Fatalf("got %v for %s, want %v", keychain[host], host, creds)
+		}
+	}
+
+	if keychain := PullSecretKeychain(context.Background(), clientset, "dev", []string{"hub"}); len(keychain) != 0 {
+		t.Fatalf("secret of another namespace read: %v", keychain)
+	}
+}
@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+)
+
+const (
+	// tagsPageSize is the number of tags asked for per page
+	tagsPageSize = 1000
+	// maxTagPages bounds the pages read for one repository, so that a repository with a tag per commit can't exhaust a rate limit
+	maxTagPages = 20
+)
+
+// ListTags returns the tags of the repository of an image, following the pagination of the registry
+func (c *Client) ListTags(ctx context.Context, image inventory.Image, keychain Keychain) ([]string, error) {
+	host, repository := apiHost(image.Registry), apiRepository(image)
+	creds := c.credentialsFor(host, keychain)
+
+	var tags []string
+	path := "/v2/" + repository + "/tags/list?n=" + fmt.Sprint(tagsPageSize)
+	for page := 0; page < maxTagPages && path != ""; page++ {
+		resp, err := c.do(ctx, http.MethodGet, host, repository, path, "application/json", creds)
+		if err != nil {
+			return nil, err
+		}
+		var body struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(io.LimitReader(resp.Body, maxManifestBytes)).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: listing tags: registry answered %s", image.RepositoryKey(), resp.Status)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: listing tags: %w", image.RepositoryKey(), err)
+		}
+		tags = append(tags, body.Tags...)
+		path = nextPage(resp.Header.Get("Link"))
+	}
+	return tags, nil
+}
+
+// nextPage extracts the path of the next page from a Link header: `</v2/library/nginx/tags/list?last=1.25&n=1000>; rel="next"`
+func nextPage(link string) string {
+	target, params, ok := strings.Cut(link, ";")
+	if !ok || !strings.Contains(params, `rel="next"`) {
+		return ""
+	}
+	target = strings.Trim(strings.TrimSpace(target), "<>")
+	u, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	// The link is relative to the registry, even when some registries write it absolute
+	return u.RequestURI()
+}
@@ -0,0 +1,262 @@
+/*
+Reloadable configuration data.
+
+SCOPE:
+- Hand the content of a file, or of a ConfigMap key, to a callback when Sentinel starts and every time it changes
+- Same for sets of files: the files of a directory, or the keys of the ConfigMaps matching a label selector
+- Files are polled (comparing a hash of their content): this survives the symlink swaps of mounted ConfigMaps,
+  which file system notifications don't
+- ConfigMaps are watched with an informer restricted to the one object (or to the label selector)
+*/
+
+package reload
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ApplyFunc receives the new content. An error keeps the previous content in use.
+type ApplyFunc func(data []byte) error
+
+/*
+WatchFile applies the content of a file now, then polls it every interval and applies it again when it changed.
+The first load is synchronous and its error is returned; later errors are logged.
+*/
+func WatchFile(name, path string, interval time.Duration, apply ApplyFunc) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	if err := apply(data); err != nil {
+		return fmt.Errorf("invalid %s %s: %w", name, path, err)
+	}
+	last := sha256.Sum256(data)
+	slog.Info("Loaded "+name, slog.String("path", path))
+
+	go func() {
+		for {
+			time.Sleep(interval)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				slog.Error("Unable to read "+name, slog.String("path", path), slog.Any("error", err))
+				continue
+			}
+			sum := sha256.Sum256(data)
+			if sum == last {
+				continue
+			}
+			if err := apply(data); err != nil {
+				slog.Error("Invalid "+name+", keeping the previous one", slog.String("path", path), slog.Any("error", err))
+			} else {
+				slog.Info("Reloaded "+name, slog.String("path", path))
+			}
+			last = sum // Don't retry an invalid content until it changes again
+		}
+	}()
+	return nil
+}
+
+/*
+WatchConfigMap applies the value of a ConfigMap key every time the ConfigMap is created or updated.
+A deletion is logged and the previous content kept in use, until the ConfigMap is created again.
+*/
+func WatchConfigMap(name string, clientset kubernetes.Interface, namespace, configMap, key string, apply ApplyFunc) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", configMap).String()
+		}),
+	)
+
+	var last [sha256.Size]byte
+	handle := func(obj interface{}) {
+		cm, ok := obj.(*corev1.ConfigMap)
+		if !ok {
+			return
+		}
+		data, found := cm.Data[key]
+		if !found {
+			slog.Error("ConfigMap key not found", slog.String("configmap", namespace+"/"+configMap), slog.String("key", key))
+			return
+		}
+		sum := sha256.Sum256([]byte(data))
+		if sum == last {
+			return
+		}
+		last = sum
+		if err := apply([]byte(data)); err != nil {
+			slog.Error("Invalid "+name+", keeping the previous one", slog.String("configmap", namespace+"/"+configMap), slog.Any("error", err))
+			return
+		}
+		slog.Info("Loaded "+name, slog.String("configmap", namespace+"/"+configMap), slog.String("key", key))
+	}
+
+	factory.Core().V1().ConfigMaps().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    handle,
+		UpdateFunc: func(oldObj, newObj interface{}) { handle(newObj) },
+		DeleteFunc: func(obj interface{}) {
+			slog.Warn("ConfigMap deleted, keeping the previous "+name, slog.String("configmap", namespace+"/"+configMap))
+			last = [sha256.Size]byte{} // Apply the ConfigMap again once recreated, even with the same content
+		},
+	})
+	factory.Start(make(chan struct{}))
+}
+
+// ApplyFilesFunc receives the new content of a set of files, by name. An error keeps the previous content in use.
+type ApplyFilesFunc func(files map[string][]byte) error
+
+/*
+WatchDir applies the files of a directory now, then polls it every interval and applies them again when any of them
+was added, changed or removed. Hidden files (like the "..data" links of a mounted ConfigMap) and subdirectories are skipped.
+The first load is synchronous and its error is returned; later errors are logged.
+*/
+func WatchDir(name, dir string, interval time.Duration, apply ApplyFilesFunc) error {
+	files, err := readDir(dir)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", dir, err)
+	}
+	if err := apply(files); err != nil {
+		return fmt.Errorf("invalid %s %s: %w", name, dir, err)
+	}
+	last := hashFiles(files)
+	slog.Info("Loaded "+name, slog.String("path", dir), slog.Int("files", len(files)))
+
+	go func() {
+		for {
+			time.Sleep(interval)
+			files, err := readDir(dir)
+			if err != nil {
+				slog.Error("Unable to read "+name, slog.String("path", dir), slog.Any("error", err))
+				continue
+			}
+			sum := hashFiles(files)
+			if sum == last {
+				continue
+			}
+			if err := apply(files); err != nil {
+				slog.Error("Invalid "+name+", keeping the previous one", slog.String("path", dir), slog.Any("error", err))
+			} else {
+				slog.Info("Reloaded "+name, slog.String("path", dir), slog.Int("files", len(files)))
+			}
+			last = sum
+		}
+	}()
+	return nil
+}
+
+/*
+WatchConfigMaps applies the keys of every ConfigMap matching a label selector (named "<namespace>/<configmap>/<key>")
+once they are listed, then again every time one of them is created, updated or deleted.
+*/
+func WatchConfigMaps(name string, clientset kubernetes.Interface, namespace, labelSelector string, apply ApplyFilesFunc) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = labelSelector
+		}),
+	)
+	informer := factory.Core().V1().ConfigMaps().Informer()
+
+	// Events are coalesced: the initial list, or a burst of updates, leads to a single apply
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { notify() },
+		DeleteFunc: func(obj interface{}) { notify() },
+	})
+
+	stopCh := make(chan struct{})
+	factory.Start(stopCh)
+
+	go func() {
+		cache.WaitForCacheSync(stopCh, informer.HasSynced)
+		notify() // Apply even when no ConfigMap matches yet
+
+		var last [sha256.Size]byte
+		for range changed {
+			files := make(map[string][]byte)
+			for _, obj := range informer.GetStore().List() {
+				cm, ok := obj.(*corev1.ConfigMap)
+				if !ok {
+					continue
+				}
+				for key, value := range cm.Data {
+					files[cm.Namespace+"/"+cm.Name+"/"+key] = []byte(value)
+				}
+			}
+			sum := hashFiles(files)
+			if sum == last {
+				continue
+			}
+			last = sum
+			if err := apply(files); err != nil {
+				slog.Error("Invalid "+name+", keeping the previous one", slog.String("selector", labelSelector), slog.Any("error", err))
+				continue
+			}
+			slog.Info("Loaded "+name, slog.String("namespace", namespace), slog.String("selector", labelSelector), slog.Int("files", len(files)))
+		}
+	}()
+}
+
+// readDir reads the regular, non hidden files of a directory (following symlinks)
+func readDir(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files[entry.Name()] = data
+	}
+	return files, nil
+}
+
+// hashFiles hashes the names and contents of a set of files, in a stable order
+func hashFiles(files map[string][]byte) [sha256.Size]byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(files[name]))
+		h.Write(files[name])
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
@@ -23,6 +23,9 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/manifests"
 	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
 	SentinelShared "github.com/MatteoMori/sentinel/pkg/shared"
 	appsv1 "k8s.io/api/apps/v1"
@@ -33,6 +36,21 @@ import (
 	"k8s.io/client-go/tools/cache"
 )
 
+// workloadInventory is the in-memory inventory fed by the informers below and served by the Sentinel API
+var workloadInventory = inventory.NewStore()
+
+// eventBroker distributes the image change events detected below. It is created by Start().
+var eventBroker *events.Broker
+
+// watchedNamespaces is the set of namespaces AppDiscovery currently runs informers for
+var watchedNamespaces sync.Map
+
+// isWatchedNamespace reports whether the workloads of a namespace are observed
+func isWatchedNamespace(namespace string) bool {
+	_, ok := watchedNamespaces.Load(namespace)
+	return ok
+}
+
 // NamespaceInformer keeps track of an informer and its stop channel for a namespace.
 type NamespaceInformer struct {
 	StopCh  chan struct{}
@@ -49,6 +67,7 @@ func AppDiscovery(
 
 	var mu sync.Mutex
 	activeInformers := make(map[string]*NamespaceInformer)
+	initialBatch := true // The first list of namespaces: the inventory synced hooks run once all of them are listed
 
 	for namespaces := range nsChannel {
 		slog.Debug("Received namespaces", slog.Any("Namespaces", strings.Join(namespaces, ", ")))
@@ -85,10 +104,10 @@ func AppDiscovery(
 				/*
 					Sentinel - Observe Deployments
 				*/
-				DeploymentInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-					AddFunc: func(obj interface{}) {
+				DeploymentInformer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+					AddFunc: func(obj interface{}, isInInitialList bool) {
 						deploy := obj.(*appsv1.Deployment)
-						handleWorkloadAdd("Deployment", nsCopy, deploy, deploy.Spec.Template.Spec.Containers, sentinelConfig.ExtraLabels)
+						handleWorkloadAdd("Deployment", nsCopy, deploy, deploy.Spec.Template.Spec.Containers, sentinelConfig.ExtraLabels, isInInitialList)
 					},
 					UpdateFunc: func(oldObj, newObj interface{}) {
 						oldDeploy := oldObj.(*appsv1.Deployment)
@@ -117,10 +136,10 @@ func AppDiscovery(
 				/*
 					Sentinel - Observe Statefulsets
 				*/
-				StatefulsetsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-					AddFunc: func(obj interface{}) {
+				StatefulsetsInformer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+					AddFunc: func(obj interface{}, isInInitialList bool) {
 						statefulset := obj.(*appsv1.StatefulSet)
-						handleWorkloadAdd("StatefulSet", nsCopy, statefulset, statefulset.Spec.Template.Spec.Containers, sentinelConfig.ExtraLabels)
+						handleWorkloadAdd("StatefulSet", nsCopy, statefulset, statefulset.Spec.Template.Spec.Containers, sentinelConfig.ExtraLabels, isInInitialList)
 					},
 					UpdateFunc: func(oldObj, newObj interface{}) {
 						oldStatefulSet := oldObj.(*appsv1.StatefulSet)
@@ -149,10 +168,10 @@ func AppDiscovery(
 				/*
 					Sentinel - Observe Daemonsets
 				*/
-				DaemonsetsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-					AddFunc: func(obj interface{}) {
+				DaemonsetsInformer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+					AddFunc: func(obj interface{}, isInInitialList bool) {
 						daemonset := obj.(*appsv1.DaemonSet)
-						handleWorkloadAdd("DaemonSet", nsCopy, daemonset, daemonset.Spec.Template.Spec.Containers, sentinelConfig.ExtraLabels)
+						handleWorkloadAdd("DaemonSet", nsCopy, daemonset, daemonset.Spec.Template.Spec.Containers, sentinelConfig.ExtraLabels, isInInitialList)
 					},
 					UpdateFunc: func(oldObj, newObj interface{}) {
 						oldDaemonSet := oldObj.(*appsv1.DaemonSet)
@@ -177,12 +196,34 @@ func AppDiscovery(
 						}
 					},
 				})
+
+				// Previous images, from the ReplicaSets and ControllerRevisions of the workloads above
+				if sentinelConfig.Backfill.Enabled {
+					watchRevisions(factory, nsCopy)
+				}
+
 				go factory.Start(stopCh)
+				go func() {
+					// Only the workloads gate the checkers: a revision informer that can't list (e.g. an older ClusterRole) only loses the backfill
+					if !cache.WaitForCacheSync(stopCh, DeploymentInformer.HasSynced, StatefulsetsInformer.HasSynced, DaemonsetsInformer.HasSynced) {
+						return // Stopped before the end of the initial listing
+					}
+					namespaceSynced(nsCopy)
+				}()
 				activeInformers[ns] = &NamespaceInformer{
 					StopCh:  stopCh,
 					Factory: factory,
 				}
+				watchedNamespaces.Store(ns, struct{}{})
+			}
+		}
+		if initialBatch {
+			initialBatch = false
+			initial := make([]*NamespaceInformer, 0, len(activeInformers))
+			for _, informer := range activeInformers {
+				initial = append(initial, informer)
 			}
+			go inventorySynced(initial)
 		}
 
 		// Stop informers for namespaces that are no longer present
@@ -191,24 +232,47 @@ func AppDiscovery(
 				slog.Info("Stopping Deployment informer for namespace", slog.String("Namespace", ns))
 				close(informer.StopCh)
 				delete(activeInformers, ns)
+				watchedNamespaces.Delete(ns)
+				forgetNamespace(ns)
 			}
 		}
 		mu.Unlock()
 	}
 }
 
-func handleWorkloadAdd(resourceType, namespace string, workload metav1.Object, containers []corev1.Container, extraLabels []SentinelShared.ExtraLabel) {
+/*
+handleWorkloadAdd records a workload the informers listed or saw created. The workloads of the initial listing of a
+namespace were already running: they get no image.added event (every restart of Sentinel would replay the whole cluster
+to the subscribers), the checkers evaluate them through the namespace synced hooks instead.
+*/
+func handleWorkloadAdd(resourceType, namespace string, workload metav1.Object, containers []corev1.Container, extraLabels []SentinelShared.ExtraLabel, initialList bool) {
 	slog.Debug("New workload identified",
 		slog.String("type", resourceType),
 		slog.String("ns/name", namespace+"/"+workload.GetName()))
 
 	// Extract extra label values from the workload
-	extraLabelValues := extractExtraLabelValues(workload, extraLabels)
+	extraLabelValues := manifests.ExtraLabelValues(workload, extraLabels)
 
 	// Process each container and set metrics
 	for _, container := range containers {
 		setContainerMetric(resourceType, namespace, workload.GetName(), container, extraLabelValues)
 	}
+
+	record := manifests.InventoryRecord(resourceType, namespace, workload, containers, extraLabels, extraLabelValues)
+	previous, existed := workloadInventory.Upsert(record)
+	evaluatePolicies(record, workload, containers)
+	if initialList {
+		return
+	}
+
+	// Announce the containers we didn't know about yet
+	// (informers re-list every workload when the watch of a namespace is restarted)
+	for _, c := range record.Containers {
+		if existed && containerIndex(previous.Containers, c.Name) >= 0 {
+			continue
+		}
+		publishImageEvent(events.ImageAdded, record, c.Name, nil, &c.Image)
+	}
 }
 
 func handleWorkloadUpdate(resourceType, namespace string, newWorkload metav1.Object, oldGen, newGen int64, newContainers []corev1.Container, oldContainers []corev1.Container, extraLabels []SentinelShared.ExtraLabel) {
@@ -218,7 +282,12 @@ func handleWorkloadUpdate(resourceType, namespace string, newWorkload metav1.Obj
 			slog.String("ns/name", namespace+"/"+newWorkload.GetName()))
 
 		// Extract extra label values from the workload
-		extraLabelValues := extractExtraLabelValues(newWorkload, extraLabels)
+		extraLabelValues := manifests.ExtraLabelValues(newWorkload, extraLabels)
+		record := manifests.InventoryRecord(resourceType, namespace, newWorkload, newContainers, extraLabels, extraLabelValues)
+
+		// Update the inventory first: event subscribers may look the workload up
+		workloadInventory.Upsert(record)
+		evaluatePolicies(record, newWorkload, newContainers)
 
 		// Build maps of old container images for comparison
 		oldImages := make(map[string]string) // containerName -> image
@@ -233,8 +302,9 @@ func handleWorkloadUpdate(resourceType, namespace string, newWorkload metav1.Obj
 			// Check if this container's image changed
 			if oldImage, existed := oldImages[newContainer.Name]; existed && oldImage != newContainer.Image {
 				// Image changed! Track it
-				_, _, oldTag := parseImage(oldImage)
-				_, _, newTag := parseImage(newContainer.Image)
+				oldRef := inventory.ParseImage(oldImage)
+				newRef := inventory.ParseImage(newContainer.Image)
+				oldTag, newTag := oldRef.Tag, newRef.Tag
 
 				slog.Info("Image change detected",
 					slog.String("workload", namespace+"/"+newWorkload.GetName()),
@@ -251,6 +321,20 @@ func handleWorkloadUpdate(resourceType, namespace string, newWorkload metav1.Obj
 					oldTag,
 					newTag,
 				).Inc()
+
+				// Let subscribers (SSE clients, gRPC watchers, ...) know about it
+				publishImageEvent(events.ImageChanged, record, newContainer.Name, &oldRef, &newRef)
+			} else if !existed {
+				newRef := inventory.ParseImage(newContainer.Image)
+				publishImageEvent(events.ImageAdded, record, newContainer.Name, nil, &newRef)
+			}
+		}
+
+		// Containers that are gone from the pod template
+		for _, oldContainer := range oldContainers {
+			if containerIndex(record.Containers, oldContainer.Name) < 0 {
+				oldRef := inventory.ParseImage(oldContainer.Image)
+				publishImageEvent(events.ImageRemoved, record, oldContainer.Name, &oldRef, nil)
 			}
 		}
 	}
@@ -261,12 +345,85 @@ func handleWorkloadDelete(resourceType, namespace, name string, containers []cor
 		slog.String("type", resourceType),
 		slog.String("ns/name", namespace+"/"+name))
 
+	record, existed := workloadInventory.Delete(namespace, resourceType, name)
+	forgetPolicies(namespace, resourceType, name)
+	if !existed {
+		record = inventory.Workload{Namespace: namespace, Kind: resourceType, Name: name}
+		for _, container := range containers {
+			record.Containers = append(record.Containers, inventory.Container{Name: container.Name, Image: inventory.ParseImage(container.Image)})
+		}
+	}
+	for _, c := range record.Containers {
+		publishImageEvent(events.ImageRemoved, record, c.Name, &c.Image, nil)
+	}
+
 	// TODO: Delete Prometheus metrics for this workload
 	// This is tricky because we need to track which label combinations exist
 	// For now, metrics will persist (which is acceptable - they'll just stop updating)
 	// A proper implementation would require maintaining a registry of active metrics
 }
 
+// namespaceSyncedHooks are called once the initial listing of the workloads of a namespace is done
+var namespaceSyncedHooks []func(namespace string)
+
+// onNamespaceSynced registers a hook evaluating the workloads of a namespace after its initial listing. Must be called before AppDiscovery.
+func onNamespaceSynced(hook func(namespace string)) {
+	namespaceSyncedHooks = append(namespaceSyncedHooks, hook)
+}
+
+// namespaceSynced runs the namespace synced hooks
+func namespaceSynced(namespace string) {
+	slog.Debug("Initial listing of the namespace done", slog.String("Namespace", namespace))
+	for _, hook := range namespaceSyncedHooks {
+		go hook(namespace)
+	}
+}
+
+// inventorySyncedHooks are called once the namespaces watched at startup are all listed
+var inventorySyncedHooks []func()
+
+// onInventorySynced registers a hook run once the inventory holds every workload running at startup. Must be called before AppDiscovery.
+func onInventorySynced(hook func()) {
+	inventorySyncedHooks = append(inventorySyncedHooks, hook)
+}
+
+// inventorySynced waits for the initial listing of the namespaces watched at startup, then runs the inventory synced hooks
+func inventorySynced(initial []*NamespaceInformer) {
+	for _, informer := range initial {
+		for _, synced := range informer.Factory.WaitForCacheSync(informer.StopCh) {
+			if !synced {
+				return // A namespace stopped matching before the end of its listing: the next restart will reconcile
+			}
+		}
+	}
+	slog.Info("Initial listing of the watched namespaces done", slog.Int("workloads", len(workloadInventory.List())))
+	for _, hook := range inventorySyncedHooks {
+		go hook()
+	}
+}
+
+// forgetNamespace removes the workloads of a namespace no longer watched from the inventory, as if they were deleted
+func forgetNamespace(namespace string) {
+	for _, w := range workloadInventory.ListNamespace(namespace) {
+		handleWorkloadDelete(w.Kind, w.Namespace, w.Name, nil)
+	}
+}
+
+// publishImageEvent sends an event about one container of a workload to the subscribers of the event broker
+func publishImageEvent(eventType events.Type, record inventory.Workload, container string, oldImage, newImage *inventory.Image) {
+	eventBroker.Publish(events.Event{
+		Type:            eventType,
+		Namespace:       record.Namespace,
+		Kind:            record.Kind,
+		Workload:        record.Name,
+		Container:       container,
+		ResourceVersion: record.ResourceVersion,
+		OldImage:        oldImage,
+		NewImage:        newImage,
+		ExtraLabels:     record.ExtraLabels,
+	})
+}
+
 // setContainerMetric sets the Prometheus metric for a container image
 // It parses the image string and combines base labels with extra labels
 func setContainerMetric(workloadType, namespace, workloadName string, container corev1.Container, extraLabelValues []string) {
@@ -3,11 +3,9 @@ package sentinel
 import (
 	"log/slog"
 	"os"
-	"strings"
 
-	SentinelShared "github.com/MatteoMori/sentinel/pkg/shared"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // namespaceMatchesSelector checks if a namespace has all the required labels with correct values
@@ -20,6 +18,22 @@ func namespaceMatchesSelector(ns *v1.Namespace, selector map[string]string) bool
 	return true
 }
 
+/*
+namespaceLabelSelector builds a label selector string from the namespace selector map
+You need to build this string: "sentinel.io/controlled=enabled"
+If the map had multiple entries, you'd need: "key1=value1,key2=value2"
+*/
+func namespaceLabelSelector(selector map[string]string) string {
+	var labelSelector string
+	for key, value := range selector {
+		if labelSelector != "" {
+			labelSelector += "," // Add comma separator for multiple labels
+		}
+		labelSelector += key + "=" + value
+	}
+	return labelSelector
+}
+
 // setupLogging configures the logging level based on the verbosity setting.
 func setupLogging(verbosity int) {
 	var level slog.Level
@@ -34,8 +48,9 @@ func setupLogging(verbosity int) {
 		level = slog.LevelInfo
 	}
 
+	// Logs go to stderr: stdout is left to the audit log (audit.output: stdout), one JSON record per line
 	handler := slog.NewTextHandler(
-		os.Stdout,
+		os.Stderr,
 		&slog.HandlerOptions{
 			Level:     level,
 			AddSource: true,
@@ -54,70 +69,29 @@ func slicePurge(slice []string, item string) []string {
 	return slice // Return the original slice if the item was not found
 }
 
-/*
-extractExtraLabelValues extracts label/annotation values from a Kubernetes object based on configuration
-- Returns a slice of values in the same order as the extraLabels config
-- If a label/annotation is not found, an empty string is used
-*/
-func extractExtraLabelValues(obj metav1.Object, extraLabels []SentinelShared.ExtraLabel) []string {
-	values := make([]string, len(extraLabels))
-
-	for i, extractor := range extraLabels {
-		var value string
-
-		switch extractor.Type {
-		case "annotation":
-			if obj.GetAnnotations() != nil {
-				value = obj.GetAnnotations()[extractor.Key]
-			}
-		case "label":
-			if obj.GetLabels() != nil {
-				value = obj.GetLabels()[extractor.Key]
-			}
-		default:
-			slog.Warn("Unknown extraLabel type, skipping",
-				slog.String("type", extractor.Type),
-				slog.String("key", extractor.Key))
-		}
-
-		// Use empty string if not found (Prometheus requires all series to have same label set)
-		values[i] = value
-	}
-
-	return values
-}
-
 // parseImage splits a container image string into registry, repository, and tag components
 // Example: "ghcr.io/myorg/myapp:v1.2.3" -> ("ghcr.io", "myorg/myapp", "v1.2.3")
 func parseImage(image string) (registry, repository, tag string) {
-	// Default tag if not specified
-	tag = "latest"
-
-	// Split by tag separator ':'
-	parts := strings.SplitN(image, ":", 2)
-	imagePath := parts[0]
-	if len(parts) == 2 {
-		tag = parts[1]
-	}
+	ref := inventory.ParseImage(image)
+	return ref.Registry, ref.Repository, ref.Tag
+}
 
-	// Split image path into registry and repository
-	// If there's no '/', assume it's docker.io (Docker Hub)
-	pathParts := strings.SplitN(imagePath, "/", 2)
-	if len(pathParts) == 1 {
-		// No registry specified, default to docker.io
-		registry = "docker.io"
-		repository = pathParts[0]
-	} else {
-		// Check if first part looks like a registry (has '.' or ':' or is 'localhost')
-		if strings.Contains(pathParts[0], ".") || strings.Contains(pathParts[0], ":") || pathParts[0] == "localhost" {
-			registry = pathParts[0]
-			repository = pathParts[1]
-		} else {
-			// First part is namespace, not registry (e.g., "library/nginx")
-			registry = "docker.io"
-			repository = imagePath
+// containerIndex returns the position of the named container, or -1
+func containerIndex(containers []inventory.Container, name string) int {
+	for i, c := range containers {
+		if c.Name == name {
+			return i
 		}
 	}
+	return -1
+}
 
-	return registry, repository, tag
+// reconcileHistory records in the history what changed in the live workloads while Sentinel was down
+func reconcileHistory(live []inventory.Workload, inScope func(namespace string) bool) {
+	recorded, err := historyStore.Reconcile(live, inScope)
+	if err != nil {
+		slog.Error("Failed to reconcile the history with the live workloads", slog.Any("error", err))
+		return
+	}
+	slog.Debug("History reconciled with the live workloads", slog.Int("workloads", len(live)), slog.Int("transitions", recorded))
 }
@@ -0,0 +1,74 @@
+/*
+  One-shot listing of the workloads Sentinel would watch, without informers or a metrics server.
+  Used by `sentinel inventory`: the namespaces are selected and the images parsed exactly as the controller does.
+*/
+
+package sentinel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/manifests"
+	SentinelShared "github.com/MatteoMori/sentinel/pkg/shared"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+/*
+ListInventory lists the Deployments, StatefulSets and DaemonSets of the namespaces matching the namespace selector,
+or of the given namespace only, and returns them as inventory records sorted by workload.
+Any Kubernetes API error is returned: a partial inventory is never presented as complete.
+*/
+func ListInventory(ctx context.Context, clientset kubernetes.Interface, config SentinelShared.Config, namespace string) ([]inventory.Workload, error) {
+	namespaces := []string{namespace}
+	if namespace == "" {
+		list, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+			LabelSelector: namespaceLabelSelector(config.NamespaceSelector),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list namespaces: %w", err)
+		}
+		namespaces = namespaces[:0]
+		for i := range list.Items {
+			namespaces = append(namespaces, list.Items[i].Name)
+		}
+	}
+
+	store := inventory.NewStore()
+	add := func(resourceType, namespace string, workload metav1.Object, containers []corev1.Container) {
+		extraLabelValues := manifests.ExtraLabelValues(workload, config.ExtraLabels)
+		store.Upsert(manifests.InventoryRecord(resourceType, namespace, workload, containers, config.ExtraLabels, extraLabelValues))
+	}
+
+	apps := clientset.AppsV1()
+	for _, ns := range namespaces {
+		deployments, err := apps.Deployments(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list Deployments in %s: %w", ns, err)
+		}
+		for i := range deployments.Items {
+			add("Deployment", ns, &deployments.Items[i], deployments.Items[i].Spec.Template.Spec.Containers)
+		}
+
+		statefulsets, err := apps.StatefulSets(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list StatefulSets in %s: %w", ns, err)
+		}
+		for i := range statefulsets.Items {
+			add("StatefulSet", ns, &statefulsets.Items[i], statefulsets.Items[i].Spec.Template.Spec.Containers)
+		}
+
+		daemonsets, err := apps.DaemonSets(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list DaemonSets in %s: %w", ns, err)
+		}
+		for i := range daemonsets.Items {
+			add("DaemonSet", ns, &daemonsets.Items[i], daemonsets.Items[i].Spec.Template.Spec.Containers)
+		}
+	}
+
+	return store.List(), nil
+}
@@ -0,0 +1,41 @@
+package sentinel
+
+import (
+	"sync"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/policy"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// policyEngine evaluates the image policies on every workload added or updated; nil when policies are disabled
+var policyEngine *policy.Engine
+
+// policyRecorder keeps the violations of the live workloads and their metrics
+var policyRecorder = policy.NewRecorder()
+
+// namespaceLabels caches the labels of the namespaces (name -> labels), for the rules scoped by a namespace selector
+var namespaceLabels sync.Map
+
+// evaluatePolicies checks the containers of a workload against the image policies
+func evaluatePolicies(record inventory.Workload, workload metav1.Object, containers []corev1.Container) {
+	if policyEngine == nil {
+		return
+	}
+
+	labels, _ := namespaceLabels.Load(record.Namespace)
+	target := policy.Target{Workload: record, Containers: containers, Object: workload}
+	if labels != nil {
+		target.NamespaceLabels = labels.(map[string]string)
+	}
+	policyRecorder.Record(record.Namespace, record.Kind, record.Name, policyEngine.Evaluate(target))
+}
+
+// forgetPolicies drops the violations of a deleted workload
+func forgetPolicies(namespace, kind, name string) {
+	if policyEngine == nil {
+		return
+	}
+	policyRecorder.Record(namespace, kind, name, nil)
+}
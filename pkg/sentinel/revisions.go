@@ -0,0 +1,352 @@
+/*
+  Reconstruct the images a workload ran before Sentinel saw it, from the history Kubernetes already keeps:
+	- Deployments: the ReplicaSets they own, ordered by the deployment.kubernetes.io/revision annotation
+	- StatefulSets and DaemonSets: the ControllerRevisions they own, ordered by revision
+
+  Logic:
+	Every time a workload or one of its revisions is added, deleted or updated (spec or revision number, not status), the
+	revisions of the workload are listed again and:
+	- sentinel_image_previous_info is updated for the workload: one series per image a container ran before its current one
+	- the change history (pkg/history, if enabled) is backfilled with the revisions that predate what it recorded live,
+	  so a change is never counted twice. It is only written to when the revisions of a container changed.
+*/
+
+package sentinel
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/history"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// deploymentRevisionAnnotation is set by the Deployment controller on the ReplicaSets it owns
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// historyStore is the persistent change history, nil when disabled. It is opened by Start().
+var historyStore *history.Store
+
+// previousImagesMu serializes the refreshes, so that two of them never interleave on the same workload series
+var previousImagesMu sync.Mutex
+
+// previousImagesSeries holds the label values of the sentinel_image_previous_info series of each workload, so that a refresh
+// only deletes the series that are gone instead of rebuilding them all (a scrape could see them missing). Guarded by previousImagesMu.
+var previousImagesSeries = make(map[string]map[string][]string) // workload key -> joined label values -> label values
+
+// backfilledRevisions holds what was last backfilled for each container, so that the history is only written when a
+// revision is new. Guarded by previousImagesMu.
+var backfilledRevisions = make(map[string]string) // workload key + "/" + container -> revisions signature
+
+// workloadRevision is the pod template of a workload at a given revision
+type workloadRevision struct {
+	number     int64
+	created    time.Time
+	containers []corev1.Container
+}
+
+// watchRevisions registers the ReplicaSet and ControllerRevision informers of a namespace, and refreshes the
+// previous images of a workload whenever it or one of its revisions changes. It must be called before the factory is started.
+func watchRevisions(factory informers.SharedInformerFactory, namespace string) {
+	refreshOwner := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		revision, ok := obj.(metav1.Object)
+		if !ok {
+			return
+		}
+		if owner := metav1.GetControllerOf(revision); owner != nil {
+			refreshPreviousImages(factory, owner.Kind, namespace, owner.Name)
+		}
+	}
+	revisionHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: refreshOwner,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if revisionChanged(oldObj, newObj) {
+				refreshOwner(newObj)
+			}
+		},
+		DeleteFunc: refreshOwner,
+	}
+	factory.Apps().V1().ReplicaSets().Informer().AddEventHandler(revisionHandler)
+	factory.Apps().V1().ControllerRevisions().Informer().AddEventHandler(revisionHandler)
+
+	// Informers sync independently: whichever of the workload and its revisions shows up last triggers the refresh
+	workloadHandler := func(kind string) cache.ResourceEventHandlerFuncs {
+		refresh := func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if workload, ok := obj.(metav1.Object); ok {
+				refreshPreviousImages(factory, kind, namespace, workload.GetName())
+			}
+		}
+		return cache.ResourceEventHandlerFuncs{
+			AddFunc: refresh,
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				// Status updates (scaling, pod churn) don't change the revisions
+				oldWorkload, okOld := oldObj.(metav1.Object)
+				newWorkload, okNew := newObj.(metav1.Object)
+				if okOld && okNew && oldWorkload.GetGeneration() == newWorkload.GetGeneration() {
+					return
+				}
+				refresh(newObj)
+			},
+			DeleteFunc: refresh,
+		}
+	}
+	factory.Apps().V1().Deployments().Informer().AddEventHandler(workloadHandler("Deployment"))
+	factory.Apps().V1().StatefulSets().Informer().AddEventHandler(workloadHandler("StatefulSet"))
+	factory.Apps().V1().DaemonSets().Informer().AddEventHandler(workloadHandler("DaemonSet"))
+}
+
+/*
+revisionChanged reports whether an update of a ReplicaSet or a ControllerRevision matters to the previous images:
+its pod template (generation), its revision number (a rollback renumbers an old ReplicaSet) or its owner changed.
+The status updates of the ReplicaSets on every scale or pod churn don't.
+*/
+func revisionChanged(oldObj, newObj interface{}) bool {
+	switch o := oldObj.(type) {
+	case *appsv1.ReplicaSet:
+		n, ok := newObj.(*appsv1.ReplicaSet)
+		return !ok || o.Generation != n.Generation || o.Annotations[deploymentRevisionAnnotation] != n.Annotations[deploymentRevisionAnnotation] || !sameController(o, n)
+	case *appsv1.ControllerRevision:
+		n, ok := newObj.(*appsv1.ControllerRevision)
+		return !ok || o.Revision != n.Revision || !sameController(o, n)
+	}
+	return true
+}
+
+// sameController reports whether two versions of an object have the same controller
+func sameController(a, b metav1.Object) bool {
+	ownerA, ownerB := metav1.GetControllerOf(a), metav1.GetControllerOf(b)
+	if ownerA == nil || ownerB == nil {
+		return ownerA == ownerB
+	}
+	return ownerA.UID == ownerB.UID
+}
+
+// refreshPreviousImages updates sentinel_image_previous_info for a workload and backfills the change history
+func refreshPreviousImages(factory informers.SharedInformerFactory, kind, namespace, name string) {
+	previousImagesMu.Lock()
+	defer previousImagesMu.Unlock()
+
+	workloadKey := inventory.WorkloadKey(namespace, kind, name)
+	series := make(map[string][]string) // The series the workload should have after the refresh
+	defer func() {
+		for key, labelValues := range previousImagesSeries[workloadKey] {
+			if _, keep := series[key]; !keep {
+				SentinelPrometheus.SentinelImagePreviousInfo.DeleteLabelValues(labelValues...)
+			}
+		}
+		if len(series) == 0 {
+			delete(previousImagesSeries, workloadKey)
+		} else {
+			previousImagesSeries[workloadKey] = series
+		}
+	}()
+
+	containers, revisions, err := listWorkloadRevisions(factory, kind, namespace, name)
+	if apierrors.IsNotFound(err) {
+		// Workload deleted: its series are gone, and a workload created again with the same name is backfilled again
+		for key := range backfilledRevisions {
+			if strings.HasPrefix(key, workloadKey+"/") {
+				delete(backfilledRevisions, key)
+			}
+		}
+		return
+	}
+	if err != nil {
+		slog.Warn("Unable to list workload revisions",
+			slog.String("type", kind),
+			slog.String("ns/name", namespace+"/"+name),
+			slog.Any("error", err))
+		series = previousImagesSeries[workloadKey] // Keep the series as they are
+		return
+	}
+
+	var extraLabels map[string]string
+	if record, ok := workloadInventory.Get(namespace, kind, name); ok {
+		extraLabels = record.ExtraLabels
+	}
+
+	for _, container := range containers {
+		latest := make(map[string]int64) // previous image -> latest revision using it
+		var backfill []history.Revision
+
+		for _, rev := range revisions {
+			i := containerSpecIndex(rev.containers, container.Name)
+			if i < 0 {
+				continue
+			}
+			image := rev.containers[i].Image
+			backfill = append(backfill, history.Revision{Number: rev.number, Time: rev.created, Image: inventory.ParseImage(image)})
+			if image != container.Image {
+				latest[image] = rev.number
+			}
+		}
+
+		for image, number := range latest {
+			registry, repository, tag := parseImage(image)
+			labelValues := []string{
+				namespace,
+				kind,
+				name,
+				container.Name,
+				image,
+				registry,
+				repository,
+				tag,
+				strconv.FormatInt(number, 10),
+			}
+			series[strings.Join(labelValues, "\x00")] = labelValues
+			SentinelPrometheus.SentinelImagePreviousInfo.WithLabelValues(labelValues...).Set(1)
+		}
+
+		if historyStore == nil || len(backfill) == 0 {
+			continue
+		}
+		backfillKey, signature := workloadKey+"/"+container.Name, revisionsSignature(backfill)
+		if backfilledRevisions[backfillKey] == signature {
+			continue // Already backfilled with these revisions: Backfill would write nothing, but still commit a transaction
+		}
+		written, err := historyStore.Backfill(namespace, kind, name, container.Name, extraLabels, backfill)
+		if err == nil {
+			backfilledRevisions[backfillKey] = signature
+		}
+		if err != nil {
+			slog.Error("Failed to backfill image history",
+				slog.String("ns/workload", namespace+"/"+name),
+				slog.String("container", container.Name),
+				slog.Any("error", err))
+		} else if written > 0 {
+			slog.Info("Image history backfilled from revisions",
+				slog.String("type", kind),
+				slog.String("ns/workload", namespace+"/"+name),
+				slog.String("container", container.Name),
+				slog.Int("transitions", written))
+		}
+	}
+}
+
+// revisionsSignature summarizes the revisions of a container: their numbers and images
+func revisionsSignature(revisions []history.Revision) string {
+	var b strings.Builder
+	for _, rev := range revisions {
+		b.WriteString(strconv.FormatInt(rev.Number, 10) + "=" + rev.Image.Reference + ",")
+	}
+	return b.String()
+}
+
+// listWorkloadRevisions returns the current containers of a workload and its revisions, oldest first
+func listWorkloadRevisions(factory informers.SharedInformerFactory, kind, namespace, name string) ([]corev1.Container, []workloadRevision, error) {
+	apps := factory.Apps().V1()
+	var owner metav1.Object
+	var containers []corev1.Container
+	var revisions []workloadRevision
+
+	switch kind {
+	case "Deployment":
+		deploy, err := apps.Deployments().Lister().Deployments(namespace).Get(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		replicaSets, err := apps.ReplicaSets().Lister().ReplicaSets(namespace).List(labels.Everything())
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, rs := range replicaSets {
+			if !metav1.IsControlledBy(rs, deploy) {
+				continue
+			}
+			number, err := strconv.ParseInt(rs.Annotations[deploymentRevisionAnnotation], 10, 64)
+			if err != nil {
+				continue // Not (yet) annotated by the Deployment controller
+			}
+			revisions = append(revisions, workloadRevision{number: number, created: rs.CreationTimestamp.Time, containers: rs.Spec.Template.Spec.Containers})
+		}
+		return deploy.Spec.Template.Spec.Containers, sortRevisions(revisions), nil
+	case "StatefulSet":
+		statefulset, err := apps.StatefulSets().Lister().StatefulSets(namespace).Get(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		owner, containers = statefulset, statefulset.Spec.Template.Spec.Containers
+	case "DaemonSet":
+		daemonset, err := apps.DaemonSets().Lister().DaemonSets(namespace).Get(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		owner, containers = daemonset, daemonset.Spec.Template.Spec.Containers
+	default:
+		return nil, nil, nil
+	}
+
+	controllerRevisions, err := apps.ControllerRevisions().Lister().ControllerRevisions(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, cr := range controllerRevisions {
+		if !metav1.IsControlledBy(cr, owner) {
+			continue
+		}
+		template, err := controllerRevisionTemplate(cr)
+		if err != nil {
+			slog.Debug("Skipping undecodable ControllerRevision", slog.String("ns/name", namespace+"/"+cr.Name), slog.Any("error", err))
+			continue
+		}
+		revisions = append(revisions, workloadRevision{number: cr.Revision, created: cr.CreationTimestamp.Time, containers: template.Spec.Containers})
+	}
+
+	return containers, sortRevisions(revisions), nil
+}
+
+/*
+controllerRevisionTemplate extracts the pod template stored in a ControllerRevision.
+StatefulSet and DaemonSet controllers store it as a patch: {"spec":{"template":{"$patch":"replace", ...}}}
+*/
+func controllerRevisionTemplate(cr *appsv1.ControllerRevision) (corev1.PodTemplateSpec, error) {
+	var data struct {
+		Spec struct {
+			Template corev1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+	}
+	raw := cr.Data.Raw
+	if raw == nil && cr.Data.Object != nil {
+		var err error
+		if raw, err = json.Marshal(cr.Data.Object); err != nil {
+			return corev1.PodTemplateSpec{}, err
+		}
+	}
+	err := json.Unmarshal(raw, &data)
+	return data.Spec.Template, err
+}
+
+func sortRevisions(revisions []workloadRevision) []workloadRevision {
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].number < revisions[j].number })
+	return revisions
+}
+
+// containerSpecIndex returns the position of the named container in a pod spec, or -1
+func containerSpecIndex(containers []corev1.Container, name string) int {
+	for i, c := range containers {
+		if c.Name == name {
+			return i
+		}
+	}
+	return -1
+}
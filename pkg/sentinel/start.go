@@ -6,8 +6,22 @@ import (
 	"log/slog"
 	"slices"
 
+	SentinelAPI "github.com/MatteoMori/sentinel/pkg/api"
+	SentinelAudit "github.com/MatteoMori/sentinel/pkg/audit"
+	"github.com/MatteoMori/sentinel/pkg/bom"
+	"github.com/MatteoMori/sentinel/pkg/drift"
+	"github.com/MatteoMori/sentinel/pkg/eol"
+	"github.com/MatteoMori/sentinel/pkg/events"
+	SentinelGRPC "github.com/MatteoMori/sentinel/pkg/grpcapi"
+	"github.com/MatteoMori/sentinel/pkg/history"
+	SentinelNotify "github.com/MatteoMori/sentinel/pkg/notify"
+	"github.com/MatteoMori/sentinel/pkg/policy"
 	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	"github.com/MatteoMori/sentinel/pkg/registry"
 	SentinelShared "github.com/MatteoMori/sentinel/pkg/shared"
+	"github.com/MatteoMori/sentinel/pkg/signature"
+	"github.com/MatteoMori/sentinel/pkg/updates"
+	"github.com/MatteoMori/sentinel/pkg/vulnerability"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
@@ -18,6 +32,68 @@ import (
 
 func Start(Config SentinelShared.Config) {
 	setupLogging(Config.Verbosity)
+	eventBroker = events.NewBroker(Config.Events.BufferSize)
+	SentinelAPI.Init(workloadInventory, eventBroker, Config.ClusterName)
+	if Config.GRPC.Enabled {
+		SentinelGRPC.Init(Config.GRPC.Port, workloadInventory, eventBroker)
+	}
+	SentinelNotify.Init(Config.Notifications, Config.ClusterName, eventBroker)
+	if err := SentinelAudit.Init(Config.Audit, Config.ClusterName, eventBroker); err != nil {
+		slog.Error("Failed to start audit log", slog.Any("error", err))
+		return
+	}
+	if Config.History.Enabled {
+		if Config.History.CompactionInterval <= 0 || Config.History.Retention <= 0 {
+			slog.Error("history.retention and history.compactionInterval must be positive",
+				slog.Duration("retention", Config.History.Retention), slog.Duration("compactionInterval", Config.History.CompactionInterval))
+			return
+		}
+		var err error
+		historyStore, err = history.Open(Config.History.Path)
+		if err != nil {
+			slog.Error("Failed to open history store", slog.Any("error", err))
+			return
+		}
+		eventBroker.Consume("history", 1024, func(e events.Event) {
+			if err := historyStore.Record(e); err != nil {
+				slog.Error("Failed to record image transition", slog.Uint64("event_id", e.ID), slog.Any("error", err))
+			}
+		})
+		// What changed while Sentinel was down: in each namespace once listed, and the namespaces that stopped matching meanwhile
+		onInventorySynced(func() { reconcileHistory(nil, func(ns string) bool { return !isWatchedNamespace(ns) }) })
+		onNamespaceSynced(func(namespace string) {
+			reconcileHistory(workloadInventory.ListNamespace(namespace), func(ns string) bool { return ns == namespace })
+		})
+		go historyStore.RunCompaction(Config.History.Retention, Config.History.CompactionInterval)
+		SentinelAPI.InitHistory(historyStore)
+	}
+	if Config.Policy.Enabled {
+		engine, err := policy.NewEngine(Config.Policy)
+		if err != nil {
+			slog.Error("Invalid policy configuration", slog.Any("error", err))
+			return
+		}
+		policyEngine = engine
+		SentinelAPI.InitPolicy(policyRecorder)
+	}
+	if Config.EOL.Enabled {
+		checker, err := eol.Init(Config.EOL, workloadInventory, eventBroker)
+		if err != nil {
+			slog.Error("Failed to load EOL catalogue", slog.Any("error", err))
+			return
+		}
+		SentinelAPI.InitEOL(checker)
+		onNamespaceSynced(checker.EvaluateNamespace)
+	}
+	if Config.Drift.Enabled {
+		detector, err := drift.NewDetector(Config.Drift, workloadInventory, isWatchedNamespace)
+		if err != nil {
+			slog.Error("Invalid drift configuration", slog.Any("error", err))
+			return
+		}
+		go detector.Run()
+		SentinelAPI.InitDrift(detector)
+	}
 	SentinelPrometheus.Init(Config.MetricsPort, Config.ExtraLabels)
 
 	slog.Info("Starting Sentinel controller")
@@ -35,6 +111,57 @@ func Start(Config SentinelShared.Config) {
 		return
 	}
 
+	if Config.BOM.Enabled {
+		checker, err := bom.Init(Config.BOM, workloadInventory, eventBroker, clientset)
+		if err != nil {
+			slog.Error("Failed to load BOM", slog.Any("error", err))
+			return
+		}
+		SentinelAPI.InitBOM(checker)
+		onNamespaceSynced(checker.EvaluateNamespace)
+	}
+	if Config.Vulnerabilities.Enabled {
+		checker, err := vulnerability.Init(Config.Vulnerabilities, workloadInventory, eventBroker, clientset)
+		if err != nil {
+			slog.Error("Failed to load vulnerability reports", slog.Any("error", err))
+			return
+		}
+		SentinelAPI.InitVulnerabilities(checker)
+		onNamespaceSynced(checker.EvaluateNamespace)
+	}
+	// One registry client for every feature, so that they share the rate limits (and the bearer tokens) of each registry
+	var registryClient *registry.Client
+	if Config.ImageMetadata.Enabled || Config.Updates.Enabled || Config.Signatures.Enabled {
+		registryClient, err = registry.NewClient(Config.Registries)
+		if err != nil {
+			slog.Error("Failed to initialize the registry client", slog.Any("error", err))
+			return
+		}
+	}
+	if Config.ImageMetadata.Enabled {
+		enricher := registry.InitEnricher(Config.ImageMetadata, registryClient, workloadInventory, eventBroker, clientset)
+		SentinelAPI.InitImageMetadata(enricher)
+		onNamespaceSynced(enricher.EvaluateNamespace)
+	}
+	if Config.Updates.Enabled {
+		checker, err := updates.Init(Config.Updates, registryClient, workloadInventory, eventBroker, clientset)
+		if err != nil {
+			slog.Error("Failed to initialize the detection of newer versions", slog.Any("error", err))
+			return
+		}
+		SentinelAPI.InitUpdates(checker)
+		onNamespaceSynced(checker.EvaluateNamespace)
+	}
+	if Config.Signatures.Enabled {
+		checker, err := signature.Init(Config.Signatures, registryClient, workloadInventory, eventBroker, clientset)
+		if err != nil {
+			slog.Error("Failed to initialize the signature verification", slog.Any("error", err))
+			return
+		}
+		SentinelAPI.InitSignatures(checker)
+		onNamespaceSynced(checker.EvaluateNamespace)
+	}
+
 	// Monitor the K8s cluster for new namespaces matching the label and return a channel to use after.
 	nsChannel := NamespaceWatcher(clientset, Config.NamespaceSelector) // nsChannel will be used later by ServiceDiscovery
 	AppDiscovery(clientset, nsChannel, Config)
@@ -47,18 +174,7 @@ Monitor the K8s cluster for namespaces matching the Sentinel label selector MAP.
 - Return: a Channel
 */
 func NamespaceWatcher(clientset *kubernetes.Clientset, NamespaceSelector map[string]string) chan []string {
-	/*
-	  Build label selector string from map
-	  You need to build this string: "sentinel.io/controlled=enabled"
-	  If the map had multiple entries, you'd need: "key1=value1,key2=value2"
-	*/
-	var labelSelector string
-	for key, value := range NamespaceSelector {
-		if labelSelector != "" {
-			labelSelector += "," // Add comma separator for multiple labels
-		}
-		labelSelector += key + "=" + value
-	}
+	labelSelector := namespaceLabelSelector(NamespaceSelector)
 
 	// Start by getting a list of the existing namespaces containing the Sentinel label selector and the proper value
 	namespaces, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{
@@ -73,6 +189,7 @@ func NamespaceWatcher(clientset *kubernetes.Clientset, NamespaceSelector map[str
 	var initialNamespaces []string
 	for i := range namespaces.Items {
 		initialNamespaces = append(initialNamespaces, namespaces.Items[i].Name)
+		namespaceLabels.Store(namespaces.Items[i].Name, namespaces.Items[i].Labels)
 	}
 	slog.Debug("Initial namespaces", slog.Any("Namespaces", initialNamespaces))
 
@@ -91,6 +208,7 @@ func NamespaceWatcher(clientset *kubernetes.Clientset, NamespaceSelector map[str
 		AddFunc: func(obj interface{}) {
 			// Get the newly created namespace
 			namespace := obj.(*v1.Namespace)
+			namespaceLabels.Store(namespace.Name, namespace.Labels)
 
 			// Is this a new namespace + it contains the Sentinel label selector?
 			if !slices.Contains(initialNamespaces, namespace.Name) && namespaceMatchesSelector(namespace, NamespaceSelector) {
@@ -114,6 +232,7 @@ func NamespaceWatcher(clientset *kubernetes.Clientset, NamespaceSelector map[str
 		UpdateFunc: func(oldObj, newObj interface{}) {
 			oldNs := oldObj.(*v1.Namespace)
 			newNs := newObj.(*v1.Namespace)
+			namespaceLabels.Store(newNs.Name, newNs.Labels)
 
 			oldMatches := namespaceMatchesSelector(oldNs, NamespaceSelector)
 			newMatches := namespaceMatchesSelector(newNs, NamespaceSelector)
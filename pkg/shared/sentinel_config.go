@@ -1,5 +1,7 @@
 package shared
 
+import "time"
+
 // ExtraLabel defines how to extract a label/annotation from a workload and expose it as a Prometheus label
 type ExtraLabel struct {
 	Type                string `mapstructure:"type"`                // "annotation" or "label" - where to extract from
@@ -7,9 +9,263 @@ type ExtraLabel struct {
 	TimeseriesLabelName string `mapstructure:"timeseriesLabelName"` // The name to use in the Prometheus metric (e.g., "owner")
 }
 
+// EventsConfig configures the stream of image change events (served on /api/v1/events)
+type EventsConfig struct {
+	BufferSize int `mapstructure:"bufferSize"` // Number of past events kept in memory so that clients can resume with Last-Event-ID
+}
+
+// GRPCConfig configures the optional gRPC API (sentinel.v1.InventoryService)
+type GRPCConfig struct {
+	Enabled bool   `mapstructure:"enabled"` // Start the gRPC server
+	Port    string `mapstructure:"port"`    // Port the gRPC server listens on
+}
+
+// EventFilter restricts which events are sent to a notification endpoint. Empty fields match everything.
+type EventFilter struct {
+	Types       []string          `mapstructure:"types"`       // Event types, e.g. ["image.changed"]. Each notifier has its own default.
+	Namespaces  []string          `mapstructure:"namespaces"`  // Namespace globs, e.g. ["prod-*"]
+	Kinds       []string          `mapstructure:"kinds"`       // Workload kinds, e.g. ["Deployment"]
+	ExtraLabels map[string]string `mapstructure:"extraLabels"` // timeseriesLabelName -> value glob, e.g. {"owner": "payments"}
+}
+
+// DeliveryConfig controls how notifications are queued and retried
+type DeliveryConfig struct {
+	QueueSize      int           `mapstructure:"queueSize"`      // Max events waiting for delivery, newer events are dropped when full
+	MaxRetries     *int          `mapstructure:"maxRetries"`     // Retries after the first failed attempt (unset: 5, 0 disables retries)
+	InitialBackoff time.Duration `mapstructure:"initialBackoff"` // Wait before the first retry, doubled at each retry
+	MaxBackoff     time.Duration `mapstructure:"maxBackoff"`     // Upper bound for the wait between retries
+	Timeout        time.Duration `mapstructure:"timeout"`        // HTTP request timeout
+}
+
+// WebhookConfig defines an HTTP endpoint receiving image change notifications
+type WebhookConfig struct {
+	Name      string            `mapstructure:"name"`      // Used in logs and metrics
+	URL       string            `mapstructure:"url"`       // Endpoint receiving the POST requests
+	Headers   map[string]string `mapstructure:"headers"`   // Extra HTTP headers
+	Secret    string            `mapstructure:"secret"`    // HMAC-SHA256 signing key (prefer secretEnv)
+	SecretEnv string            `mapstructure:"secretEnv"` // Environment variable holding the signing key
+	Template  string            `mapstructure:"template"`  // Optional Go template for the body, the default is the event as JSON
+	Filter    EventFilter       `mapstructure:"filter"`
+	Delivery  DeliveryConfig    `mapstructure:"delivery"`
+}
+
+// CloudEventsSinkConfig defines an endpoint receiving image events as CloudEvents v1.0
+type CloudEventsSinkConfig struct {
+	Name     string            `mapstructure:"name"`    // Used in logs and metrics
+	URL      string            `mapstructure:"url"`     // e.g. a Knative Broker ingress
+	Mode     string            `mapstructure:"mode"`    // "structured" (default) or "binary" HTTP content mode
+	Headers  map[string]string `mapstructure:"headers"` // Extra HTTP headers
+	Filter   EventFilter       `mapstructure:"filter"`
+	Delivery DeliveryConfig    `mapstructure:"delivery"`
+}
+
+// ChatRouteConfig sends the changes whose routing extraLabel matches Value to a specific chat webhook
+type ChatRouteConfig struct {
+	Value string `mapstructure:"value"` // extraLabel value glob, e.g. "payments" or "team-*"
+	URL   string `mapstructure:"url"`   // Incoming webhook of the channel
+}
+
+// ChatNotifierConfig defines a Slack or Microsoft Teams notifier sending digests of image changes
+type ChatNotifierConfig struct {
+	Name                 string            `mapstructure:"name"`                 // Used in logs and metrics
+	Format               string            `mapstructure:"format"`               // "slack" or "teams"
+	URL                  string            `mapstructure:"url"`                  // Default incoming webhook, for changes matching no route (optional)
+	RouteBy              string            `mapstructure:"routeBy"`              // extraLabel (timeseriesLabelName) used for routing, e.g. "owner"
+	Routes               []ChatRouteConfig `mapstructure:"routes"`               // Evaluated in order, the first match wins
+	Window               time.Duration     `mapstructure:"window"`               // Changes received within this window are sent as one digest
+	MaxChangesPerMessage int               `mapstructure:"maxChangesPerMessage"` // Changes listed in a digest, the rest is summarized
+	Filter               EventFilter       `mapstructure:"filter"`
+	Delivery             DeliveryConfig    `mapstructure:"delivery"`
+}
+
+// NotificationsConfig groups the outbound notification subsystems
+type NotificationsConfig struct {
+	Webhooks    []WebhookConfig         `mapstructure:"webhooks"`
+	CloudEvents []CloudEventsSinkConfig `mapstructure:"cloudEvents"`
+	Chat        []ChatNotifierConfig    `mapstructure:"chat"`
+}
+
+// AuditConfig configures the JSON lines audit log of every image added/changed/removed event
+type AuditConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Output     string        `mapstructure:"output"`     // "stdout", "stderr" or a file path, e.g. /var/log/sentinel/audit.jsonl
+	MaxSizeMB  int           `mapstructure:"maxSizeMB"`  // Rotate the file when it would grow beyond this size (0 = no limit)
+	MaxAge     time.Duration `mapstructure:"maxAge"`     // Rotate the file when it's older than this (0 = no limit)
+	MaxBackups int           `mapstructure:"maxBackups"` // Rotated files to keep (0 = keep all)
+}
+
+// HistoryConfig configures the persistent history of image transitions
+type HistoryConfig struct {
+	Enabled            bool          `mapstructure:"enabled"`
+	Path               string        `mapstructure:"path"`               // bbolt database file, ideally on a PVC
+	Retention          time.Duration `mapstructure:"retention"`          // Transitions older than this are folded into the baseline
+	CompactionInterval time.Duration `mapstructure:"compactionInterval"` // How often the retention is applied
+}
+
+// DriftConfig configures the comparison of the cluster with a directory of manifests (the desired state)
+type DriftConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Path             string        `mapstructure:"path"`             // Manifests directory, e.g. a git-sync checkout
+	Interval         time.Duration `mapstructure:"interval"`         // How often the manifests are parsed and compared
+	DefaultNamespace string        `mapstructure:"defaultNamespace"` // Namespace of the manifests without metadata.namespace
+}
+
+// ConfigMapRef points to a key of a ConfigMap
+type ConfigMapRef struct {
+	Namespace string `mapstructure:"namespace"`
+	Name      string `mapstructure:"name"`
+	Key       string `mapstructure:"key"`
+}
+
+// BOMConfig configures the desired version "bill of materials" compliance checks
+type BOMConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Path           string        `mapstructure:"path"`           // BOM file (e.g. a mounted ConfigMap)...
+	ConfigMap      ConfigMapRef  `mapstructure:"configMap"`      // ...or a ConfigMap read through the API
+	ReloadInterval time.Duration `mapstructure:"reloadInterval"` // How often the file is checked for changes
+}
+
+// ConfigMapSelector selects ConfigMaps by label
+type ConfigMapSelector struct {
+	Namespace     string `mapstructure:"namespace"` // Empty for all namespaces
+	LabelSelector string `mapstructure:"labelSelector"`
+}
+
+// VulnerabilitiesConfig configures the ingestion of image vulnerability reports (Trivy, Grype)
+type VulnerabilitiesConfig struct {
+	Enabled        bool              `mapstructure:"enabled"`
+	Path           string            `mapstructure:"path"`           // Directory of reports (e.g. a mounted volume)...
+	ConfigMaps     ConfigMapSelector `mapstructure:"configMaps"`     // ...or ConfigMaps holding one report per key
+	ReloadInterval time.Duration     `mapstructure:"reloadInterval"` // How often the directory is checked for changes
+}
+
+// EOLConfig configures the end-of-life detection of base images and runtimes
+type EOLConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Path           string        `mapstructure:"path"`           // Catalogue file overriding or extending the bundled one (optional)
+	ReloadInterval time.Duration `mapstructure:"reloadInterval"` // How often the file is checked for changes
+}
+
+// DigestPinningConfig requires images pinned by digest in the namespaces matching a label selector
+type DigestPinningConfig struct {
+	Enabled           bool              `mapstructure:"enabled"`
+	NamespaceSelector map[string]string `mapstructure:"namespaceSelector"` // Empty for every namespace
+}
+
+// CustomRule is a user-defined image policy rule, written as a CEL expression
+type CustomRule struct {
+	Name       string `mapstructure:"name"`
+	Severity   string `mapstructure:"severity"`   // error (default), warning or info
+	Expression string `mapstructure:"expression"` // Must return true when the container complies
+	Message    string `mapstructure:"message"`    // Reported when the expression returns false
+}
+
+// PolicyConfig configures the built-in image policy checks
+type PolicyConfig struct {
+	Enabled               bool                `mapstructure:"enabled"`               // Evaluate the live workloads (sentinel scan always evaluates)
+	AllowedRegistries     []string            `mapstructure:"allowedRegistries"`     // Registry (or "registry/repository") globs; empty allows any
+	ForbiddenTags         []string            `mapstructure:"forbiddenTags"`         // Tag globs
+	RequireDigest         DigestPinningConfig `mapstructure:"requireDigest"`         // Digest pinning
+	ForbiddenPullPolicies []string            `mapstructure:"forbiddenPullPolicies"` // e.g. Never
+	Severities            map[string]string   `mapstructure:"severities"`            // Rule name -> severity, overriding the defaults
+	DisabledRules         []string            `mapstructure:"disabledRules"`         // Rule names
+	CustomRules           []CustomRule        `mapstructure:"customRules"`           // CEL rules, evaluated after the built-in ones
+}
+
+// AdmissionAction sets the action of the violations of some rules in some namespaces
+type AdmissionAction struct {
+	Rules             []string          `mapstructure:"rules"`             // Rule names; empty for every rule
+	NamespaceSelector map[string]string `mapstructure:"namespaceSelector"` // Empty for every namespace
+	Action            string            `mapstructure:"action"`            // enforce, warn or dryrun
+}
+
+// AdmissionConfig configures the admission webhook server (sentinel webhook)
+type AdmissionConfig struct {
+	Port          string            `mapstructure:"port"`
+	CertFile      string            `mapstructure:"certFile"`      // TLS certificate, reloaded when it changes
+	KeyFile       string            `mapstructure:"keyFile"`       // TLS private key
+	DefaultAction string            `mapstructure:"defaultAction"` // Action of the violations no entry of actions matches
+	Actions       []AdmissionAction `mapstructure:"actions"`       // First match wins
+	PinDigests    PinDigestsConfig  `mapstructure:"pinDigests"`    // Mutating webhook pinning tags to digests
+}
+
+// PinDigestsConfig configures the mutating webhook rewriting repo:tag images to repo:tag@sha256:...
+type PinDigestsConfig struct {
+	Enabled           bool              `mapstructure:"enabled"`           // Serve /mutate
+	NamespaceSelector map[string]string `mapstructure:"namespaceSelector"` // Empty for every namespace
+	Registries        []string          `mapstructure:"registries"`        // Registry globs to pin images of, e.g. ["ghcr.io", "*.dkr.ecr.*.amazonaws.com"]; empty for all
+	CacheTTL          time.Duration     `mapstructure:"cacheTTL"`          // How long a resolved digest is reused
+}
+
+// ImageMetadataConfig configures the enrichment of the inventory with image metadata read from the registries
+type ImageMetadataConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	UsePullSecrets  bool          `mapstructure:"usePullSecrets"`  // Authenticate with the imagePullSecrets of the workloads (needs get on secrets)
+	CacheSize       int           `mapstructure:"cacheSize"`       // Max images kept in the LRU cache
+	CacheTTL        time.Duration `mapstructure:"cacheTTL"`        // How long the metadata of a tag is reused (images pinned by digest never change)
+	Concurrency     int           `mapstructure:"concurrency"`     // Max registry lookups in flight
+	RefreshInterval time.Duration `mapstructure:"refreshInterval"` // How often the whole inventory is looked up again
+	SourceInfo      bool          `mapstructure:"sourceInfo"`      // Expose the source repository, revision and version of the images (sentinel_image_source_info)
+}
+
+// UpdatesConfig configures the detection of newer versions of the images, from the tags of their repositories
+type UpdatesConfig struct {
+	Enabled            bool          `mapstructure:"enabled"`
+	Repositories       []string      `mapstructure:"repositories"`       // Allow-list of "registry/repository" globs to check, e.g. ["docker.io/library/*"]; empty for all
+	Interval           time.Duration `mapstructure:"interval"`           // How often the tags are listed again
+	IncludePrereleases bool          `mapstructure:"includePrereleases"` // Count prereleases (1.2.0-rc.1) as newer versions
+	PrereleasePattern  string        `mapstructure:"prereleasePattern"`  // Regexp telling prerelease suffixes from variant suffixes (-alpine)
+	MatchVariant       bool          `mapstructure:"matchVariant"`       // Only compare a -alpine image with -alpine tags (false: ignore the suffix)
+	UsePullSecrets     bool          `mapstructure:"usePullSecrets"`     // Authenticate with the imagePullSecrets of the workloads
+	Concurrency        int           `mapstructure:"concurrency"`        // Max tag listings in flight
+}
+
+// SignaturesConfig configures the verification of the cosign signatures and attestations of the images
+type SignaturesConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	PublicKeys     []string      `mapstructure:"publicKeys"`     // Paths of PEM public keys (cosign.pub); a signature by any of them is accepted
+	Repositories   []string      `mapstructure:"repositories"`   // Allow-list of "registry/repository" globs to verify, e.g. ["ghcr.io/myorg/*"]; empty for all
+	Interval       time.Duration `mapstructure:"interval"`       // How often the unsigned images are checked again (verified digests stay cached)
+	CacheSize      int           `mapstructure:"cacheSize"`      // Max digests kept in the LRU cache
+	UsePullSecrets bool          `mapstructure:"usePullSecrets"` // Authenticate with the imagePullSecrets of the workloads
+	Concurrency    int           `mapstructure:"concurrency"`    // Max verifications in flight
+}
+
+// RegistryConfig holds the credentials and connection settings of a container registry
+type RegistryConfig struct {
+	Host        string `mapstructure:"host"`        // e.g. "ghcr.io", "docker.io", "registry.local:5000"
+	Username    string `mapstructure:"username"`    // Empty for anonymous access
+	Password    string `mapstructure:"password"`    // Password or token (prefer passwordEnv)
+	PasswordEnv string `mapstructure:"passwordEnv"` // Environment variable holding the password
+	Insecure    bool   `mapstructure:"insecure"`    // Plain HTTP instead of HTTPS
+	RateLimit   int    `mapstructure:"rateLimit"`   // Max requests per minute to the registry, 0 for no limit
+}
+
+// BackfillConfig configures the reconstruction of previous images from ReplicaSets and ControllerRevisions
+type BackfillConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
 type Config struct {
-	NamespaceSelector map[string]string `mapstructure:"namespaceSelector"` // Label selector for namespaces to watch
-	MetricsPort       string            `mapstructure:"metricsPort"`       // Port for Prometheus metrics endpoint
-	Verbosity         int               `mapstructure:"verbosity"`         // Log verbosity level (0-2)
-	ExtraLabels       []ExtraLabel      `mapstructure:"extraLabels"`       // Additional labels to extract from workloads
+	ClusterName       string                `mapstructure:"clusterName"`       // Name of the cluster Sentinel runs in, used to identify it in exported data
+	NamespaceSelector map[string]string     `mapstructure:"namespaceSelector"` // Label selector for namespaces to watch
+	MetricsPort       string                `mapstructure:"metricsPort"`       // Port for Prometheus metrics endpoint
+	Verbosity         int                   `mapstructure:"verbosity"`         // Log verbosity level (0-2)
+	ExtraLabels       []ExtraLabel          `mapstructure:"extraLabels"`       // Additional labels to extract from workloads
+	Events            EventsConfig          `mapstructure:"events"`            // Image change event stream settings
+	GRPC              GRPCConfig            `mapstructure:"grpc"`              // Optional gRPC API
+	Notifications     NotificationsConfig   `mapstructure:"notifications"`     // Outbound notifications on image changes
+	Audit             AuditConfig           `mapstructure:"audit"`             // JSON lines audit log
+	History           HistoryConfig         `mapstructure:"history"`           // Persistent history of image transitions
+	Backfill          BackfillConfig        `mapstructure:"backfill"`          // Previous images from the Kubernetes revision history
+	Drift             DriftConfig           `mapstructure:"drift"`             // GitOps drift detection
+	BOM               BOMConfig             `mapstructure:"bom"`               // Approved versions compliance
+	Vulnerabilities   VulnerabilitiesConfig `mapstructure:"vulnerabilities"`   // Vulnerability reports of the running images
+	EOL               EOLConfig             `mapstructure:"eol"`               // End-of-life detection
+	Policy            PolicyConfig          `mapstructure:"policy"`            // Built-in image policy checks
+	Admission         AdmissionConfig       `mapstructure:"admission"`         // Admission webhook server
+	Registries        []RegistryConfig      `mapstructure:"registries"`        // Credentials of the registries Sentinel talks to
+	ImageMetadata     ImageMetadataConfig   `mapstructure:"imageMetadata"`     // Image metadata read from the registries
+	Updates           UpdatesConfig         `mapstructure:"updates"`           // Newer versions available in the registries
+	Signatures        SignaturesConfig      `mapstructure:"signatures"`        // Cosign signatures and provenance attestations
 }
@@ -0,0 +1,307 @@
+package signature
+
+import (
+	"context"
+	"crypto"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	"github.com/MatteoMori/sentinel/pkg/registry"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// verifyTimeout bounds the registry requests of one image
+	verifyTimeout = time.Minute
+	// maxErrorTTL bounds how long a failed verification is cached
+	maxErrorTTL = 5 * time.Minute
+	// verifiedTTL is how long a fully verified digest is reused: the content behind a digest never changes
+	verifiedTTL = 24 * time.Hour
+)
+
+// Verification is what was verified about an image digest
+type Verification struct {
+	Digest         string   `json:"digest"`
+	Signed         bool     `json:"signed"`                   // Has a cosign signature verified with a configured key
+	Provenance     bool     `json:"provenance"`               // Has a SLSA provenance attestation verified with a configured key
+	PredicateTypes []string `json:"predicateTypes,omitempty"` // Predicate types of all the verified attestations
+}
+
+/*
+Result is the verification of the image of one container.
+An image running a tag is verified at the digest the tag points to when it is verified (TagResolved): the pods
+may still run the digest the tag pointed to when they started. Pin the images by digest to verify what runs.
+*/
+type Result struct {
+	Namespace    string          `json:"namespace"`
+	Kind         string          `json:"kind"`
+	Workload     string          `json:"workload"`
+	Container    string          `json:"container"`
+	Image        inventory.Image `json:"image"`
+	TagResolved  bool            `json:"tagResolved,omitempty"` // The digest verified was resolved from the tag, see above
+	Verification *Verification   `json:"verification,omitempty"`
+	Error        string          `json:"error,omitempty"` // Why the verification couldn't be done
+}
+
+// Checker verifies the signatures and attestations of the running images and keeps the signature metrics up to date
+type Checker struct {
+	client           *registry.Client
+	keys             []crypto.PublicKey
+	store            *inventory.Store
+	clientset        kubernetes.Interface
+	repositories     []string
+	interval         time.Duration
+	usePullSecrets   bool
+	digests          *registry.Cache[string]       // "<credentials identity> registry/repository:tag" -> digest, for the images not pinned by digest
+	verifications    *registry.Cache[Verification] // "<credentials identity> registry/repository@digest" -> verification (see registry.Client.CacheKey)
+	slots            chan struct{}                 // Limits the registry lookups in flight
+	signedSeries     *SentinelPrometheus.SeriesSet // By workload key
+	provenanceSeries *SentinelPrometheus.SeriesSet // By workload key
+
+	mu          sync.Mutex
+	results     map[string][]Result // workload key -> results of its containers
+	generations map[string]uint64   // workload key -> evaluation counter, so that a slow verification never overwrites a newer one
+}
+
+/*
+Init verifies every workload added or changed in the inventory, and the whole inventory every interval.
+A digest signed and attested is verified once; the others are verified again after interval, for signatures pushed late.
+*/
+func Init(cfg shared.SignaturesConfig, client *registry.Client, store *inventory.Store, broker *events.Broker, clientset kubernetes.Interface) (*Checker, error) {
+	keys, err := LoadPublicKeys(cfg.PublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	checker := &Checker{
+		client:           client,
+		keys:             keys,
+		store:            store,
+		clientset:        clientset,
+		repositories:     cfg.Repositories,
+		interval:         cfg.Interval,
+		usePullSecrets:   cfg.UsePullSecrets,
+		digests:          registry.NewCache[string](cfg.CacheSize),
+		verifications:    registry.NewCache[Verification](cfg.CacheSize),
+		slots:            make(chan struct{}, cfg.Concurrency),
+		signedSeries:     SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelImageSignatureVerified),
+		provenanceSeries: SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelImageProvenanceVerified),
+		results:          make(map[string][]Result),
+		generations:      make(map[string]uint64),
+	}
+
+	broker.Consume("signatures", 256, func(e events.Event) {
+		go checker.evaluateWorkload(e.Namespace, e.Kind, e.Workload)
+	})
+	go func() {
+		for {
+			time.Sleep(cfg.Interval)
+			for _, w := range store.List() {
+				checker.evaluateWorkload(w.Namespace, w.Kind, w.Name)
+			}
+		}
+	}()
+	return checker, nil
+}
+
+// allowed reports whether the repository of an image is in the allow-list
+func (c *Checker) allowed(image inventory.Image) bool {
+	return len(c.repositories) == 0 || slices.ContainsFunc(c.repositories, func(glob string) bool { return inventory.MatchGlob(glob, image.RepositoryKey()) })
+}
+
+// EvaluateNamespace evaluates every workload of a namespace, after its initial listing (which publishes no event)
+func (c *Checker) EvaluateNamespace(namespace string) {
+	for _, w := range c.store.ListNamespace(namespace) {
+		c.evaluateWorkload(w.Namespace, w.Kind, w.Name)
+	}
+}
+
+// evaluateWorkload verifies the images of one workload after a change, or forgets it once deleted
+func (c *Checker) evaluateWorkload(namespace, kind, name string) {
+	key := inventory.WorkloadKey(namespace, kind, name)
+	c.mu.Lock()
+	c.generations[key]++
+	generation := c.generations[key]
+	c.mu.Unlock()
+
+	var results []Result
+	w, ok := c.store.Get(namespace, kind, name)
+	if ok {
+		results = c.evaluate(w)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generations[key] != generation {
+		return // A newer evaluation of the workload started meanwhile
+	}
+	if !ok {
+		delete(c.generations, key)
+	}
+	c.setResults(namespace, kind, name, results)
+}
+
+// evaluate verifies the image of every container of a workload
+func (c *Checker) evaluate(w inventory.Workload) []Result {
+	var keychain registry.Keychain
+	keychainRead := false
+
+	var results []Result
+	for _, container := range w.Containers {
+		image := container.Image
+		if !c.allowed(image) {
+			continue
+		}
+		if c.usePullSecrets && !keychainRead && len(w.ImagePullSecrets) > 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+			keychain = registry.PullSecretKeychain(ctx, c.clientset, w.Namespace, w.ImagePullSecrets)
+			cancel()
+			keychainRead = true
+		}
+
+		result := Result{Namespace: w.Namespace, Kind: w.Kind, Workload: w.Name, Container: container.Name, Image: image, TagResolved: image.Digest == ""}
+		verification, err := c.verify(image, keychain)
+		if err != nil {
+			slog.Debug("Image signature verification failed",
+				slog.String("ns/workload", w.Namespace+"/"+w.Name),
+				slog.String("container", container.Name),
+				slog.String("image", image.Reference),
+				slog.Any("error", err))
+			result.Error = err.Error()
+		} else {
+			result.Verification = &verification
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// verify returns the verification of the digest of an image (the one its tag points to now when not pinned), from the cache or from its registry
+func (c *Checker) verify(image inventory.Image, keychain registry.Keychain) (Verification, error) {
+	digest := image.Digest
+	if digest == "" {
+		var err error
+		digest, err = c.digests.Load(c.client.CacheKey(image, keychain, image.RepositoryKey()+":"+image.Tag), func() (string, error, time.Duration) {
+			c.slots <- struct{}{}
+			defer func() { <-c.slots }()
+
+			ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+			defer cancel()
+			digest, err := c.client.ResolveDigest(ctx, image, keychain)
+			if err != nil {
+				return "", err, min(c.interval, maxErrorTTL)
+			}
+			return digest, nil, c.interval
+		})
+		if err != nil {
+			return Verification{}, err
+		}
+	}
+
+	return c.verifications.Load(c.client.CacheKey(image, keychain, image.RepositoryKey()+"@"+digest), func() (Verification, error, time.Duration) {
+		c.slots <- struct{}{}
+		defer func() { <-c.slots }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+		defer cancel()
+		verification, err := c.verifyDigest(ctx, image, digest, keychain)
+		switch {
+		case err != nil:
+			return verification, err, min(c.interval, maxErrorTTL)
+		case verification.Signed && verification.Provenance:
+			return verification, nil, verifiedTTL
+		}
+		return verification, nil, c.interval
+	})
+}
+
+// verifyDigest reads and verifies the signatures and attestations cosign stored for a digest
+func (c *Checker) verifyDigest(ctx context.Context, image inventory.Image, digest string, keychain registry.Keychain) (Verification, error) {
+	verification := Verification{Digest: digest}
+	tag := strings.Replace(digest, ":", "-", 1) // sha256:abc... -> sha256-abc...
+
+	layers, found, err := c.client.ArtifactLayers(ctx, image, tag+".sig", keychain)
+	if err != nil {
+		return verification, err
+	}
+	if found {
+		verification.Signed = verifySignatures(c.keys, layers, digest)
+	}
+
+	layers, found, err = c.client.ArtifactLayers(ctx, image, tag+".att", keychain)
+	if err != nil {
+		return verification, err
+	}
+	if found {
+		verification.PredicateTypes = verifyAttestations(c.keys, layers, digest)
+		for _, predicateType := range verification.PredicateTypes {
+			if isProvenance(predicateType) {
+				verification.Provenance = true
+			}
+		}
+	}
+	return verification, nil
+}
+
+// setResults replaces the results and the series of a workload. Must be called with the lock held.
+func (c *Checker) setResults(namespace, kind, name string, results []Result) {
+	key := inventory.WorkloadKey(namespace, kind, name)
+	signed, provenance := c.signedSeries.Update(key), c.provenanceSeries.Update(key)
+	for _, r := range results {
+		if r.Verification == nil {
+			continue
+		}
+		labels := []string{r.Namespace, r.Kind, r.Workload, r.Container, r.Image.Reference, r.Verification.Digest}
+		signed.Set(boolToFloat(r.Verification.Signed), labels...)
+		provenance.Set(boolToFloat(r.Verification.Provenance), labels...)
+	}
+	signed.Commit()
+	provenance.Commit()
+
+	if len(results) == 0 {
+		delete(c.results, key)
+		return
+	}
+	c.results[key] = results
+}
+
+// boolToFloat converts a bool to a gauge value
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Results returns the verifications of the containers of a namespace ("" for all), sorted by workload. unsignedOnly drops the signed ones.
+func (c *Checker) Results(namespace string, unsignedOnly bool) []Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	results := []Result{}
+	for _, workloadResults := range c.results {
+		for _, r := range workloadResults {
+			if (namespace != "" && r.Namespace != namespace) || (unsignedOnly && r.Verification != nil && r.Verification.Signed) {
+				continue
+			}
+			results = append(results, r)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		ki := inventory.WorkloadKey(results[i].Namespace, results[i].Kind, results[i].Workload) + "/" + results[i].Container
+		kj := inventory.WorkloadKey(results[j].Namespace, results[j].Kind, results[j].Workload) + "/" + results[j].Container
+		return ki < kj
+	})
+	return results
+}
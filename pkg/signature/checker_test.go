@@ -0,0 +1,287 @@
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"github.com/MatteoMori/sentinel/pkg/registry"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+)
+
+// testRegistry serves manifests by tag or digest and blobs by digest, anonymously
+type testRegistry struct {
+	*httptest.Server
+	content map[string][]byte // "manifests/<reference>" or "blobs/<digest>" -> content
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	t.Helper()
+	r := &testRegistry{content: make(map[string][]byte)}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, key, _ := strings.Cut(req.URL.Path, "/acme/api/")
+		data, ok := r.content[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if strings.HasPrefix(key, "manifests/") {
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Docker-Content-Digest", digestOf(data))
+		}
+		if req.Method != http.MethodHead {
+			w.Write(data)
+		}
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// addManifest stores a manifest of the given layers under a tag (and its digest), and returns its digest
+func (r *testRegistry) addManifest(t *testing.T, tag string, layers []map[string]any) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"config":        map[string]any{"mediaType": "application/vnd.oci.image.config.v1+json", "digest": "sha256:00", "size": 2},
+		"layers":        layers,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := digestOf(data)
+	r.content["manifests/"+tag] = data
+	r.content["manifests/"+digest] = data
+	return digest
+}
+
+// layer stores a blob and returns its descriptor
+func (r *testRegistry) layer(mediaType string, data []byte, annotations map[string]string) map[string]any {
+	digest := digestOf(data)
+	r.content["blobs/"+digest] = data
+	return map[string]any{"mediaType": mediaType, "digest": digest, "size": len(data), "annotations": annotations}
+}
+
+// newTestChecker returns a checker verifying with the public keys of the signers, talking to the registry
+func newTestChecker(t *testing.T, r *testRegistry, signers ...crypto.Signer) *Checker {
+	t.Helper()
+	var paths []string
+	for i, signer := range signers {
+		der, err := x509.MarshalPKIXPublicKey(signer.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), fmt.Sprintf("cosign-%d.pub", i))
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	keys, err := LoadPublicKeys(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := registry.NewClient([]shared.RegistryConfig{{Host: strings.TrimPrefix(r.URL, "http://"), Insecure: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Checker{
+		client:        client,
+		keys:          keys,
+		interval:      time.Minute,
+		digests:       registry.NewCache[string](10),
+		verifications: registry.NewCache[Verification](10),
+		slots:         make(chan struct{}, 1),
+	}
+}
+
+// sign signs a message the way cosign does for the key type
+func sign(t *testing.T, signer crypto.Signer, message []byte) string {
+	t.Helper()
+	var signature []byte
+	var err error
+	switch signer.(type) {
+	case ed25519.PrivateKey:
+		signature, err = signer.Sign(rand.Reader, message, crypto.Hash(0))
+	default:
+		digest := sha256.Sum256(message)
+		signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(signature)
+}
+
+// signatureLayer returns a cosign simple signing layer signing payload, whose content is stored as data
+func (r *testRegistry) signatureLayer(t *testing.T, signer crypto.Signer, payload, data []byte) map[string]any {
+	return r.layer(simpleSigningMediaType, data, map[string]string{signatureAnnotation: sign(t, signer, payload)})
+}
+
+// attestationLayer returns a DSSE layer with an in-toto statement about subjectDigest
+func (r *testRegistry) attestationLayer(t *testing.T, signer crypto.Signer, predicateType, subjectDigest string) map[string]any {
+	algorithm, hex, _ := strings.Cut(subjectDigest, ":")
+	payload, err := json.Marshal(map[string]any{
+		"_type":         "https://in-toto.io/Statement/v1",
+		"predicateType": predicateType,
+		"subject":       []map[string]any{{"name": "acme/api", "digest": map[string]string{algorithm: hex}}},
+		"predicate":     map[string]any{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := json.Marshal(map[string]any{
+		"payloadType": inTotoPayloadType,
+		"payload":     base64.StdEncoding.EncodeToString(payload),
+		"signatures":  []map[string]string{{"sig": sign(t, signer, pae(inTotoPayloadType, payload))}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r.layer(dsseMediaType, env, nil)
+}
+
+func simpleSigning(digest string) []byte {
+	return []byte(fmt.Sprintf(`{"critical": {"identity": {"docker-reference": "acme/api"}, "image": {"docker-manifest-digest": %q}, "type": "cosign container image signature"}, "optional": null}`, digest))
+}
+
+func TestVerify(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const provenance = "https://slsa.dev/provenance/v1"
+	const otherDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+	tests := []struct {
+		name string
+		// artifacts stores the .sig and .att layers of the image digest
+		artifacts func(t *testing.T, r *testRegistry, digest string) (sig, att []map[string]any)
+		signed    bool
+		predicate []string
+	}{
+		{"ecdsa", func(t *testing.T, r *testRegistry, digest string) ([]map[string]any, []map[string]any) {
+			return []map[string]any{r.signatureLayer(t, ecdsaKey, simpleSigning(digest), simpleSigning(digest))}, nil
+		}, true, nil},
+		{"rsa", func(t *testing.T, r *testRegistry, digest string) ([]map[string]any, []map[string]any) {
+			return []map[string]any{r.signatureLayer(t, rsaKey, simpleSigning(digest), simpleSigning(digest))}, nil
+		}, true, nil},
+		{"ed25519", func(t *testing.T, r *testRegistry, digest string) ([]map[string]any, []map[string]any) {
+			return []map[string]any{r.signatureLayer(t, ed25519Key, simpleSigning(digest), simpleSigning(digest))}, nil
+		}, true, nil},
+		{"unknown key", func(t *testing.T, r *testRegistry, digest string) ([]map[string]any, []map[string]any) {
+			return []map[string]any{r.signatureLayer(t, otherKey, simpleSigning(digest), simpleSigning(digest))}, nil
+		}, false, nil},
+		{"signature of another digest", func(t *testing.T, r *testRegistry, digest string) ([]map[string]any, []map[string]any) {
+			return []map[string]any{r.signatureLayer(t, ecdsaKey, simpleSigning(otherDigest), simpleSigning(otherDigest))}, nil
+		}, false, nil},
+		{"tampered payload", func(t *testing.T, r *testRegistry, digest string) ([]map[string]any, []map[string]any) {
+			// Signed over another digest, then rewritten to claim this one
+			return []map[string]any{r.signatureLayer(t, ecdsaKey, simpleSigning(otherDigest), simpleSigning(digest))}, nil
+		}, false, nil},
+		{"provenance", func(t *testing.T, r *testRegistry, digest string) ([]map[string]any, []map[string]any) {
+			sig := []map[string]any{r.signatureLayer(t, ecdsaKey, simpleSigning(digest), simpleSigning(digest))}
+			att := []map[string]any{
+				r.attestationLayer(t, ecdsaKey, provenance, digest),
+				r.attestationLayer(t, ed25519Key, "https://spdx.dev/Document", digest),
+			}
+			return sig, att
+		}, true, []string{provenance, "https://spdx.dev/Document"}},
+		{"attestation of another subject", func(t *testing.T, r *testRegistry, digest string) ([]map[string]any, []map[string]any) {
+			return nil, []map[string]any{r.attestationLayer(t, ecdsaKey, provenance, otherDigest)}
+		}, false, nil},
+		{"attestation by an unknown key", func(t *testing.T, r *testRegistry, digest string) ([]map[string]any, []map[string]any) {
+			return nil, []map[string]any{r.attestationLayer(t, otherKey, provenance, digest)}
+		}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRegistry(t)
+			digest := r.addManifest(t, "1.0.0", []map[string]any{r.layer("application/vnd.oci.image.layer.v1.tar+gzip", []byte("rootfs"), nil)})
+			sig, att := tt.artifacts(t, r, digest)
+			tag := strings.Replace(digest, ":", "-", 1)
+			if sig != nil {
+				r.addManifest(t, tag+".sig", sig)
+			}
+			if att != nil {
+				r.addManifest(t, tag+".att", att)
+			}
+			checker := newTestChecker(t, r, ecdsaKey, rsaKey, ed25519Key)
+			host := strings.TrimPrefix(r.URL, "http://")
+
+			// By tag (resolved to the digest) and by digest
+			for _, image := range []inventory.Image{inventory.ParseImage(host + "/acme/api:1.0.0"), inventory.ParseImage(host + "/acme/api@" + digest)} {
+				verification, err := checker.verify(image, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if verification.Digest != digest || verification.Signed != tt.signed || strings.Join(verification.PredicateTypes, ",") != strings.Join(tt.predicate, ",") {
+					t.Fatalf("%s: got %+v, want signed=%t and predicate types %v", image.Reference, verification, tt.signed, tt.predicate)
+				}
+				if verification.Provenance != (len(tt.predicate) > 0) {
+					t.Fatalf("%s: got provenance=%t", image.Reference, verification.Provenance)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateTagResolved(t *testing.T) {
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRegistry(t)
+	digest := r.addManifest(t, "1.0.0", nil)
+	checker := newTestChecker(t, r, signer)
+	host := strings.TrimPrefix(r.URL, "http://")
+
+	results := checker.evaluate(inventory.Workload{Namespace: "prod", Kind: "Deployment", Name: "api", Containers: []inventory.Container{
+		{Name: "tag", Image: inventory.ParseImage(host + "/acme/api:1.0.0")},
+		{Name: "digest", Image: inventory.ParseImage(host + "/acme/api@" + digest)},
+	}})
+	if len(results) != 2 || !results[0].TagResolved || results[1].TagResolved {
+		t.Fatalf("got %+v, want only the tag flagged as resolved", results)
+	}
+	for _, result := range results {
+		if result.Verification == nil || result.Verification.Digest != digest || result.Verification.Signed {
+			t.Fatalf("unexpected verification %+v (%s)", result.Verification, result.Error)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Verification of the cosign signatures and attestations of the running images.
+
+SCOPE:
+- Read the signatures and attestations cosign stores next to an image, under the tags sha256-<digest>.sig and .att
+- Verify them with the configured public keys (ECDSA, RSA or Ed25519, as written by cosign generate-key-pair)
+- Check that they are about the running digest, and tell SLSA provenance attestations from the others
+- Keyless (Fulcio/Rekor) signatures and the OCI 1.1 referrers/bundle format of cosign v3 are not verified
+*/
+
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MatteoMori/sentinel/pkg/registry"
+)
+
+const (
+	// simpleSigningMediaType is the layer media type of cosign signatures
+	simpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// dsseMediaType is the layer media type of cosign attestations
+	dsseMediaType = "application/vnd.dsse.envelope.v1+json"
+	// signatureAnnotation holds the base64 signature of a simple signing layer
+	signatureAnnotation = "dev.cosignproject.cosign/signature"
+	// inTotoPayloadType is the payload type of the DSSE envelopes of attestations
+	inTotoPayloadType = "application/vnd.in-toto+json"
+	// slsaProvenancePrefix starts the predicate types of the SLSA provenance attestations (v0.2, v1...)
+	slsaProvenancePrefix = "https://slsa.dev/provenance/"
+)
+
+// LoadPublicKeys reads PEM public keys from files
+func LoadPublicKeys(paths []string) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("signatures: %w", err)
+		}
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "PUBLIC KEY" {
+				continue
+			}
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("signatures: %s: %w", path, err)
+			}
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("signatures: no public key found in %s", strings.Join(paths, ", "))
+	}
+	return keys, nil
+}
+
+// verifyWithAny reports whether one of the keys verifies the signature of a message
+func verifyWithAny(keys []crypto.PublicKey, message, signature []byte) bool {
+	digest := sha256.Sum256(message)
+	for _, key := range keys {
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, digest[:], signature) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil {
+				return true
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, message, signature) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// simpleSigningPayload is the signed payload of a cosign signature
+type simpleSigningPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// verifySignatures reports whether one of the layers of a .sig artifact is a valid signature of the digest
+func verifySignatures(keys []crypto.PublicKey, layers []registry.Layer, digest string) bool {
+	for _, layer := range layers {
+		if layer.MediaType != simpleSigningMediaType {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(layer.Annotations[signatureAnnotation])
+		if err != nil || !verifyWithAny(keys, layer.Data, signature) {
+			continue
+		}
+		var payload simpleSigningPayload
+		if err := json.Unmarshal(layer.Data, &payload); err != nil {
+			continue
+		}
+		// A valid signature of another image proves nothing about this one
+		if payload.Critical.Image.DockerManifestDigest == digest {
+			return true
+		}
+	}
+	return false
+}
+
+// envelope is a DSSE envelope
+type envelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"` // base64
+	Signatures  []struct {
+		Sig string `json:"sig"` // base64
+	} `json:"signatures"`
+}
+
+// statement is an in-toto statement
+type statement struct {
+	PredicateType string `json:"predicateType"`
+	Subject       []struct {
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+}
+
+// pae is the DSSE pre-authentication encoding, what is actually signed
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// verifyAttestations returns the predicate types of the layers of a .att artifact validly signed for the digest
+func verifyAttestations(keys []crypto.PublicKey, layers []registry.Layer, digest string) []string {
+	algorithm, hex, _ := strings.Cut(digest, ":")
+	var predicateTypes []string
+	for _, layer := range layers {
+		if layer.MediaType != dsseMediaType {
+			continue
+		}
+		var env envelope
+		if err := json.Unmarshal(layer.Data, &env); err != nil || env.PayloadType != inTotoPayloadType {
+			continue
+		}
+		payload, err := base64.StdEncoding.DecodeString(env.Payload)
+		if err != nil {
+			continue
+		}
+		signed := false
+		for _, s := range env.Signatures {
+			if signature, err := base64.StdEncoding.DecodeString(s.Sig); err == nil && verifyWithAny(keys, pae(env.PayloadType, payload), signature) {
+				signed = true
+				break
+			}
+		}
+		if !signed {
+			continue
+		}
+
+		var st statement
+		if err := json.Unmarshal(payload, &st); err != nil {
+			continue
+		}
+		for _, subject := range st.Subject {
+			if subject.Digest[algorithm] == hex {
+				predicateTypes = append(predicateTypes, st.PredicateType)
+				break
+			}
+		}
+	}
+	return predicateTypes
+}
+
+// isProvenance reports whether a predicate type is a SLSA provenance
+func isProvenance(predicateType string) bool {
+	return strings.HasPrefix(predicateType, slsaProvenancePrefix)
+}
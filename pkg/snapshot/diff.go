@@ -0,0 +1,193 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+)
+
+// Source describes one side of a diff
+type Source struct {
+	Name      string    `json:"name"` // File path or Sentinel URL
+	Cluster   string    `json:"cluster"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WorkloadRef identifies a workload
+type WorkloadRef struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+}
+
+// ImageChange is a container whose image differs between the two snapshots
+type ImageChange struct {
+	Namespace string           `json:"namespace"`
+	Kind      string           `json:"kind"`
+	Workload  string           `json:"workload"`
+	Container string           `json:"container"`
+	Old       *inventory.Image `json:"old"` // null when the container only exists in the second snapshot
+	New       *inventory.Image `json:"new"` // null when the container only exists in the first snapshot
+}
+
+// Report lists the differences between two snapshots
+type Report struct {
+	From             Source        `json:"from"`
+	To               Source        `json:"to"`
+	AddedWorkloads   []WorkloadRef `json:"addedWorkloads"`
+	RemovedWorkloads []WorkloadRef `json:"removedWorkloads"`
+	ChangedImages    []ImageChange `json:"changedImages"`
+}
+
+// Empty reports whether both snapshots run the same images
+func (r Report) Empty() bool {
+	return len(r.AddedWorkloads) == 0 && len(r.RemovedWorkloads) == 0 && len(r.ChangedImages) == 0
+}
+
+// Diff compares two snapshots: workloads are matched on namespace/kind/name, containers on their name
+func Diff(from, to Snapshot, fromName, toName string) Report {
+	report := Report{
+		From:             Source{Name: fromName, Cluster: from.Cluster, CreatedAt: from.CreatedAt},
+		To:               Source{Name: toName, Cluster: to.Cluster, CreatedAt: to.CreatedAt},
+		AddedWorkloads:   []WorkloadRef{},
+		RemovedWorkloads: []WorkloadRef{},
+		ChangedImages:    []ImageChange{},
+	}
+
+	before := indexWorkloads(from.Workloads)
+	after := indexWorkloads(to.Workloads)
+
+	for key, w := range before {
+		if _, ok := after[key]; !ok {
+			report.RemovedWorkloads = append(report.RemovedWorkloads, WorkloadRef{Namespace: w.Namespace, Kind: w.Kind, Name: w.Name})
+		}
+	}
+
+	for key, w := range after {
+		old, ok := before[key]
+		if !ok {
+			report.AddedWorkloads = append(report.AddedWorkloads, WorkloadRef{Namespace: w.Namespace, Kind: w.Kind, Name: w.Name})
+			continue
+		}
+
+		oldImages := containerImages(old.Containers)
+		newImages := containerImages(w.Containers)
+		for name, newImage := range newImages {
+			oldImage, existed := oldImages[name]
+			if existed && oldImage.Reference == newImage.Reference {
+				continue
+			}
+			change := ImageChange{Namespace: w.Namespace, Kind: w.Kind, Workload: w.Name, Container: name, New: newImage}
+			if existed {
+				change.Old = oldImage
+			}
+			report.ChangedImages = append(report.ChangedImages, change)
+		}
+		for name, oldImage := range oldImages {
+			if _, ok := newImages[name]; !ok {
+				report.ChangedImages = append(report.ChangedImages, ImageChange{Namespace: w.Namespace, Kind: w.Kind, Workload: w.Name, Container: name, Old: oldImage})
+			}
+		}
+	}
+
+	sortRefs(report.AddedWorkloads)
+	sortRefs(report.RemovedWorkloads)
+	sort.Slice(report.ChangedImages, func(i, j int) bool {
+		a, b := report.ChangedImages[i], report.ChangedImages[j]
+		if ka, kb := inventory.WorkloadKey(a.Namespace, a.Kind, a.Workload), inventory.WorkloadKey(b.Namespace, b.Kind, b.Workload); ka != kb {
+			return ka < kb
+		}
+		return a.Container < b.Container
+	})
+
+	return report
+}
+
+// WriteText prints a report in a plain, diff-like format
+func (r Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", describe(r.From), describe(r.To))
+	if r.Empty() {
+		b.WriteString("No differences\n")
+	}
+	for _, ref := range r.AddedWorkloads {
+		fmt.Fprintf(&b, "+ %s/%s/%s\n", ref.Namespace, ref.Kind, ref.Name)
+	}
+	for _, ref := range r.RemovedWorkloads {
+		fmt.Fprintf(&b, "- %s/%s/%s\n", ref.Namespace, ref.Kind, ref.Name)
+	}
+	for _, c := range r.ChangedImages {
+		fmt.Fprintf(&b, "~ %s/%s/%s [%s] %s -> %s\n", c.Namespace, c.Kind, c.Workload, c.Container, reference(c.Old), reference(c.New))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMarkdown prints a report as Markdown tables, e.g. for a pull request comment
+func (r Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Inventory diff\n\n`%s` → `%s`\n\n", describe(r.From), describe(r.To))
+	if r.Empty() {
+		b.WriteString("No differences.\n")
+	}
+
+	if len(r.AddedWorkloads) > 0 || len(r.RemovedWorkloads) > 0 {
+		b.WriteString("### Workloads\n\n| | Namespace | Kind | Name |\n|---|---|---|---|\n")
+		for _, ref := range r.AddedWorkloads {
+			fmt.Fprintf(&b, "| added | %s | %s | %s |\n", ref.Namespace, ref.Kind, ref.Name)
+		}
+		for _, ref := range r.RemovedWorkloads {
+			fmt.Fprintf(&b, "| removed | %s | %s | %s |\n", ref.Namespace, ref.Kind, ref.Name)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(r.ChangedImages) > 0 {
+		b.WriteString("### Images\n\n| Namespace | Kind | Workload | Container | Before | After |\n|---|---|---|---|---|---|\n")
+		for _, c := range r.ChangedImages {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | `%s` | `%s` |\n", c.Namespace, c.Kind, c.Workload, c.Container, reference(c.Old), reference(c.New))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func indexWorkloads(workloads []inventory.Workload) map[string]inventory.Workload {
+	index := make(map[string]inventory.Workload, len(workloads))
+	for _, w := range workloads {
+		index[w.Key()] = w
+	}
+	return index
+}
+
+func containerImages(containers []inventory.Container) map[string]*inventory.Image {
+	images := make(map[string]*inventory.Image, len(containers))
+	for i := range containers {
+		images[containers[i].Name] = &containers[i].Image
+	}
+	return images
+}
+
+func sortRefs(refs []WorkloadRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		return inventory.WorkloadKey(refs[i].Namespace, refs[i].Kind, refs[i].Name) < inventory.WorkloadKey(refs[j].Namespace, refs[j].Kind, refs[j].Name)
+	})
+}
+
+// describe renders a source as "name (cluster, created at)"
+func describe(s Source) string {
+	return fmt.Sprintf("%s (cluster %s, %s)", s.Name, s.Cluster, s.CreatedAt.Format(time.RFC3339))
+}
+
+func reference(img *inventory.Image) string {
+	if img == nil {
+		return "(none)"
+	}
+	return img.Reference
+}
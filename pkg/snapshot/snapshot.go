@@ -0,0 +1,109 @@
+/*
+Inventory snapshots.
+
+SCOPE:
+- Capture the full inventory Sentinel builds (workloads, containers, parsed images, extra labels) in a versioned document
+- Read and write snapshots as JSON or YAML
+- Compare two snapshots (see diff.go): two clusters, or a cluster against its own past
+*/
+
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	"sigs.k8s.io/yaml"
+)
+
+/*
+SchemaVersion identifies the format of the snapshots.
+Fields may be added within a version; renaming or removing a field requires a new version.
+*/
+const SchemaVersion = "sentinel.snapshot/v1"
+
+// Snapshot is the inventory of a cluster at a point in time
+type Snapshot struct {
+	SchemaVersion string               `json:"schemaVersion"`
+	Cluster       string               `json:"cluster"`
+	CreatedAt     time.Time            `json:"createdAt"`
+	Workloads     []inventory.Workload `json:"workloads"`
+}
+
+// New builds a snapshot of the given workloads
+func New(clusterName string, workloads []inventory.Workload) Snapshot {
+	if workloads == nil {
+		workloads = []inventory.Workload{}
+	}
+	return Snapshot{
+		SchemaVersion: SchemaVersion,
+		Cluster:       clusterName,
+		CreatedAt:     time.Now().UTC(),
+		Workloads:     workloads,
+	}
+}
+
+// Write serializes a snapshot as "json" or "yaml"
+func Write(w io.Writer, s Snapshot, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(s)
+	case "yaml":
+		data, err := yaml.Marshal(s)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	default:
+		return fmt.Errorf("unknown snapshot format %q (supported: json, yaml)", format)
+	}
+}
+
+// Parse reads a JSON or YAML snapshot and checks its schema version
+func Parse(data []byte) (Snapshot, error) {
+	var s Snapshot
+	// YAML is a superset of JSON: one decoder handles both
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return Snapshot{}, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if s.SchemaVersion != SchemaVersion {
+		return Snapshot{}, fmt.Errorf("unsupported snapshot schemaVersion %q (expected %q)", s.SchemaVersion, SchemaVersion)
+	}
+	return s, nil
+}
+
+// Load reads a snapshot file ("-" reads stdin)
+func Load(path string) (Snapshot, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	s, err := Parse(data)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// FormatFromPath guesses the snapshot format from a file extension, defaulting to JSON
+func FormatFromPath(path string) string {
+	if strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml") {
+		return "yaml"
+	}
+	return "json"
+}
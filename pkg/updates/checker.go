@@ -0,0 +1,235 @@
+package updates
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	"github.com/MatteoMori/sentinel/pkg/registry"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// listTimeout bounds the listing of the tags of one repository, rate limit waits included
+	listTimeout = 5 * time.Minute
+	// maxErrorTTL bounds how long a failed listing is cached
+	maxErrorTTL = 5 * time.Minute
+	// maxRepositories bounds the tag lists kept in memory
+	maxRepositories = 1000
+)
+
+// Result is the newest version available for the image of one container
+type Result struct {
+	Namespace string          `json:"namespace"`
+	Kind      string          `json:"kind"`
+	Workload  string          `json:"workload"`
+	Container string          `json:"container"`
+	Image     inventory.Image `json:"image"`
+	Latest    string          `json:"latest"` // Newest comparable tag, the running one when up to date
+	Behind    Behind          `json:"behind"`
+}
+
+// Outdated reports whether a newer version is available
+func (r Result) Outdated() bool {
+	return r.Behind != Behind{}
+}
+
+// Checker compares the tags of the running images with the tags of their repositories
+type Checker struct {
+	client         *registry.Client
+	matcher        *Matcher
+	store          *inventory.Store
+	clientset      kubernetes.Interface
+	repositories   []string
+	interval       time.Duration
+	usePullSecrets bool
+	tags           *registry.Cache[[]string]     // "<credentials identity> registry/repository" -> tags (see registry.Client.CacheKey)
+	slots          chan struct{}                 // Limits the listings in flight
+	series         *SentinelPrometheus.SeriesSet // By workload key
+
+	mu          sync.Mutex
+	results     map[string][]Result // workload key -> results of its containers running a version tag
+	generations map[string]uint64   // workload key -> evaluation counter, so that a slow listing never overwrites a newer one
+}
+
+/*
+Init evaluates every workload added or changed in the inventory, and the whole inventory every interval,
+listing the tags of each repository at most once per interval.
+*/
+func Init(cfg shared.UpdatesConfig, client *registry.Client, store *inventory.Store, broker *events.Broker, clientset kubernetes.Interface) (*Checker, error) {
+	matcher, err := NewMatcher(cfg.PrereleasePattern, cfg.IncludePrereleases, cfg.MatchVariant)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	checker := &Checker{
+		client:         client,
+		matcher:        matcher,
+		store:          store,
+		clientset:      clientset,
+		repositories:   cfg.Repositories,
+		interval:       cfg.Interval,
+		usePullSecrets: cfg.UsePullSecrets,
+		tags:           registry.NewCache[[]string](maxRepositories),
+		slots:          make(chan struct{}, cfg.Concurrency),
+		series:         SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelImageVersionsBehind),
+		results:        make(map[string][]Result),
+		generations:    make(map[string]uint64),
+	}
+
+	broker.Consume("updates", 256, func(e events.Event) {
+		go checker.evaluateWorkload(e.Namespace, e.Kind, e.Workload)
+	})
+	go func() {
+		for {
+			time.Sleep(cfg.Interval)
+			for _, w := range store.List() {
+				checker.evaluateWorkload(w.Namespace, w.Kind, w.Name)
+			}
+		}
+	}()
+	return checker, nil
+}
+
+// allowed reports whether the repository of an image is in the allow-list
+func (c *Checker) allowed(image inventory.Image) bool {
+	return len(c.repositories) == 0 || slices.ContainsFunc(c.repositories, func(glob string) bool { return inventory.MatchGlob(glob, image.RepositoryKey()) })
+}
+
+// EvaluateNamespace evaluates every workload of a namespace, after its initial listing (which publishes no event)
+func (c *Checker) EvaluateNamespace(namespace string) {
+	for _, w := range c.store.ListNamespace(namespace) {
+		c.evaluateWorkload(w.Namespace, w.Kind, w.Name)
+	}
+}
+
+// evaluateWorkload compares the images of one workload after a change, or forgets it once deleted
+func (c *Checker) evaluateWorkload(namespace, kind, name string) {
+	key := inventory.WorkloadKey(namespace, kind, name)
+	c.mu.Lock()
+	c.generations[key]++
+	generation := c.generations[key]
+	c.mu.Unlock()
+
+	var results []Result
+	w, ok := c.store.Get(namespace, kind, name)
+	if ok {
+		results = c.evaluate(w)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generations[key] != generation {
+		return // A newer evaluation of the workload started meanwhile
+	}
+	if !ok {
+		delete(c.generations, key)
+	}
+	c.setResults(namespace, kind, name, results)
+}
+
+// evaluate compares the tag of every container of a workload with the tags of its repository
+func (c *Checker) evaluate(w inventory.Workload) []Result {
+	var keychain registry.Keychain
+	keychainRead := false
+
+	var results []Result
+	for _, container := range w.Containers {
+		image := container.Image
+		if image.Tag == "" || image.TagDefaulted() || !c.allowed(image) {
+			continue
+		}
+		if _, _, ok := c.matcher.Compare(image.Tag, nil); !ok {
+			continue // Not a version tag (latest, main, stable...): nothing to compare with
+		}
+
+		if c.usePullSecrets && !keychainRead && len(w.ImagePullSecrets) > 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+			keychain = registry.PullSecretKeychain(ctx, c.clientset, w.Namespace, w.ImagePullSecrets)
+			cancel()
+			keychainRead = true
+		}
+		tags, err := c.tags.Load(c.client.CacheKey(image, keychain, image.RepositoryKey()), func() ([]string, error, time.Duration) {
+			c.slots <- struct{}{}
+			defer func() { <-c.slots }()
+
+			ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+			defer cancel()
+			tags, err := c.client.ListTags(ctx, image, keychain)
+			if err != nil {
+				return nil, err, min(c.interval, maxErrorTTL)
+			}
+			return tags, nil, c.interval
+		})
+		if err != nil {
+			slog.Debug("Unable to list the tags of an image",
+				slog.String("ns/workload", w.Namespace+"/"+w.Name),
+				slog.String("container", container.Name),
+				slog.String("repository", image.RepositoryKey()),
+				slog.Any("error", err))
+			continue
+		}
+
+		latest, behind, _ := c.matcher.Compare(image.Tag, tags)
+		results = append(results, Result{
+			Namespace: w.Namespace,
+			Kind:      w.Kind,
+			Workload:  w.Name,
+			Container: container.Name,
+			Image:     image,
+			Latest:    latest,
+			Behind:    behind,
+		})
+	}
+	return results
+}
+
+// setResults replaces the results and the series of a workload. Must be called with the lock held.
+func (c *Checker) setResults(namespace, kind, name string, results []Result) {
+	key := inventory.WorkloadKey(namespace, kind, name)
+	series := c.series.Update(key)
+	for _, r := range results {
+		for level, count := range map[string]int{"major": r.Behind.Major, "minor": r.Behind.Minor, "patch": r.Behind.Patch} {
+			series.Set(float64(count), r.Namespace, r.Kind, r.Workload, r.Container, r.Image.Reference, r.Latest, level)
+		}
+	}
+	series.Commit()
+
+	if len(results) == 0 {
+		delete(c.results, key)
+		return
+	}
+	c.results[key] = results
+}
+
+// Results returns the comparisons of the containers of a namespace ("" for all), sorted by workload. outdatedOnly drops the up to date ones.
+func (c *Checker) Results(namespace string, outdatedOnly bool) []Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	results := []Result{}
+	for _, workloadResults := range c.results {
+		for _, r := range workloadResults {
+			if (namespace != "" && r.Namespace != namespace) || (outdatedOnly && !r.Outdated()) {
+				continue
+			}
+			results = append(results, r)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		ki := inventory.WorkloadKey(results[i].Namespace, results[i].Kind, results[i].Workload) + "/" + results[i].Container
+		kj := inventory.WorkloadKey(results[j].Namespace, results[j].Kind, results[j].Workload) + "/" + results[j].Container
+		return ki < kj
+	})
+	return results
+}
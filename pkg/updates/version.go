@@ -0,0 +1,166 @@
+/*
+Detection of the newer versions of the running images, from the tags of their repositories.
+
+SCOPE:
+- Read the tags that look like versions: 1, 1.25, 1.25.3, v1.25.3, 1.25.3-alpine, 1.26.0-rc.1
+- Compare a tag only with the tags of the same shape: "1.25" with the other major.minor tags, "1.25.3-alpine"
+  with the other -alpine tags (variants), prereleases only when asked to
+- Count how many major, minor and patch versions a container is behind the newest tag
+*/
+
+package updates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// DefaultPrereleasePattern tells the usual prerelease suffixes (rc.1, beta, nightly) from variant suffixes (alpine, slim)
+const DefaultPrereleasePattern = "(?i)^(alpha|beta|rc|pre|preview|dev|snapshot|nightly)"
+
+// versionTag matches the tags that look like versions: optional "v", 1 to 3 numbers, optional "-suffix"
+var versionTag = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z][0-9A-Za-z.\-]*))?$`)
+
+// version is a tag parsed as a version
+type version struct {
+	tag        string
+	parts      int // Number of version numbers in the tag: 1, 2 or 3
+	numbers    [3]uint64
+	variant    string // Suffix that isn't a prerelease, e.g. "alpine"
+	prerelease string // e.g. "rc.1"
+}
+
+// Behind counts the versions released after the running one
+type Behind struct {
+	Major int `json:"major"` // Newer major versions
+	Minor int `json:"minor"` // Newer minor versions of the running major version
+	Patch int `json:"patch"` // Newer patch versions of the running minor version
+}
+
+// Matcher parses and compares tags according to the configured prerelease and variant handling
+type Matcher struct {
+	prerelease         *regexp.Regexp
+	includePrereleases bool
+	matchVariant       bool
+}
+
+// NewMatcher builds a matcher. prereleasePattern tells prerelease suffixes (rc.1) from variant suffixes (alpine).
+func NewMatcher(prereleasePattern string, includePrereleases, matchVariant bool) (*Matcher, error) {
+	prerelease, err := regexp.Compile(prereleasePattern)
+	if err != nil {
+		return nil, fmt.Errorf("updates: invalid prereleasePattern: %w", err)
+	}
+	return &Matcher{prerelease: prerelease, includePrereleases: includePrereleases, matchVariant: matchVariant}, nil
+}
+
+// parse reads a tag as a version
+func (m *Matcher) parse(tag string) (version, bool) {
+	groups := versionTag.FindStringSubmatch(tag)
+	if groups == nil {
+		return version{}, false
+	}
+	v := version{tag: tag}
+	for i := 1; i <= 3 && groups[i] != ""; i++ {
+		n, err := strconv.ParseUint(groups[i], 10, 64)
+		if err != nil {
+			return version{}, false
+		}
+		v.numbers[i-1], v.parts = n, i
+	}
+	if suffix := groups[4]; suffix != "" {
+		if m.prerelease.MatchString(suffix) {
+			v.prerelease = suffix
+		} else {
+			v.variant = suffix
+		}
+	}
+	return v, true
+}
+
+// less orders two versions of the same shape: by numbers, then a prerelease before its release
+func (v version) less(other version) bool {
+	for i := range v.numbers {
+		if v.numbers[i] != other.numbers[i] {
+			return v.numbers[i] < other.numbers[i]
+		}
+	}
+	switch {
+	case v.prerelease == other.prerelease:
+		return false
+	case v.prerelease == "":
+		return false
+	case other.prerelease == "":
+		return true
+	}
+	// Both prereleases of the same version: semver precedence (rc.2 < rc.10)
+	a, errA := semver.NewVersion("0.0.0-" + v.prerelease)
+	b, errB := semver.NewVersion("0.0.0-" + other.prerelease)
+	if errA != nil || errB != nil {
+		return v.prerelease < other.prerelease
+	}
+	return a.LessThan(b)
+}
+
+// comparable reports whether a tag is to be compared with the running one
+func (m *Matcher) comparable(current, candidate version) bool {
+	if candidate.parts != current.parts {
+		return false
+	}
+	if candidate.prerelease != "" && !m.includePrereleases {
+		return false
+	}
+	return !m.matchVariant || candidate.variant == current.variant
+}
+
+/*
+Downgrade reports whether a tag replaced by another one is a lower version, prerelease included (1.26.0 -> 1.26.0-rc.1).
+Only tags of the same shape are compared: 1.25.3 -> 1.25.3-alpine or 20-slim -> 20-alpine change the variant, not the version.
+*/
+func (m *Matcher) Downgrade(oldTag, newTag string) bool {
+	oldVersion, okOld := m.parse(oldTag)
+	newVersion, okNew := m.parse(newTag)
+	if !okOld || !okNew || oldVersion.parts != newVersion.parts || oldVersion.variant != newVersion.variant {
+		return false
+	}
+	return newVersion.less(oldVersion)
+}
+
+/*
+Compare returns the newest tag comparable with the running one, and how many versions the running one is behind.
+The versions are counted among the released tags: 1.25.3 with 1.25.5, 1.26.0, 1.27.0 and 1.27.1 released is
+0 major, 2 minor (1.26, 1.27) and 1 patch (1.25.5) behind 1.27.1. A running prerelease is 1 patch behind its release.
+ok is false when the running tag isn't a version.
+*/
+func (m *Matcher) Compare(currentTag string, tags []string) (latest string, behind Behind, ok bool) {
+	current, ok := m.parse(currentTag)
+	if !ok {
+		return "", Behind{}, false
+	}
+
+	newest := current
+	majors, minors, patches := map[uint64]bool{}, map[uint64]bool{}, map[uint64]bool{}
+	for _, tag := range tags {
+		candidate, ok := m.parse(tag)
+		if !ok || !m.comparable(current, candidate) || !current.less(candidate) {
+			continue
+		}
+		if newest.less(candidate) {
+			newest = candidate
+		}
+		switch {
+		case candidate.numbers[0] > current.numbers[0]:
+			majors[candidate.numbers[0]] = true
+		case candidate.numbers[1] > current.numbers[1]:
+			minors[candidate.numbers[1]] = true
+		case candidate.numbers[2] > current.numbers[2]:
+			patches[candidate.numbers[2]] = true
+		case candidate.prerelease == "":
+			// The release of the running prerelease (1.26.0 when running 1.26.0-rc.1) counts as a patch
+			patches[candidate.numbers[2]] = true
+		}
+	}
+	return newest.tag, Behind{Major: len(majors), Minor: len(minors), Patch: len(patches)}, true
+}
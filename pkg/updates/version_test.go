@@ -0,0 +1,31 @@
+package updates
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	m, err := NewMatcher(DefaultPrereleasePattern, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := []string{"latest", "1.25.3", "1.25.5", "1.26.0-rc.1", "1.26.0", "1.27.0", "1.27.1", "2.0.0", "1.27", "1.25.3-alpine", "1.25.4-alpine"}
+
+	tests := []struct {
+		current string
+		latest  string
+		behind  Behind
+		ok      bool
+	}{
+		{"1.25.3", "2.0.0", Behind{Major: 1, Minor: 2, Patch: 1}, true},
+		{"2.0.0", "2.0.0", Behind{}, true},
+		{"1.26.0-rc.1", "2.0.0", Behind{Major: 1, Minor: 1, Patch: 1}, true}, // 1.26.0 replaced the running prerelease
+		{"1.25", "1.27", Behind{Minor: 1}, true},
+		{"1.25.3-alpine", "1.25.4-alpine", Behind{Patch: 1}, true},
+		{"latest", "", Behind{}, false},
+	}
+	for _, tt := range tests {
+		latest, behind, ok := m.Compare(tt.current, tags)
+		if latest != tt.latest || behind != tt.behind || ok != tt.ok {
+			t.Errorf("Compare(%q) = %q, %+v, %t; want %q, %+v, %t", tt.current, latest, behind, ok, tt.latest, tt.behind, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package vulnerability
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/events"
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+	SentinelPrometheus "github.com/MatteoMori/sentinel/pkg/prometheus"
+	"github.com/MatteoMori/sentinel/pkg/reload"
+	"github.com/MatteoMori/sentinel/pkg/shared"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Result is the vulnerability report matched with one container. Report is nil when no report covers its image.
+type Result struct {
+	Namespace string          `json:"namespace"`
+	Kind      string          `json:"kind"`
+	Workload  string          `json:"workload"`
+	Container string          `json:"container"`
+	Image     inventory.Image `json:"image"`
+	Report    *Report         `json:"report"`
+}
+
+// Summary is the state of the whole inventory
+type Summary struct {
+	LoadedAt time.Time `json:"loadedAt"`
+	Reports  int       `json:"reports"` // Reports loaded
+	Missing  int       `json:"missing"` // Containers without report, in the namespace asked for
+	Results  []Result  `json:"results"`
+}
+
+// Checker joins the reports with the inventory and keeps the metrics up to date
+type Checker struct {
+	store               *inventory.Store
+	vulnerabilitySeries *SentinelPrometheus.SeriesSet // By workload key
+	reportMissingSeries *SentinelPrometheus.SeriesSet // By workload key
+
+	mu       sync.Mutex
+	index    *Index
+	loadedAt time.Time
+	results  map[string][]Result // workload key -> results of its containers
+}
+
+// NewChecker builds a checker without reports: nothing is evaluated until Apply() is called
+func NewChecker(store *inventory.Store) *Checker {
+	return &Checker{
+		store:               store,
+		vulnerabilitySeries: SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelImageVulnerabilities),
+		reportMissingSeries: SentinelPrometheus.NewSeriesSet(SentinelPrometheus.SentinelImageVulnerabilityReportMissing),
+		results:             make(map[string][]Result),
+	}
+}
+
+/*
+Init loads the reports from a directory or from the ConfigMaps matching a label selector, reloads them when
+they change and evaluates every workload added or changed in the inventory.
+*/
+func Init(cfg shared.VulnerabilitiesConfig, store *inventory.Store, broker *events.Broker, clientset kubernetes.Interface) (*Checker, error) {
+	checker := NewChecker(store)
+
+	switch {
+	case cfg.ConfigMaps.LabelSelector != "":
+		reload.WatchConfigMaps("vulnerability reports", clientset, cfg.ConfigMaps.Namespace, cfg.ConfigMaps.LabelSelector, checker.Apply)
+	case cfg.Path != "":
+		if err := reload.WatchDir("vulnerability reports", cfg.Path, cfg.ReloadInterval, checker.Apply); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("vulnerabilities: path or configMaps.labelSelector is required")
+	}
+
+	broker.Consume("vulnerabilities", 256, func(e events.Event) {
+		checker.evaluateWorkload(e.Namespace, e.Kind, e.Workload)
+	})
+	return checker, nil
+}
+
+/*
+Apply parses a new set of reports and evaluates the whole inventory against them.
+A file that isn't a Trivy or Grype image report is logged and skipped, so that one bad report doesn't hide the others.
+*/
+func (c *Checker) Apply(files map[string][]byte) error {
+	sources := make([]string, 0, len(files))
+	for source := range files {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	reports := make([]Report, 0, len(files))
+	for _, source := range sources {
+		report, err := Parse(source, files[source])
+		if err != nil {
+			slog.Warn("Skipping vulnerability report", slog.String("source", source), slog.Any("error", err))
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.index, c.loadedAt = NewIndex(reports), time.Now().UTC()
+
+	// The series are replaced workload by workload, so that a scrape during the reload never sees them vanish
+	stale := maps.Clone(c.results)
+	for _, w := range c.store.List() {
+		delete(stale, w.Key())
+		c.setResults(w.Namespace, w.Kind, w.Name, c.evaluate(w))
+	}
+	for _, results := range stale {
+		c.setResults(results[0].Namespace, results[0].Kind, results[0].Workload, nil)
+	}
+	SentinelPrometheus.SentinelVulnerabilityReports.Set(float64(len(reports)))
+	return nil
+}
+
+// EvaluateNamespace evaluates every workload of a namespace, after its initial listing (which publishes no event)
+func (c *Checker) EvaluateNamespace(namespace string) {
+	for _, w := range c.store.ListNamespace(namespace) {
+		c.evaluateWorkload(w.Namespace, w.Kind, w.Name)
+	}
+}
+
+// evaluateWorkload re-evaluates one workload after a change, or forgets it once deleted
+func (c *Checker) evaluateWorkload(namespace, kind, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.index == nil {
+		return
+	}
+
+	var results []Result
+	if w, ok := c.store.Get(namespace, kind, name); ok {
+		results = c.evaluate(w)
+	}
+	c.setResults(namespace, kind, name, results)
+}
+
+// evaluate finds the report of every container of a workload. Must be called with the lock held.
+func (c *Checker) evaluate(w inventory.Workload) []Result {
+	results := make([]Result, 0, len(w.Containers))
+	for _, container := range w.Containers {
+		result := Result{Namespace: w.Namespace, Kind: w.Kind, Workload: w.Name, Container: container.Name, Image: container.Image}
+		if report, ok := c.index.Lookup(container.Image); ok {
+			result.Report = &report
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// setResults replaces the results and the series of a workload. Must be called with the lock held.
+func (c *Checker) setResults(namespace, kind, name string, results []Result) {
+	key := inventory.WorkloadKey(namespace, kind, name)
+	vulnerabilities, missing := c.vulnerabilitySeries.Update(key), c.reportMissingSeries.Update(key)
+	for _, r := range results {
+		if r.Report == nil {
+			missing.Set(1, r.Namespace, r.Kind, r.Workload, r.Container, r.Image.Reference)
+			continue
+		}
+		for _, severity := range Severities {
+			vulnerabilities.Set(float64(r.Report.Counts[severity]), r.Namespace, r.Kind, r.Workload, r.Container, r.Image.Reference, severity)
+		}
+	}
+	vulnerabilities.Commit()
+	missing.Commit()
+
+	if len(results) == 0 {
+		delete(c.results, key)
+		return
+	}
+	c.results[key] = results
+}
+
+// Summary returns the results of the containers of a namespace ("" for all), optionally only the ones without report
+func (c *Checker) Summary(namespace string, missingOnly bool) Summary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	summary := Summary{LoadedAt: c.loadedAt, Results: []Result{}}
+	if c.index != nil {
+		summary.Reports = c.index.Len()
+	}
+	for _, results := range c.results {
+		for _, r := range results {
+			if namespace != "" && r.Namespace != namespace {
+				continue
+			}
+			if r.Report == nil {
+				summary.Missing++
+			} else if missingOnly {
+				continue
+			}
+			summary.Results = append(summary.Results, r)
+		}
+	}
+
+	sort.Slice(summary.Results, func(i, j int) bool {
+		ki := inventory.WorkloadKey(summary.Results[i].Namespace, summary.Results[i].Kind, summary.Results[i].Workload)
+		kj := inventory.WorkloadKey(summary.Results[j].Namespace, summary.Results[j].Kind, summary.Results[j].Workload)
+		if ki != kj {
+			return ki < kj
+		}
+		return strings.Compare(summary.Results[i].Container, summary.Results[j].Container) < 0
+	})
+	return summary
+}
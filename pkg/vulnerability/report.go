@@ -0,0 +1,228 @@
+/*
+Vulnerability reports produced by image scanners (in CI, or by an operator).
+
+SCOPE:
+- Parse Trivy ("trivy image --format json") and Grype ("grype -o json") reports
+- Reduce them to the number of distinct vulnerabilities per severity
+- Index them by image digest and by image reference, so that they can be joined with the running containers
+
+An image is matched by digest first (the report lists the repo digests of the scanned image), then by
+reference: registry, repository and tag, with the same defaults as container images ("nginx" is "docker.io/nginx:latest").
+*/
+
+package vulnerability
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MatteoMori/sentinel/pkg/inventory"
+)
+
+// Severities are the severity levels reported, from the most to the least severe
+var Severities = []string{"critical", "high", "medium", "low", "unknown"}
+
+// Report is the summary of a vulnerability report
+type Report struct {
+	Source    string         `json:"source"`              // File name, or "<namespace>/<configmap>/<key>"
+	Scanner   string         `json:"scanner"`             // trivy or grype
+	Image     string         `json:"image"`               // Image reference as scanned
+	Digests   []string       `json:"digests,omitempty"`   // Digests of the scanned image
+	CreatedAt time.Time      `json:"createdAt,omitempty"` // When the scan ran, when the report tells
+	Counts    map[string]int `json:"counts"`              // Distinct vulnerabilities per severity
+}
+
+// trivyReport is the part of a Trivy JSON report Sentinel reads
+type trivyReport struct {
+	SchemaVersion int       `json:"SchemaVersion"`
+	CreatedAt     time.Time `json:"CreatedAt"`
+	ArtifactName  string    `json:"ArtifactName"`
+	ArtifactType  string    `json:"ArtifactType"`
+	Metadata      struct {
+		RepoDigests []string `json:"RepoDigests"`
+		RepoTags    []string `json:"RepoTags"`
+	} `json:"Metadata"`
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"VulnerabilityID"`
+			Severity        string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// grypeReport is the part of a Grype JSON report Sentinel reads
+type grypeReport struct {
+	Matches []struct {
+		Vulnerability struct {
+			ID       string `json:"id"`
+			Severity string `json:"severity"`
+		} `json:"vulnerability"`
+	} `json:"matches"`
+	Source *struct {
+		Type   string `json:"type"`
+		Target struct {
+			UserInput   string   `json:"userInput"`
+			RepoDigests []string `json:"repoDigests"`
+		} `json:"target"`
+	} `json:"source"`
+	Descriptor struct {
+		Name      string `json:"name"`
+		Timestamp string `json:"timestamp"`
+	} `json:"descriptor"`
+}
+
+// Parse reads a Trivy or Grype JSON report of a container image
+func Parse(source string, data []byte) (Report, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return Report{}, err
+	}
+
+	switch {
+	case probe["ArtifactName"] != nil:
+		return parseTrivy(source, data)
+	case probe["matches"] != nil:
+		return parseGrype(source, data)
+	default:
+		return Report{}, fmt.Errorf("not a Trivy or Grype JSON report")
+	}
+}
+
+func parseTrivy(source string, data []byte) (Report, error) {
+	var t trivyReport
+	if err := json.Unmarshal(data, &t); err != nil {
+		return Report{}, err
+	}
+	if t.ArtifactType != "" && t.ArtifactType != "container_image" {
+		return Report{}, fmt.Errorf("trivy report of a %s, not of a container image", t.ArtifactType)
+	}
+
+	report := newReport(source, "trivy", t.ArtifactName, t.Metadata.RepoDigests)
+	report.CreatedAt = t.CreatedAt
+	seen := make(map[string]struct{})
+	for _, result := range t.Results {
+		for _, v := range result.Vulnerabilities {
+			report.count(seen, v.VulnerabilityID, v.Severity)
+		}
+	}
+	return report, nil
+}
+
+func parseGrype(source string, data []byte) (Report, error) {
+	var g grypeReport
+	if err := json.Unmarshal(data, &g); err != nil {
+		return Report{}, err
+	}
+	if g.Source == nil || g.Source.Type != "image" {
+		return Report{}, fmt.Errorf("grype report not of a container image")
+	}
+
+	report := newReport(source, "grype", g.Source.Target.UserInput, g.Source.Target.RepoDigests)
+	if createdAt, err := time.Parse(time.RFC3339, g.Descriptor.Timestamp); err == nil {
+		report.CreatedAt = createdAt
+	}
+	seen := make(map[string]struct{})
+	for _, m := range g.Matches {
+		report.count(seen, m.Vulnerability.ID, m.Vulnerability.Severity)
+	}
+	return report, nil
+}
+
+func newReport(source, scanner, image string, repoDigests []string) Report {
+	report := Report{Source: source, Scanner: scanner, Image: image, Counts: make(map[string]int, len(Severities))}
+	for _, severity := range Severities {
+		report.Counts[severity] = 0
+	}
+	// Repo digests are "repository@sha256:..."
+	for _, repoDigest := range repoDigests {
+		if _, digest, ok := strings.Cut(repoDigest, "@"); ok {
+			report.Digests = append(report.Digests, digest)
+		}
+	}
+	if digest := inventory.ParseImage(image).Digest; digest != "" {
+		report.Digests = append(report.Digests, digest)
+	}
+	return report
+}
+
+// count adds a vulnerability once, however many packages it affects
+func (r *Report) count(seen map[string]struct{}, id, severity string) {
+	if _, dup := seen[id]; dup {
+		return
+	}
+	seen[id] = struct{}{}
+	r.Counts[normalizeSeverity(severity)]++
+}
+
+// normalizeSeverity maps the severities of the scanners onto Severities (Grype's "negligible" counts as low)
+func normalizeSeverity(severity string) string {
+	severity = strings.ToLower(severity)
+	switch severity {
+	case "critical", "high", "medium", "low":
+		return severity
+	case "negligible":
+		return "low"
+	default:
+		return "unknown"
+	}
+}
+
+// referenceKey identifies an image by "registry/repository:tag", "library/" being implied on Docker Hub
+func referenceKey(img inventory.Image) string {
+	if img.Tag == "" {
+		return ""
+	}
+	repository := img.Repository
+	if img.Registry == "docker.io" {
+		repository = strings.TrimPrefix(repository, "library/")
+	}
+	return img.Registry + "/" + repository + ":" + img.Tag
+}
+
+// Index holds the reports, by digest and by reference
+type Index struct {
+	reports     []Report
+	byDigest    map[string]*Report
+	byReference map[string]*Report
+}
+
+// NewIndex indexes reports. When several reports describe the same image, the most recent one wins.
+func NewIndex(reports []Report) *Index {
+	idx := &Index{reports: reports, byDigest: make(map[string]*Report), byReference: make(map[string]*Report)}
+	newer := func(current *Report, candidate *Report) bool {
+		return current == nil || candidate.CreatedAt.After(current.CreatedAt)
+	}
+	for i := range idx.reports {
+		r := &idx.reports[i]
+		for _, digest := range r.Digests {
+			if newer(idx.byDigest[digest], r) {
+				idx.byDigest[digest] = r
+			}
+		}
+		if r.Image == "" {
+			continue
+		}
+		if key := referenceKey(inventory.ParseImage(r.Image)); key != "" && newer(idx.byReference[key], r) {
+			idx.byReference[key] = r
+		}
+	}
+	return idx
+}
+
+// Len returns the number of reports
+func (idx *Index) Len() int {
+	return len(idx.reports)
+}
+
+// Lookup finds the report of an image, by digest then by reference
+func (idx *Index) Lookup(img inventory.Image) (Report, bool) {
+	if r, ok := idx.byDigest[img.Digest]; ok && img.Digest != "" {
+		return *r, true
+	}
+	if r, ok := idx.byReference[referenceKey(img)]; ok {
+		return *r, true
+	}
+	return Report{}, false
+}